@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sniffer/application/control"
+	"syscall"
+)
+
+// runAgent implements "sniffer agent [flags]": the gRPC service remote
+// tools use to start captures on this host.
+func runAgent(args []string) int {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	listen := flags.String("listen", ":50051", "serve the gRPC API on this address")
+	cert := flags.String("cert", "", "PEM certificate of this agent")
+	key := flags.String("key", "", "PEM private key of -cert")
+	ca := flags.String("ca", "", "PEM CA that client certificates must be signed by")
+	maxCaptures := flags.Int("max-captures", 8, "number of captures that may run at once")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer agent -cert agent.pem -key agent.key -ca clients.pem [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	config, err := control.ServerTls(*cert, *key, *ca)
+	if err != nil {
+		fmt.Println("agent:", err)
+		return 1
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println("agent:", err)
+		return 1
+	}
+
+	serviceConfig := control.DefaultConfig()
+	serviceConfig.MaxCaptures = *maxCaptures
+	service := control.NewService(serviceConfig)
+	failed := make(chan error, 1)
+	go func() {
+		failed <- control.Serve(listener, service, config)
+	}()
+	fmt.Println("agent: listening on", listener.Addr())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	status := 0
+	select {
+	case <-interrupt:
+	case err := <-failed:
+		fmt.Println("agent:", err)
+		status = 1
+	}
+	listener.Close()
+	service.Close()
+	return status
+}
@@ -0,0 +1,266 @@
+package analysis
+
+import (
+	"fmt"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultNtpTimeout is how long a request waits for its reply.
+	DefaultNtpTimeout = 10 * time.Second
+	maxPendingNtp     = 4096
+)
+
+// NtpExchange is a client request paired with the server reply. Client
+// times are capture timestamps, so Offset is the server clock relative to
+// the clock of the capturing host:
+//
+//	Offset = ((T2 - T1) + (T3 - T4)) / 2
+//	Delay  = (T4 - T1) - (T3 - T2)
+type NtpExchange struct {
+	Client  flow.Endpoint
+	Server  flow.Endpoint
+	Sent    time.Time // T1
+	Receive time.Time // T2
+	Reply   time.Time // T3
+	Arrived time.Time // T4
+	Stratum byte
+	Offset  time.Duration
+	Delay   time.Duration
+}
+
+func (e NtpExchange) ToString() string {
+	return fmt.Sprintf("%s > %s - offset %s - delay %s - stratum %d",
+		e.Client.ToString(), e.Server.ToString(), e.Offset, e.Delay, e.Stratum)
+}
+
+// NtpServer sums up the exchanges with one server.
+type NtpServer struct {
+	Server        flow.Endpoint
+	Replies       int
+	Samples       int
+	LastOffset    time.Duration
+	OffsetMin     time.Duration
+	OffsetMax     time.Duration
+	offsetTotal   time.Duration
+	DelayMin      time.Duration
+	DelayMax      time.Duration
+	delayTotal    time.Duration
+	Stratum       byte
+	ReferenceId   string
+	LeapIndicator byte
+	// KissCodes counts stratum 0 replies, such as RATE or DENY.
+	KissCodes  map[string]int
+	Unanswered int
+	LastSeen   time.Time
+}
+
+func (s *NtpServer) OffsetAverage() time.Duration {
+	if s.Samples == 0 {
+		return 0
+	}
+	return s.offsetTotal / time.Duration(s.Samples)
+}
+
+func (s *NtpServer) DelayAverage() time.Duration {
+	if s.Samples == 0 {
+		return 0
+	}
+	return s.delayTotal / time.Duration(s.Samples)
+}
+
+func (s *NtpServer) add(e NtpExchange) {
+	if s.Samples == 0 || e.Offset < s.OffsetMin {
+		s.OffsetMin = e.Offset
+	}
+	if s.Samples == 0 || e.Offset > s.OffsetMax {
+		s.OffsetMax = e.Offset
+	}
+	if s.Samples == 0 || e.Delay < s.DelayMin {
+		s.DelayMin = e.Delay
+	}
+	if e.Delay > s.DelayMax {
+		s.DelayMax = e.Delay
+	}
+	s.Samples++
+	s.LastOffset = e.Offset
+	s.offsetTotal += e.Offset
+	s.delayTotal += e.Delay
+}
+
+func (s *NtpServer) ToString() string {
+	result := s.Server.ToString()
+	if s.Replies != 0 {
+		result += fmt.Sprintf(" - stratum %d - reference %s - %d replies", s.Stratum, s.ReferenceId, s.Replies)
+	}
+	if s.Samples != 0 {
+		result += fmt.Sprintf(" - offset last %s min/avg/max %s/%s/%s - delay min/avg/max %s/%s/%s over %d samples",
+			s.LastOffset, s.OffsetMin, s.OffsetAverage(), s.OffsetMax, s.DelayMin, s.DelayAverage(), s.DelayMax, s.Samples)
+	}
+	if s.LeapIndicator != 0 {
+		result += fmt.Sprintf(" - leap indicator %d", s.LeapIndicator)
+	}
+	codes := make([]string, 0, len(s.KissCodes))
+	for code := range s.KissCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		result += fmt.Sprintf(" - kiss %s %d", code, s.KissCodes[code])
+	}
+	if s.Unanswered != 0 {
+		result += fmt.Sprintf(" - %d unanswered", s.Unanswered)
+	}
+	return result
+}
+
+// ntpRequestKey finds the request a reply answers: the reply carries the
+// request's transmit timestamp as its origin timestamp.
+type ntpRequestKey struct {
+	client   flow.Endpoint
+	server   flow.Endpoint
+	transmit packet.NtpTimestamp
+}
+
+// NtpAnalyzer pairs NTP client requests with server replies.
+type NtpAnalyzer struct {
+	mutex   sync.Mutex
+	pending map[ntpRequestKey]time.Time
+	servers map[flow.Endpoint]*NtpServer
+	Timeout time.Duration
+}
+
+func NewNtpAnalyzer() *NtpAnalyzer {
+	return &NtpAnalyzer{
+		pending: make(map[ntpRequestKey]time.Time),
+		servers: make(map[flow.Endpoint]*NtpServer),
+		Timeout: DefaultNtpTimeout,
+	}
+}
+
+func findNtp(p packet.Parsable) (flow.Observation, packet.NtpPacket, bool) {
+	observation, ok := flow.Observe(p)
+	if !ok {
+		return observation, packet.NtpPacket{}, false
+	}
+	udp, ok := observation.Transport.(packet.UdpPacket)
+	if !ok || !udp.CanParseMore {
+		return observation, packet.NtpPacket{}, false
+	}
+	ntp, ok := udp.PacketParser.(packet.NtpPacket)
+	return observation, ntp, ok && ntp.Length >= packet.NtpHeaderSize
+}
+
+// Observe records the NTP message in p, if any, and returns the exchange a
+// server reply completes. Packets must be observed in capture order.
+func (a *NtpAnalyzer) Observe(p packet.Parsable, timestamp time.Time) (NtpExchange, bool) {
+	o, ntp, ok := findNtp(p)
+	if !ok {
+		return NtpExchange{}, false
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	header := ntp.Header
+	switch header.Mode {
+	case packet.NtpModeClient:
+		if len(a.pending) < maxPendingNtp {
+			a.pending[ntpRequestKey{client: o.Source, server: o.Destination, transmit: header.TransmitTime}] = timestamp
+		}
+		a.server(o.Destination).LastSeen = timestamp
+		return NtpExchange{}, false
+	case packet.NtpModeServer:
+	default:
+		return NtpExchange{}, false
+	}
+
+	s := a.server(o.Source)
+	s.LastSeen = timestamp
+	s.Replies++
+	s.Stratum = header.Stratum
+	s.ReferenceId = header.ReferenceIdToString()
+	s.LeapIndicator = header.LeapIndicator
+	if header.Stratum == 0 {
+		s.KissCodes[s.ReferenceId]++
+	}
+
+	key := ntpRequestKey{client: o.Destination, server: o.Source, transmit: header.OriginTime}
+	sent, ok := a.pending[key]
+	if !ok {
+		return NtpExchange{}, false
+	}
+	delete(a.pending, key)
+	if header.Stratum == 0 || header.ReceiveTime.IsZero() || header.TransmitTime.IsZero() {
+		return NtpExchange{}, false
+	}
+
+	e := NtpExchange{
+		Client:  o.Destination,
+		Server:  o.Source,
+		Sent:    sent,
+		Receive: header.ReceiveTime.Time(),
+		Reply:   header.TransmitTime.Time(),
+		Arrived: timestamp,
+		Stratum: header.Stratum,
+	}
+	e.Offset = (e.Receive.Sub(e.Sent) + e.Reply.Sub(e.Arrived)) / 2
+	e.Delay = e.Arrived.Sub(e.Sent) - e.Reply.Sub(e.Receive)
+	s.add(e)
+	return e, true
+}
+
+func (a *NtpAnalyzer) server(endpoint flow.Endpoint) *NtpServer {
+	s, ok := a.servers[endpoint]
+	if !ok {
+		s = &NtpServer{Server: endpoint, KissCodes: make(map[string]int)}
+		a.servers[endpoint] = s
+	}
+	return s
+}
+
+// Expire counts requests without a reply after Timeout as unanswered.
+func (a *NtpAnalyzer) Expire(now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for key, sent := range a.pending {
+		if now.Sub(sent) > a.Timeout {
+			a.servers[key.server].Unanswered++
+			delete(a.pending, key)
+		}
+	}
+}
+
+// Report lists every server seen, the largest absolute offset first.
+func (a *NtpAnalyzer) Report() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	all := make([]NtpServer, 0, len(a.servers))
+	for _, s := range a.servers {
+		all = append(all, *s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return absDuration(all[i].LastOffset) > absDuration(all[j].LastOffset)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "NTP analysis - %d servers\n", len(all))
+	for _, s := range all {
+		b.WriteString("  " + s.ToString() + "\n")
+	}
+	return b.String()
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,423 @@
+package analysis
+
+import (
+	"fmt"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Annotation names follow Wireshark's TCP expert info where one exists.
+const (
+	Retransmission      = "TCP Retransmission"
+	FastRetransmission  = "TCP Fast Retransmission"
+	SpuriousRetransmit  = "TCP Spurious Retransmission"
+	DupAck              = "TCP Dup ACK"
+	OutOfOrder          = "TCP Out-Of-Order"
+	PreviousNotCaptured = "TCP Previous segment not captured"
+	ZeroWindow          = "TCP ZeroWindow"
+	WindowFull          = "TCP Window Full"
+	KeepAlive           = "TCP Keep-Alive"
+	Reset               = "TCP Reset"
+)
+
+const (
+	// outOfOrderThreshold is used until an RTT is known, as Wireshark does.
+	outOfOrderThreshold = 3 * time.Millisecond
+	maxOutstanding      = 1024
+	DefaultIdleTimeout  = 5 * time.Minute
+	// maxReported is how many of the worst connections Report lists.
+	maxReported = 100
+)
+
+type segment struct {
+	end           uint32
+	sent          time.Time
+	retransmitted bool
+}
+
+// direction tracks what one side of the connection has sent.
+type direction struct {
+	seen        bool
+	nextSeq     uint32
+	lastAck     uint32
+	lastWindow  uint16
+	dupAcks     int
+	lastSegment time.Time
+	finSent     bool
+	outstanding []segment
+	// windowScale is the shift announced in the SYN, -1 when none was seen.
+	windowScale int
+}
+
+// window returns the advertised window in bytes. Scaling only applies when
+// both SYNs carried the option.
+func (d *direction) window(other *direction) uint32 {
+	if d.windowScale < 0 || other.windowScale < 0 {
+		return uint32(d.lastWindow)
+	}
+	return uint32(d.lastWindow) << uint(d.windowScale)
+}
+
+func windowScaleOption(options []byte) int {
+	for i := 0; i < len(options); {
+		kind := options[i]
+		switch {
+		case kind == 0:
+			return -1
+		case kind == 1:
+			i++
+			continue
+		case i+1 >= len(options) || options[i+1] < 2:
+			return -1
+		case kind == 3 && options[i+1] == 3 && i+2 < len(options):
+			return int(options[i+2])
+		}
+		i += int(options[i+1])
+	}
+	return -1
+}
+
+type Summary struct {
+	Key           flow.Key
+	HandshakeRtt  time.Duration
+	RttSamples    int
+	RttMin        time.Duration
+	RttMax        time.Duration
+	rttTotal      time.Duration
+	Events        map[string]int
+	ResetCause    string
+	LastSeen      time.Time
+	synTime       time.Time
+	synAckTime    time.Time
+	handshakeDone bool
+	synFromA      bool
+	directions    [2]direction
+}
+
+func (s *Summary) RttAverage() time.Duration {
+	if s.RttSamples == 0 {
+		return 0
+	}
+	return s.rttTotal / time.Duration(s.RttSamples)
+}
+
+func (s *Summary) addRtt(rtt time.Duration) {
+	if s.RttSamples == 0 || rtt < s.RttMin {
+		s.RttMin = rtt
+	}
+	if rtt > s.RttMax {
+		s.RttMax = rtt
+	}
+	s.RttSamples++
+	s.rttTotal += rtt
+}
+
+func (s *Summary) ToString() string {
+	result := fmt.Sprintf("%s - handshake rtt %s - rtt min/avg/max %s/%s/%s over %d samples",
+		s.Key.ToString(), s.HandshakeRtt, s.RttMin, s.RttAverage(), s.RttMax, s.RttSamples)
+
+	names := make([]string, 0, len(s.Events))
+	for name := range s.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result += fmt.Sprintf(" - %s %d", name, s.Events[name])
+	}
+	if s.ResetCause != "" {
+		result += fmt.Sprintf(" - reset: %s", s.ResetCause)
+	}
+	return result
+}
+
+type TcpAnalyzer struct {
+	mutex       sync.Mutex
+	connections map[flow.Key]*Summary
+	// worst keeps the finished connections with the most events for Report.
+	worst       []Summary
+	finished    int
+	IdleTimeout time.Duration
+}
+
+func NewTcpAnalyzer() *TcpAnalyzer {
+	return &TcpAnalyzer{
+		connections: make(map[flow.Key]*Summary),
+		IdleTimeout: DefaultIdleTimeout,
+	}
+}
+
+// Annotate analyses the TCP segment in p, if any, and returns p with the
+// segment's Annotations filled in, and the annotations. Segments must be
+// annotated in capture order.
+func (a *TcpAnalyzer) Annotate(p packet.Parsable, timestamp time.Time) (packet.Parsable, []string) {
+	observation, ok := flow.Observe(p)
+	if !ok {
+		return p, nil
+	}
+	tcp, ok := observation.Transport.(packet.TcpPacket)
+	if !ok {
+		return p, nil
+	}
+
+	tcp.Annotations = a.analyze(observation, tcp, timestamp)
+	if len(tcp.Annotations) == 0 {
+		return p, nil
+	}
+	return replaceTcp(p, tcp), tcp.Annotations
+}
+
+func replaceTcp(p packet.Parsable, tcp packet.TcpPacket) packet.Parsable {
+	switch layer := p.(type) {
+	case packet.EthernetPacket:
+		layer.PacketParser = replaceTcp(layer.PacketParser, tcp)
+		return layer
+	case packet.Ipv4Packet:
+		layer.PacketParser = tcp
+		return layer
+	}
+	return p
+}
+
+func (a *TcpAnalyzer) analyze(o flow.Observation, tcp packet.TcpPacket, timestamp time.Time) []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key, aToB := o.Key()
+	s, ok := a.connections[key]
+	if !ok {
+		s = &Summary{Key: key, Events: make(map[string]int)}
+		s.directions[0].windowScale = -1
+		s.directions[1].windowScale = -1
+		a.connections[key] = s
+	}
+	s.LastSeen = timestamp
+
+	sender, receiver := &s.directions[1], &s.directions[0]
+	if aToB {
+		sender, receiver = &s.directions[0], &s.directions[1]
+	}
+
+	header := tcp.Header
+	var annotations []string
+	note := func(annotation string) {
+		annotations = append(annotations, annotation)
+		s.Events[annotation]++
+	}
+
+	a.trackHandshake(s, header, aToB, timestamp)
+	if header.SYN {
+		sender.windowScale = windowScaleOption(header.RawOptions)
+	}
+
+	payload := uint32(len(tcp.RawPayload))
+	segmentLength := payload
+	if header.SYN {
+		segmentLength++
+	}
+	if header.FIN {
+		segmentLength++
+	}
+	seq := header.SequenceNumber
+	end := seq + segmentLength
+
+	if header.RST {
+		note(Reset)
+		s.ResetCause = resetCause(s, sender, receiver, header)
+	}
+
+	if sender.seen && segmentLength > 0 && !header.SYN {
+		switch {
+		case seqAfter(seq, sender.nextSeq):
+			note(PreviousNotCaptured)
+		case seqBefore(seq, sender.nextSeq):
+			if segmentLength == 1 && seq == sender.nextSeq-1 && !header.FIN {
+				note(KeepAlive)
+			} else if !seqAfter(end, receiver.lastAck) && receiver.seen {
+				note(SpuriousRetransmit)
+			} else if receiver.dupAcks >= 2 {
+				note(FastRetransmission)
+			} else if timestamp.Sub(sender.lastSegment) < outOfOrderLimit(s) {
+				note(OutOfOrder)
+			} else {
+				note(Retransmission)
+			}
+			markRetransmitted(sender, end)
+		}
+	}
+
+	if header.ACK && sender.seen && segmentLength == 0 && !header.RST && header.AckNumber == sender.lastAck &&
+		header.Window == sender.lastWindow && header.Window != 0 {
+		sender.dupAcks++
+		note(DupAck)
+	} else if header.ACK {
+		sender.dupAcks = 0
+	}
+
+	if header.Window == 0 && !header.RST && !header.SYN && !header.FIN {
+		note(ZeroWindow)
+	}
+
+	if receiver.seen && payload > 0 && end == receiver.lastAck+receiver.window(sender) {
+		note(WindowFull)
+	}
+
+	if header.ACK {
+		a.sampleRtt(s, receiver, header.AckNumber, timestamp)
+		sender.lastAck = header.AckNumber
+	}
+	sender.lastWindow = header.Window
+
+	if segmentLength > 0 && (!sender.seen || seqAfter(end, sender.nextSeq)) {
+		sender.outstanding = append(sender.outstanding, segment{end: end, sent: timestamp})
+		if len(sender.outstanding) > maxOutstanding {
+			sender.outstanding = sender.outstanding[1:]
+		}
+		sender.nextSeq = end
+	} else if !sender.seen {
+		sender.nextSeq = seq
+	}
+	if header.FIN {
+		sender.finSent = true
+	}
+	sender.lastSegment = timestamp
+	sender.seen = true
+
+	return annotations
+}
+
+func (a *TcpAnalyzer) trackHandshake(s *Summary, header packet.TcpHeader, aToB bool, timestamp time.Time) {
+	switch {
+	case header.SYN && !header.ACK:
+		s.synTime = timestamp
+		s.synFromA = aToB
+	case header.SYN && header.ACK && !s.synTime.IsZero() && aToB != s.synFromA:
+		s.synAckTime = timestamp
+	case header.ACK && !s.handshakeDone && !s.synAckTime.IsZero() && aToB == s.synFromA:
+		s.HandshakeRtt = timestamp.Sub(s.synTime)
+		s.handshakeDone = true
+	}
+}
+
+// sampleRtt takes an RTT sample from the oldest segment acknowledged by ack,
+// skipping retransmitted segments as in Karn's algorithm.
+func (a *TcpAnalyzer) sampleRtt(s *Summary, d *direction, ack uint32, timestamp time.Time) {
+	acked := 0
+	for _, seg := range d.outstanding {
+		if seqAfter(seg.end, ack) {
+			break
+		}
+		acked++
+		if acked == 1 && !seg.retransmitted {
+			s.addRtt(timestamp.Sub(seg.sent))
+		}
+	}
+	d.outstanding = d.outstanding[acked:]
+}
+
+func markRetransmitted(d *direction, end uint32) {
+	for i := range d.outstanding {
+		if !seqBefore(d.outstanding[i].end, end) {
+			d.outstanding[i].retransmitted = true
+			return
+		}
+	}
+}
+
+func outOfOrderLimit(s *Summary) time.Duration {
+	if s.RttSamples == 0 {
+		return outOfOrderThreshold
+	}
+	return s.RttMin
+}
+
+func resetCause(s *Summary, sender *direction, receiver *direction, header packet.TcpHeader) string {
+	switch {
+	case !s.synTime.IsZero() && s.synAckTime.IsZero():
+		return "connection refused, reset in answer to SYN"
+	case sender.finSent || receiver.finSent:
+		return "reset after FIN, data arrived after close"
+	case len(sender.outstanding) > 0 || len(receiver.outstanding) > 0:
+		return "abortive close with unacknowledged data"
+	case !header.ACK:
+		return "reset without ACK, connection unknown to sender"
+	}
+	return "abortive close"
+}
+
+// Expire moves connections idle for longer than IdleTimeout to the finished list.
+func (a *TcpAnalyzer) Expire(now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for key, s := range a.connections {
+		if now.Sub(s.LastSeen) > a.IdleTimeout {
+			a.finish(key, s)
+		}
+	}
+}
+
+func (a *TcpAnalyzer) finish(key flow.Key, s *Summary) {
+	delete(a.connections, key)
+	if len(s.Events) == 0 && s.RttSamples == 0 {
+		return
+	}
+	a.finished++
+	a.worst = append(a.worst, *s)
+	if len(a.worst) > 2*maxReported {
+		sortByEvents(a.worst)
+		a.worst = a.worst[:maxReported]
+	}
+}
+
+// Report lists the connections with the most events, both still open and
+// finished, out of every connection with events or RTT samples.
+func (a *TcpAnalyzer) Report() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	total := a.finished
+	all := append([]Summary(nil), a.worst...)
+	for _, s := range a.connections {
+		if len(s.Events) != 0 || s.RttSamples != 0 {
+			all = append(all, *s)
+			total++
+		}
+	}
+	sortByEvents(all)
+	if len(all) > maxReported {
+		all = all[:maxReported]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "TCP analysis - %d connections, worst %d shown\n", total, len(all))
+	for _, s := range all {
+		b.WriteString("  " + s.ToString() + "\n")
+	}
+	return b.String()
+}
+
+func sortByEvents(summaries []Summary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return eventCount(summaries[i]) > eventCount(summaries[j])
+	})
+}
+
+func eventCount(s Summary) int {
+	total := 0
+	for _, count := range s.Events {
+		total += count
+	}
+	return total
+}
+
+func seqBefore(a uint32, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+func seqAfter(a uint32, b uint32) bool {
+	return int32(a-b) > 0
+}
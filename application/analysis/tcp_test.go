@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"sniffer/application/flow"
+	"strings"
+	"testing"
+)
+
+func TestReportKeepsWorstConnections(t *testing.T) {
+	a := NewTcpAnalyzer()
+	const connections = 5 * maxReported
+	for i := 0; i < connections; i++ {
+		key := flow.Key{
+			A:        flow.Endpoint{Address: [4]byte{10, 0, byte(i >> 8), byte(i)}, Port: 1024},
+			B:        flow.Endpoint{Address: [4]byte{192, 168, 0, 1}, Port: 80},
+			Protocol: flow.IpProtocolTcp,
+		}
+		s := &Summary{Key: key, Events: map[string]int{Retransmission: i}, RttSamples: 1}
+		a.connections[key] = s
+		a.finish(key, s)
+		if len(a.worst) > 2*maxReported {
+			t.Fatalf("%d finished connections kept", len(a.worst))
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(a.Report(), "\n"), "\n")
+	if want := "TCP analysis - 500 connections, worst 100 shown"; lines[0] != want {
+		t.Fatalf("header %q, want %q", lines[0], want)
+	}
+	if len(lines) != 1+maxReported {
+		t.Fatalf("%d connections listed, want %d", len(lines)-1, maxReported)
+	}
+	if !strings.Contains(lines[1], Retransmission+" 499") {
+		t.Fatalf("worst connection listed first is %q", lines[1])
+	}
+	if !strings.Contains(lines[maxReported], Retransmission+" 400") {
+		t.Fatalf("last connection listed is %q", lines[maxReported])
+	}
+}
@@ -0,0 +1,82 @@
+package anonymize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"errors"
+	"sync"
+)
+
+const KeySize = 32
+
+// CryptoPan is the prefix-preserving address anonymization of Xu, Fan,
+// Ammar and Moon: two addresses sharing a k bit prefix map to addresses
+// sharing a k bit prefix. The first half of the key is the AES key, the
+// second half is encrypted to form the padding.
+type CryptoPan struct {
+	block cipher.Block
+	pad   [aes.BlockSize]byte
+	mutex sync.Mutex
+	cache map[string][]byte
+}
+
+func NewCryptoPan(key []byte) (*CryptoPan, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("crypto-pan needs a 32 byte key")
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	c := &CryptoPan{block: block, cache: make(map[string][]byte)}
+	block.Encrypt(c.pad[:], key[16:])
+	return c, nil
+}
+
+// KeyFromPassphrase derives a key so a trace can be anonymized the same way
+// twice without storing binary key material.
+func KeyFromPassphrase(passphrase string) []byte {
+	sum := sha512.Sum512([]byte(passphrase))
+	return sum[:KeySize]
+}
+
+// Anonymize maps an address of up to 16 bytes, e.g. IPv4 or MAC, to an
+// address of the same length. The mapping is consistent for the lifetime of
+// the key.
+func (c *CryptoPan) Anonymize(address []byte) []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cached, ok := c.cache[string(address)]; ok {
+		return cached
+	}
+
+	bits := len(address) * 8
+	var input, output [aes.BlockSize]byte
+	otp := make([]byte, len(address))
+	for position := 0; position < bits; position++ {
+		// Input is the first position bits of the address followed by the
+		// pad.
+		input = c.pad
+		for i := 0; i < len(address); i++ {
+			keep := position - i*8
+			switch {
+			case keep >= 8:
+				input[i] = address[i]
+			case keep > 0:
+				mask := byte(0xff << uint(8-keep))
+				input[i] = address[i]&mask | c.pad[i]&^mask
+			}
+		}
+		c.block.Encrypt(output[:], input[:])
+		otp[position/8] |= (output[0] >> 7) << uint(7-position%8)
+	}
+
+	result := make([]byte, len(address))
+	for i := range address {
+		result[i] = address[i] ^ otp[i]
+	}
+	c.cache[string(address)] = result
+	return result
+}
@@ -0,0 +1,88 @@
+package anonymize
+
+import (
+	"bytes"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// sharedPrefix is the number of leading bits a and b have in common.
+func sharedPrefix(a []byte, b []byte) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return i*8 + bits.LeadingZeros8(a[i]^b[i])
+		}
+	}
+	return len(a) * 8
+}
+
+func TestCryptoPanPreservesPrefixes(t *testing.T) {
+	pan, err := NewCryptoPan(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		a, b []byte
+	}{
+		{"same /24", []byte{192, 168, 1, 10}, []byte{192, 168, 1, 200}},
+		{"same /16", []byte{192, 168, 1, 10}, []byte{192, 168, 77, 10}},
+		{"differ in the first bit", []byte{10, 0, 0, 1}, []byte{138, 0, 0, 1}},
+		{"differ in the last bit", []byte{10, 0, 0, 1}, []byte{10, 0, 0, 0}},
+		{"identical", []byte{172, 16, 0, 1}, []byte{172, 16, 0, 1}},
+		{"mac addresses", []byte{0x02, 0, 0, 0, 0, 1}, []byte{0x02, 0, 0, 0, 0x80, 1}},
+	}
+	for _, test := range tests {
+		want := sharedPrefix(test.a, test.b)
+		got := sharedPrefix(pan.Anonymize(test.a), pan.Anonymize(test.b))
+		if got != want {
+			t.Errorf("%s: anonymized addresses share %d bits, want %d", test.name, got, want)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		a, b := make([]byte, 4), make([]byte, 4)
+		r.Read(a)
+		copy(b, a)
+		// Flip one bit and randomise everything after it.
+		bit := r.Intn(32)
+		b[bit/8] ^= 0x80 >> uint(bit%8)
+		for j := bit + 1; j < 32; j++ {
+			if r.Intn(2) == 1 {
+				b[j/8] ^= 0x80 >> uint(j%8)
+			}
+		}
+		if got := sharedPrefix(pan.Anonymize(a), pan.Anonymize(b)); got != bit {
+			t.Fatalf("%v and %v share %d bits, anonymized they share %d", a, b, bit, got)
+		}
+	}
+}
+
+func TestCryptoPanIsConsistentPerKey(t *testing.T) {
+	address := []byte{10, 1, 2, 3}
+	first, _ := NewCryptoPan(testKey)
+	second, _ := NewCryptoPan(testKey)
+	other, _ := NewCryptoPan(KeyFromPassphrase("other"))
+
+	anonymized := first.Anonymize(address)
+	if bytes.Equal(anonymized, address) {
+		t.Fatalf("%v was left unchanged", address)
+	}
+	if got := second.Anonymize(address); !bytes.Equal(got, anonymized) {
+		t.Fatalf("same key gave %v and %v", anonymized, got)
+	}
+	if got := other.Anonymize(address); bytes.Equal(got, anonymized) {
+		t.Fatalf("different keys both gave %v", got)
+	}
+	if !bytes.Equal(address, []byte{10, 1, 2, 3}) {
+		t.Fatalf("input changed to %v", address)
+	}
+}
+
+func TestCryptoPanRejectsShortKeys(t *testing.T) {
+	if _, err := NewCryptoPan(testKey[:16]); err == nil {
+		t.Fatal("a 16 byte key was accepted")
+	}
+}
@@ -0,0 +1,101 @@
+package anonymize
+
+import "encoding/binary"
+
+const (
+	dnsHeaderSize = 12
+	dnsTypeA      = 1
+	dnsTypeNs     = 2
+	dnsTypeCname  = 5
+	dnsTypeSoa    = 6
+	dnsTypePtr    = 12
+	dnsTypeMx     = 15
+	dnsTypeAaaa   = 28
+	dnsTypeSrv    = 33
+)
+
+// scrubDns overwrites every label of every name in a DNS message with 'x',
+// keeping label lengths and compression pointers so the message still
+// parses, and passes A and AAAA record addresses to anonymize. Messages are edited
+// in place as far as they can be walked; a truncated message is scrubbed up
+// to where it ends.
+func scrubDns(message []byte, anonymize func([]byte)) {
+	if len(message) < dnsHeaderSize {
+		return
+	}
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	records := int(binary.BigEndian.Uint16(message[6:])) + int(binary.BigEndian.Uint16(message[8:])) + int(binary.BigEndian.Uint16(message[10:]))
+
+	offset := dnsHeaderSize
+	for i := 0; i < questions; i++ {
+		var ok bool
+		if offset, ok = scrubName(message, offset); !ok || offset+4 > len(message) {
+			return
+		}
+		offset += 4
+	}
+	for i := 0; i < records; i++ {
+		var ok bool
+		if offset, ok = scrubName(message, offset); !ok || offset+10 > len(message) {
+			return
+		}
+		recordType := binary.BigEndian.Uint16(message[offset:])
+		length := int(binary.BigEndian.Uint16(message[offset+8:]))
+		offset += 10
+		end := offset + length
+		if end > len(message) {
+			end = len(message)
+		}
+		data := message[offset:end]
+
+		switch recordType {
+		case dnsTypeA:
+			if len(data) == 4 {
+				anonymize(data)
+			}
+		case dnsTypeAaaa:
+			if len(data) == 16 {
+				anonymize(data)
+			}
+		case dnsTypeNs, dnsTypeCname, dnsTypePtr:
+			scrubName(message[:end], offset)
+		case dnsTypeMx:
+			scrubName(message[:end], offset+2)
+		case dnsTypeSrv:
+			scrubName(message[:end], offset+6)
+		case dnsTypeSoa:
+			if next, ok := scrubName(message[:end], offset); ok {
+				scrubName(message[:end], next)
+			}
+		default:
+			// TXT and unknown records may carry anything.
+			for i := range data {
+				data[i] = 0
+			}
+		}
+		offset += length
+	}
+}
+
+// scrubName masks the labels of the name at offset and returns the offset
+// just past it.
+func scrubName(message []byte, offset int) (int, bool) {
+	for offset < len(message) {
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			return offset + 1, true
+		case length&0xc0 == 0xc0:
+			// The name continues elsewhere and is scrubbed where it is stored.
+			return offset + 2, offset+2 <= len(message)
+		case length&0xc0 != 0:
+			return offset, false
+		}
+		offset++
+		for i := 0; i < length && offset < len(message); i++ {
+			message[offset] = 'x'
+			offset++
+		}
+	}
+	return offset, false
+}
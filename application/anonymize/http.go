@@ -0,0 +1,66 @@
+package anonymize
+
+import "bytes"
+
+var httpMethods = [][]byte{
+	[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("HEAD "), []byte("DELETE "),
+	[]byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "), []byte("TRACE "), []byte("HTTP/1."),
+}
+
+func isHttp(payload []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(payload, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubHttp masks the request target and every header value of an HTTP/1
+// message with '*', keeping the method, status line, header names and all
+// lengths. It returns where the body starts, or len(payload) when the header
+// block does not end inside this segment.
+func scrubHttp(payload []byte) int {
+	lineEnd := bytes.Index(payload, []byte("\r\n"))
+	if lineEnd < 0 {
+		lineEnd = len(payload)
+	}
+	if !bytes.HasPrefix(payload, []byte("HTTP/")) {
+		// Request line: METHOD target VERSION.
+		line := payload[:lineEnd]
+		if first := bytes.IndexByte(line, ' '); first >= 0 {
+			last := bytes.LastIndexByte(line, ' ')
+			if last <= first {
+				last = len(line)
+			}
+			mask(line[first+1 : last])
+		}
+	}
+
+	offset := lineEnd + 2
+	for offset < len(payload) {
+		end := bytes.Index(payload[offset:], []byte("\r\n"))
+		if end == 0 {
+			return offset + 2
+		}
+		if end < 0 {
+			end = len(payload) - offset
+		}
+		line := payload[offset : offset+end]
+		if colon := bytes.IndexByte(line, ':'); colon >= 0 {
+			mask(line[colon+1:])
+		} else {
+			mask(line)
+		}
+		offset += end + 2
+	}
+	return len(payload)
+}
+
+func mask(data []byte) {
+	for i := range data {
+		if data[i] != ' ' {
+			data[i] = '*'
+		}
+	}
+}
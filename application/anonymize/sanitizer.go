@@ -0,0 +1,361 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	PayloadKeep     = "keep"
+	PayloadTruncate = "truncate"
+	PayloadZero     = "zero"
+)
+
+type Config struct {
+	Key []byte
+	// Payload is keep, truncate or zero.
+	Payload string
+	// KeepBytes is how much of each payload survives truncation.
+	KeepBytes int
+	StripDns  bool
+	StripHttp bool
+}
+
+type Stats struct {
+	Frames     int
+	Malformed  int
+	Truncated  int
+	DnsScrubs  int
+	HttpScrubs int
+}
+
+func (s Stats) ToString() string {
+	return fmt.Sprintf("%d frames sanitized, %d truncated, %d DNS and %d HTTP messages scrubbed, %d left undecoded",
+		s.Frames, s.Truncated, s.DnsScrubs, s.HttpScrubs, s.Malformed)
+}
+
+// Sanitizer scrubs frames before a trace leaves the building: addresses go
+// through Crypto-PAn, payloads are cut or zeroed, DNS names and HTTP headers
+// are masked and the checksums are recomputed.
+type Sanitizer struct {
+	config Config
+	pan    *CryptoPan
+	Stats  Stats
+}
+
+func NewSanitizer(config Config) (*Sanitizer, error) {
+	pan, err := NewCryptoPan(config.Key)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Payload {
+	case "":
+		config.Payload = PayloadKeep
+	case PayloadKeep, PayloadTruncate, PayloadZero:
+	default:
+		return nil, fmt.Errorf("payload must be %s, %s or %s", PayloadKeep, PayloadTruncate, PayloadZero)
+	}
+	return &Sanitizer{config: config, pan: pan}, nil
+}
+
+// anonymizeMac leaves broadcast and all-zero addresses alone and keeps the
+// group bit, so multicast stays multicast.
+func (s *Sanitizer) anonymizeMac(mac []byte) {
+	if isAll(mac, 0xff) || isAll(mac, 0) {
+		return
+	}
+	group := mac[0] & 1
+	copy(mac, s.pan.Anonymize(mac))
+	mac[0] = mac[0]&^1 | group
+}
+
+func (s *Sanitizer) anonymizeIp(ip []byte) {
+	if isAll(ip, 0xff) || isAll(ip, 0) {
+		return
+	}
+	copy(ip, s.pan.Anonymize(ip))
+}
+
+func isAll(data []byte, value byte) bool {
+	for _, b := range data {
+		if b != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Sanitize returns the scrubbed copy of frame, which is shorter than the
+// original when payloads are truncated.
+func (s *Sanitizer) Sanitize(frame []byte) (result []byte) {
+	s.Stats.Frames++
+	result = append([]byte(nil), frame...)
+	if len(result) < packet.HeaderLength {
+		s.Stats.Malformed++
+		return result
+	}
+	s.anonymizeMac(result[packet.DestMacOffset : packet.DestMacOffset+packet.DestMacSize])
+	s.anonymizeMac(result[packet.SrcMacOffset : packet.SrcMacOffset+packet.SrcMacSize])
+
+	etherType := binary.BigEndian.Uint16(result[packet.TypeOffset:])
+	body := result[packet.HeaderLength:]
+	switch etherType {
+	case packet.ARP.Value:
+		s.sanitizeArp(body)
+		return result
+	case packet.IPV4.Value:
+		length, ok := s.sanitizeIpv4(body, frame[packet.HeaderLength:])
+		if !ok {
+			s.Stats.Malformed++
+		}
+		return result[:packet.HeaderLength+length]
+	}
+	// Unknown EtherTypes are treated as opaque payload.
+	return s.sanitizePayload(result, packet.HeaderLength)
+}
+
+// SanitizeIpv4 is Sanitize for a packet that starts at its IPv4 header,
+// as on raw IP links. Anything but an IPv4 header comes back empty, since
+// there is no telling where its addresses are.
+func (s *Sanitizer) SanitizeIpv4(ip []byte) []byte {
+	s.Stats.Frames++
+	if len(ip) < packet.Ipv4MinHeaderSize || ip[packet.Ipv4VersionAndIhlOffset]>>4 != 4 {
+		s.Stats.Malformed++
+		return nil
+	}
+	result := append([]byte(nil), ip...)
+	length, ok := s.sanitizeIpv4(result, ip)
+	if !ok {
+		s.Stats.Malformed++
+	}
+	return result[:length]
+}
+
+// SanitizeLink scrubs a frame of any link type. It returns nil for frames
+// it cannot anonymize, which must then be dropped rather than passed on in
+// the clear.
+func (s *Sanitizer) SanitizeLink(frame []byte, linkType layers.LinkType) []byte {
+	switch linkType {
+	case layers.LinkTypeEthernet:
+		return s.Sanitize(frame)
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4:
+		return s.SanitizeIpv4(frame)
+	}
+	s.Stats.Frames++
+	s.Stats.Malformed++
+	return nil
+}
+
+func (s *Sanitizer) sanitizeArp(arp []byte) {
+	if len(arp) < packet.ArpHeaderLength {
+		s.Stats.Malformed++
+		return
+	}
+	s.anonymizeMac(arp[packet.ArpSourceMacAddressOffset : packet.ArpSourceMacAddressOffset+packet.ArpSourceMacAddressSize])
+	s.anonymizeMac(arp[packet.ArpDestHardwareAddressOffset : packet.ArpDestHardwareAddressOffset+packet.ArpDestHardwareAddressSize])
+	s.anonymizeIp(arp[packet.ArpSourceProtocolAddressOffset : packet.ArpSourceProtocolAddressOffset+packet.ArpSourceProtocolAddressSize])
+	s.anonymizeIp(arp[packet.ArpDestProtocolAddressOffset : packet.ArpDestProtocolAddressOffset+packet.ArpDestProtocolAddressSize])
+}
+
+// sanitizeIpv4 scrubs an IPv4 packet in place and returns how many of its
+// bytes to keep. original is the untouched copy, needed to adjust checksums
+// that cannot be recomputed.
+func (s *Sanitizer) sanitizeIpv4(ip []byte, original []byte) (int, bool) {
+	if len(ip) < packet.Ipv4MinHeaderSize {
+		return len(ip), false
+	}
+	headerLength := int(ip[packet.Ipv4VersionAndIhlOffset]&0x0f) * 4
+	if headerLength < packet.Ipv4MinHeaderSize || headerLength > len(ip) {
+		return len(ip), false
+	}
+	header := ip[:headerLength]
+	oldAddresses := original[packet.Ipv4SourceAddressOffset : packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize]
+	s.anonymizeIp(header[packet.Ipv4SourceAddressOffset : packet.Ipv4SourceAddressOffset+packet.Ipv4SourceAddressSize])
+	s.anonymizeIp(header[packet.Ipv4DestAddressOffset : packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize])
+	// Options such as record route carry addresses too, drop their contents.
+	for i := packet.Ipv4OptionsOffset; i < headerLength; i++ {
+		header[i] = 0
+	}
+	setChecksum(header, packet.Ipv4HeaderChecksumOffset, 0)
+	setChecksum(header, packet.Ipv4HeaderChecksumOffset, packet.InternetChecksum(header))
+
+	totalLength := int(binary.BigEndian.Uint16(header[packet.Ipv4TotalLengthOffset:]))
+	segment := ip[headerLength:]
+	// complete is false when the capture was cut short, in which case the
+	// transport checksum can only be adjusted for what changed.
+	complete := totalLength == len(ip)
+	if totalLength >= headerLength && totalLength < len(ip) {
+		// Ethernet padding.
+		segment = ip[headerLength:totalLength]
+		complete = true
+	}
+	originalSegment := original[headerLength : headerLength+len(segment)]
+	fragmentOffset := binary.BigEndian.Uint16(header[packet.Ipv4FlagsAndFragmentOffset:]) & 0x1fff
+	if fragmentOffset != 0 {
+		return headerLength + len(s.sanitizePayload(segment, 0)), true
+	}
+
+	addresses := header[packet.Ipv4SourceAddressOffset : packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize]
+	protocol := header[packet.Ipv4ProtocolOffset]
+	fix := func(kept int, checksumOffset int, pseudo bool) {
+		fixChecksum(segment, originalSegment, kept, checksumOffset, complete, protocol, addresses, oldAddresses, pseudo)
+	}
+
+	switch protocol {
+	case flow.IpProtocolTcp:
+		if len(segment) < packet.TcpMinHeaderSize {
+			return len(ip), false
+		}
+		tcpHeaderLength := int(segment[packet.TcpDataOffsetAndReservedBitsOffset]>>4) * 4
+		if tcpHeaderLength < packet.TcpMinHeaderSize || tcpHeaderLength > len(segment) {
+			return len(ip), false
+		}
+		kept := s.sanitizeTcpPayload(segment, tcpHeaderLength)
+		fix(kept, packet.TcpChecksumOffset, true)
+		return headerLength + kept, true
+
+	case flow.IpProtocolUdp:
+		if len(segment) < packet.UdpHeaderSize {
+			return len(ip), false
+		}
+		kept := s.sanitizeUdpPayload(segment)
+		// Zero means the sender did not compute a checksum.
+		if binary.BigEndian.Uint16(segment[packet.UdpChecksumOffset:]) != 0 {
+			fix(kept, packet.UdpChecksumOffset, true)
+		}
+		return headerLength + kept, true
+
+	case flow.IpProtocolIcmp:
+		if len(segment) < packet.IcmpV4HeaderSize {
+			return len(ip), false
+		}
+		if isIcmpError(segment[0]) && len(segment) >= icmpErrorHeaderSize {
+			s.anonymizeEmbeddedIpv4(segment[icmpErrorHeaderSize:])
+		}
+		kept := packet.IcmpV4HeaderSize + len(s.sanitizePayload(segment[packet.IcmpV4HeaderSize:], 0))
+		fix(kept, packet.IcmpV4ChecksumOffset, false)
+		return headerLength + kept, true
+	}
+	return headerLength + len(s.sanitizePayload(segment, 0)), true
+}
+
+// icmpErrorHeaderSize covers type, code, checksum and the unused word that
+// precede the quoted datagram.
+const icmpErrorHeaderSize = 8
+
+func isIcmpError(icmpType byte) bool {
+	switch icmpType {
+	case 3, 4, 5, 11, 12:
+		return true
+	}
+	return false
+}
+
+// anonymizeEmbeddedIpv4 rewrites the addresses of the datagram quoted in an
+// ICMP error, which would otherwise leak the original endpoints.
+func (s *Sanitizer) anonymizeEmbeddedIpv4(ip []byte) {
+	if len(ip) < packet.Ipv4MinHeaderSize {
+		return
+	}
+	s.anonymizeIp(ip[packet.Ipv4SourceAddressOffset : packet.Ipv4SourceAddressOffset+packet.Ipv4SourceAddressSize])
+	s.anonymizeIp(ip[packet.Ipv4DestAddressOffset : packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize])
+	headerLength := int(ip[packet.Ipv4VersionAndIhlOffset]&0x0f) * 4
+	if headerLength >= packet.Ipv4MinHeaderSize && headerLength <= len(ip) {
+		setChecksum(ip, packet.Ipv4HeaderChecksumOffset, 0)
+		setChecksum(ip, packet.Ipv4HeaderChecksumOffset, packet.InternetChecksum(ip[:headerLength]))
+	}
+}
+
+func (s *Sanitizer) sanitizeTcpPayload(segment []byte, headerLength int) int {
+	// TCP options stay, analysis tools depend on them.
+	payload := segment[headerLength:]
+	start := 0
+	if s.config.StripHttp && isHttp(payload) {
+		start = scrubHttp(payload)
+		s.Stats.HttpScrubs++
+	}
+	if s.config.StripDns && isDnsPort(segment) && len(payload) > 2 {
+		// DNS over TCP carries a two byte length prefix.
+		scrubDns(payload[2:], s.anonymizeIp)
+		s.Stats.DnsScrubs++
+		return len(segment)
+	}
+	return headerLength + start + len(s.sanitizePayload(payload[start:], 0))
+}
+
+func (s *Sanitizer) sanitizeUdpPayload(segment []byte) int {
+	payload := segment[packet.UdpHeaderSize:]
+	if s.config.StripDns && isDnsPort(segment) {
+		scrubDns(payload, s.anonymizeIp)
+		s.Stats.DnsScrubs++
+		return len(segment)
+	}
+	return packet.UdpHeaderSize + len(s.sanitizePayload(payload, 0))
+}
+
+// isDnsPort looks at the port pair shared by the TCP and UDP headers.
+func isDnsPort(segment []byte) bool {
+	return binary.BigEndian.Uint16(segment[0:]) == 53 || binary.BigEndian.Uint16(segment[2:]) == 53
+}
+
+// sanitizePayload applies the payload policy to data[start:] and returns
+// what is left of data.
+func (s *Sanitizer) sanitizePayload(data []byte, start int) []byte {
+	payload := data[start:]
+	switch s.config.Payload {
+	case PayloadZero:
+		for i := range payload {
+			payload[i] = 0
+		}
+	case PayloadTruncate:
+		if len(payload) > s.config.KeepBytes {
+			s.Stats.Truncated++
+			return data[:start+s.config.KeepBytes]
+		}
+	}
+	return data
+}
+
+// fixChecksum recomputes a transport checksum when the whole segment is
+// still present. Otherwise it adjusts the captured checksum for the bytes
+// that changed, which keeps it right for the bytes that were never captured.
+func fixChecksum(segment []byte, original []byte, kept int, offset int, complete bool, protocol byte, addresses []byte, oldAddresses []byte, pseudo bool) {
+	if len(segment) < offset+2 {
+		return
+	}
+	var checksum uint16
+	if complete && kept == len(segment) {
+		setChecksum(segment, offset, 0)
+		if pseudo {
+			checksum = packet.TransportChecksum(addresses[:4], addresses[4:], protocol, segment)
+		} else {
+			checksum = packet.InternetChecksum(segment)
+		}
+	} else {
+		checksum = binary.BigEndian.Uint16(segment[offset:])
+		if pseudo {
+			checksum = packet.AdjustChecksum(checksum, oldAddresses, addresses)
+		}
+		checksum = packet.AdjustChecksum(checksum, evenLength(original[:kept]), evenLength(segment[:kept]))
+	}
+	if checksum == 0 && protocol == flow.IpProtocolUdp {
+		checksum = 0xffff
+	}
+	setChecksum(segment, offset, checksum)
+}
+
+// evenLength pads odd data with the zero byte the checksum implies.
+func evenLength(data []byte) []byte {
+	if len(data)%2 == 0 {
+		return data
+	}
+	return append(append([]byte(nil), data...), 0)
+}
+
+func setChecksum(data []byte, offset int, checksum uint16) {
+	binary.BigEndian.PutUint16(data[offset:], checksum)
+}
@@ -0,0 +1,155 @@
+package anonymize
+
+import (
+	"bytes"
+	"sniffer/application/packet"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+var testKey = KeyFromPassphrase("test")
+
+func newTestSanitizer(t *testing.T, config Config) *Sanitizer {
+	t.Helper()
+	config.Key = testKey
+	s, err := NewSanitizer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// udpPacket is an IPv4 datagram from 10.0.0.1:1024 to 10.0.0.2:53 with a
+// valid UDP checksum.
+func udpPacket(payload []byte) []byte {
+	ip := []byte{
+		0x45, 0, 0, 0, 0, 1, 0, 0, 64, 17, 0, 0,
+		10, 0, 0, 1,
+		10, 0, 0, 2,
+		0x04, 0x00, 0x00, 0x35, 0, 0, 0, 0,
+	}
+	ip = append(ip, payload...)
+	ip[2], ip[3] = byte(len(ip)>>8), byte(len(ip))
+	udpLength := len(ip) - 20
+	ip[24], ip[25] = byte(udpLength>>8), byte(udpLength)
+	setChecksum(ip, 10, packet.InternetChecksum(ip[:20]))
+	setChecksum(ip, 26, packet.TransportChecksum(ip[12:16], ip[16:20], 17, ip[20:]))
+	return ip
+}
+
+func TestSanitizeLinkRawIpv4(t *testing.T) {
+	s := newTestSanitizer(t, Config{})
+	ip := udpPacket([]byte("hello"))
+	result := s.SanitizeLink(ip, layers.LinkTypeRaw)
+	if len(result) != len(ip) {
+		t.Fatalf("got %d bytes, want %d", len(result), len(ip))
+	}
+	if bytes.Equal(result[12:16], ip[12:16]) || bytes.Equal(result[16:20], ip[16:20]) {
+		t.Fatalf("addresses left in clear: % x", result[12:20])
+	}
+	if !bytes.Equal(result[:10], ip[:10]) {
+		t.Fatalf("header before the checksum changed: % x", result[:10])
+	}
+}
+
+func TestSanitizeLinkRefusesUnknownLinks(t *testing.T) {
+	s := newTestSanitizer(t, Config{})
+	ip := udpPacket(nil)
+	for _, test := range []struct {
+		name     string
+		data     []byte
+		linkType layers.LinkType
+	}{
+		{"linux cooked", ip, layers.LinkTypeLinuxSLL},
+		{"ipv6 on a raw link", append([]byte{0x60}, ip[1:]...), layers.LinkTypeRaw},
+		{"short ipv4", ip[:16], layers.LinkTypeRaw},
+	} {
+		if result := s.SanitizeLink(test.data, test.linkType); result != nil {
+			t.Errorf("%s: got % x, want nil", test.name, result)
+		}
+	}
+}
+
+// transportChecksum is the UDP checksum segment would carry between the
+// addresses, computed from scratch.
+func transportChecksum(addresses []byte, segment []byte) uint16 {
+	zeroed := append([]byte(nil), segment...)
+	setChecksum(zeroed, 6, 0)
+	checksum := packet.TransportChecksum(addresses[:4], addresses[4:], 17, zeroed)
+	if checksum == 0 {
+		checksum = 0xffff
+	}
+	return checksum
+}
+
+func TestSanitizeChecksums(t *testing.T) {
+	payload := []byte("an odd length payload")
+	ip := udpPacket(payload)
+	tests := []struct {
+		name    string
+		config  Config
+		capture []byte
+		// recomputed is true when the whole datagram survives, so the
+		// checksum covers the bytes in the result. Otherwise it must be
+		// the checksum of the original datagram with the new addresses.
+		recomputed bool
+	}{
+		{"keep", Config{}, ip, true},
+		{"zero", Config{Payload: PayloadZero}, ip, true},
+		{"truncate", Config{Payload: PayloadTruncate, KeepBytes: 5}, ip, false},
+		{"cut short by the capture", Config{}, ip[:len(ip)-3], false},
+	}
+	for _, test := range tests {
+		s := newTestSanitizer(t, test.config)
+		result := s.SanitizeIpv4(test.capture)
+		if packet.InternetChecksum(result[:20]) != 0 {
+			t.Errorf("%s: bad IP header checksum % x", test.name, result[:20])
+		}
+		got := uint16(result[26])<<8 | uint16(result[27])
+		want := transportChecksum(result[12:20], ip[20:])
+		if test.recomputed {
+			want = transportChecksum(result[12:20], result[20:])
+		}
+		if got != want {
+			t.Errorf("%s: UDP checksum %04x, want %04x", test.name, got, want)
+		}
+	}
+}
+
+func TestSanitizeTruncation(t *testing.T) {
+	payload := []byte("0123456789")
+	ip := udpPacket(payload)
+	tests := []struct {
+		keepBytes int
+		length    int
+		truncated int
+	}{
+		{0, 28, 1},
+		{4, 32, 1},
+		{len(payload), len(ip), 0},
+		{100, len(ip), 0},
+	}
+	for _, test := range tests {
+		s := newTestSanitizer(t, Config{Payload: PayloadTruncate, KeepBytes: test.keepBytes})
+		result := s.SanitizeIpv4(ip)
+		if len(result) != test.length {
+			t.Errorf("keep %d: got %d bytes, want %d", test.keepBytes, len(result), test.length)
+		}
+		if s.Stats.Truncated != test.truncated {
+			t.Errorf("keep %d: %d truncated, want %d", test.keepBytes, s.Stats.Truncated, test.truncated)
+		}
+		if !bytes.Equal(result[2:4], ip[2:4]) {
+			t.Errorf("keep %d: total length changed to % x", test.keepBytes, result[2:4])
+		}
+		if !bytes.Equal(result[28:], payload[:len(result)-28]) {
+			t.Errorf("keep %d: kept payload %q", test.keepBytes, result[28:])
+		}
+	}
+
+	s := newTestSanitizer(t, Config{Payload: PayloadZero})
+	result := s.SanitizeIpv4(ip)
+	if !bytes.Equal(result[28:], make([]byte, len(payload))) {
+		t.Errorf("zeroed payload is %q", result[28:])
+	}
+}
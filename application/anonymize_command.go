@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"io"
+	"os"
+	"sniffer/application/anonymize"
+	"strings"
+)
+
+// runAnonymize implements "sniffer anonymize [flags] in.pcap out.pcap".
+func runAnonymize(args []string) int {
+	flags := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	passphrase := flags.String("key", "", "passphrase the address mapping is derived from")
+	keyFile := flags.String("key-file", "", "file holding a 32 byte key, raw or as 64 hex digits")
+	payload := flags.String("payload", anonymize.PayloadKeep, "what to do with payloads: keep, truncate or zero")
+	keepBytes := flags.Int("keep-bytes", 0, "payload bytes kept by -payload truncate")
+	stripDns := flags.Bool("strip-dns", false, "mask names and anonymize addresses inside DNS messages")
+	stripHttp := flags.Bool("strip-http", false, "mask HTTP request targets and header values")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer anonymize [flags] in.pcap out.pcap")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 || (*passphrase == "") == (*keyFile == "") {
+		flags.Usage()
+		return 2
+	}
+
+	key := anonymize.KeyFromPassphrase(*passphrase)
+	if *keyFile != "" {
+		var err error
+		if key, err = readKey(*keyFile); err != nil {
+			fmt.Println("anonymize:", err)
+			return 1
+		}
+	}
+	sanitizer, err := anonymize.NewSanitizer(anonymize.Config{
+		Key:       key,
+		Payload:   *payload,
+		KeepBytes: *keepBytes,
+		StripDns:  *stripDns,
+		StripHttp: *stripHttp,
+	})
+	if err != nil {
+		fmt.Println("anonymize:", err)
+		return 1
+	}
+
+	input, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Println("anonymize:", err)
+		return 1
+	}
+	defer input.Close()
+	reader, err := pcapgo.NewReader(input)
+	if err != nil {
+		fmt.Println("anonymize:", err)
+		return 1
+	}
+	if reader.LinkType() != layers.LinkTypeEthernet {
+		fmt.Println("anonymize: only Ethernet captures are supported, got", reader.LinkType())
+		return 1
+	}
+
+	output, err := os.Create(flags.Arg(1))
+	if err != nil {
+		fmt.Println("anonymize:", err)
+		return 1
+	}
+	defer output.Close()
+	writer := pcapgo.NewWriterNanos(output)
+	if err := writer.WriteFileHeader(reader.Snaplen(), reader.LinkType()); err != nil {
+		fmt.Println("anonymize:", err)
+		return 1
+	}
+
+	for {
+		data, ci, err := reader.ReadPacketData()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Println("anonymize:", err)
+			return 1
+		}
+		data = sanitizer.Sanitize(data)
+		// Truncated frames keep their original length, as if captured with a
+		// smaller snap length.
+		ci.CaptureLength = len(data)
+		if err := writer.WritePacket(ci, data); err != nil {
+			fmt.Println("anonymize:", err)
+			return 1
+		}
+	}
+	fmt.Println(sanitizer.Stats.ToString())
+	return 0
+}
+
+func readKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == anonymize.KeySize {
+		return data, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != anonymize.KeySize {
+		return nil, fmt.Errorf("%s does not hold a %d byte key", path, anonymize.KeySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+	"testing"
+)
+
+var frames = Frames(4096, 256, 512)
+
+// BenchmarkDecode compares the classic ParseFactoryMethod decoder with the
+// reusable LayerParser, with and without rendering a line of text per frame.
+func BenchmarkDecode(b *testing.B) {
+	b.Run("ParseFactoryMethod", func(b *testing.B) { benchmarkParseFactory(b, false) })
+	b.Run("ParseFactoryMethod+ToString", func(b *testing.B) { benchmarkParseFactory(b, true) })
+	b.Run("LayerParser", func(b *testing.B) { benchmarkLayerParser(b, false) })
+	b.Run("LayerParser+AppendSummary", func(b *testing.B) { benchmarkLayerParser(b, true) })
+}
+
+func benchmarkParseFactory(b *testing.B, render bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		decoded := packet.ParseFactoryMethod(frames[i%len(frames)], protocol.Ethernet)
+		if render {
+			_ = decoded.ToString()
+		}
+	}
+}
+
+func benchmarkLayerParser(b *testing.B, render bool) {
+	var ethernet packet.EthernetPacket
+	var ipv4 packet.Ipv4Packet
+	var tcp packet.TcpPacket
+	var udp packet.UdpPacket
+	parser := packet.NewLayerParser(protocol.Ethernet, &ethernet, &ipv4, &tcp, &udp)
+	decoded := make([]protocol.Protocol, 0, 4)
+	line := make([]byte, 0, 256)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := parser.DecodeLayers(frames[i%len(frames)], &decoded); err != nil {
+			b.Fatal(err)
+		}
+		if render {
+			line = parser.AppendSummary(line[:0], decoded)
+		}
+	}
+}
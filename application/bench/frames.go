@@ -0,0 +1,53 @@
+// Package bench builds synthetic traffic for the decoding and pipeline
+// benchmarks, run with "go test -bench . -benchmem ./bench".
+package bench
+
+import (
+	"encoding/binary"
+	"sniffer/application/packet"
+)
+
+// Frames builds count TCP frames spread over flows conversations, with
+// valid checksums and a payload of payloadSize bytes.
+func Frames(count int, flows int, payloadSize int) [][]byte {
+	frames := make([][]byte, count)
+	for i := range frames {
+		conversation := i % flows
+		frames[i] = tcpFrame(
+			[4]byte{10, byte(conversation >> 16), byte(conversation >> 8), byte(conversation)},
+			[4]byte{192, 168, 0, 1},
+			uint16(1024+conversation%60000), 80, uint32(i), payloadSize)
+	}
+	return frames
+}
+
+func tcpFrame(source [4]byte, destination [4]byte, sourcePort uint16, destinationPort uint16, seq uint32, payloadSize int) []byte {
+	const ipStart = packet.HeaderLength
+	const tcpStart = ipStart + packet.Ipv4MinHeaderSize
+	frame := make([]byte, tcpStart+packet.TcpMinHeaderSize+payloadSize)
+
+	copy(frame[packet.DestMacOffset:], []byte{0x02, 0, 0, 0, 0, 1})
+	copy(frame[packet.SrcMacOffset:], []byte{0x02, 0, 0, 0, 0, 2})
+	binary.BigEndian.PutUint16(frame[packet.TypeOffset:], packet.IPV4.Value)
+
+	ip := frame[ipStart:tcpStart]
+	ip[packet.Ipv4VersionAndIhlOffset] = 0x45
+	binary.BigEndian.PutUint16(ip[packet.Ipv4TotalLengthOffset:], uint16(len(frame)-ipStart))
+	ip[packet.Ipv4TtlOffset] = 64
+	ip[packet.Ipv4ProtocolOffset] = 6
+	copy(ip[packet.Ipv4SourceAddressOffset:], source[:])
+	copy(ip[packet.Ipv4DestAddressOffset:], destination[:])
+	binary.BigEndian.PutUint16(ip[packet.Ipv4HeaderChecksumOffset:], packet.InternetChecksum(ip))
+
+	tcp := frame[tcpStart:]
+	binary.BigEndian.PutUint16(tcp[packet.TcpSourcePortOffset:], sourcePort)
+	binary.BigEndian.PutUint16(tcp[packet.TcpDestinationPortOffset:], destinationPort)
+	binary.BigEndian.PutUint32(tcp[packet.TcpSequenceNumberOffset:], seq)
+	binary.BigEndian.PutUint16(tcp[packet.TcpDataOffsetAndReservedBitsOffset:], 5<<12|packet.TcpFlagAck|packet.TcpFlagPsh)
+	binary.BigEndian.PutUint16(tcp[packet.TcpWindowOffset:], 65535)
+	for i := packet.TcpMinHeaderSize; i < len(tcp); i++ {
+		tcp[i] = byte('a' + i%26)
+	}
+	binary.BigEndian.PutUint16(tcp[packet.TcpChecksumOffset:], packet.TransportChecksum(source[:], destination[:], 6, tcp))
+	return frame
+}
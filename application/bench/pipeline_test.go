@@ -0,0 +1,53 @@
+package bench
+
+import (
+	"fmt"
+	"runtime"
+	"sniffer/application/capture"
+	"sniffer/application/pipeline"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// decodeAndRender is the per-frame work of the -summary output mode.
+func decodeAndRender(job *pipeline.Job, d *pipeline.Decoder) {
+	if d.Decode(job.Data, job.LinkType) {
+		job.Text = d.Summary()
+	}
+}
+
+// BenchmarkPipeline measures end to end throughput with 1, 2, 4, ... workers
+// up to GOMAXPROCS, so the scaling with cores can be read off the results.
+func BenchmarkPipeline(b *testing.B) {
+	maxWorkers := runtime.GOMAXPROCS(0)
+	for workers := 1; ; workers *= 2 {
+		if workers > maxWorkers {
+			workers = maxWorkers
+		}
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) { benchmarkPipeline(b, workers) })
+		if workers == maxWorkers {
+			break
+		}
+	}
+}
+
+func benchmarkPipeline(b *testing.B, workers int) {
+	b.ReportAllocs()
+	p := pipeline.New(pipeline.Config{Workers: workers, Decode: decodeAndRender})
+	done := make(chan struct{})
+	go func() {
+		for range p.Output() {
+		}
+		close(done)
+	}()
+	now := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := frames[i%len(frames)]
+		p.Submit(capture.Frame{Data: data, Timestamp: now, CaptureLength: len(data), Length: len(data), LinkType: layers.LinkTypeEthernet})
+	}
+	p.Close()
+	<-done
+}
@@ -0,0 +1,66 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	FanoutHash        = "hash"
+	FanoutLoadBalance = "lb"
+	FanoutCpu         = "cpu"
+	FanoutRollover    = "rollover"
+	FanoutRandom      = "random"
+)
+
+// AfPacketConfig sizes the TPACKET_V3 ring of every socket. With Fanout
+// above one, that many sockets join one fanout group and the kernel spreads
+// frames across them, each drained by its own goroutine.
+type AfPacketConfig struct {
+	Interface string
+	// BlockSize must be a multiple of the page size. TPACKET_V3 packs
+	// frames of any length into a block, so it also caps the frame size.
+	BlockSize int
+	NumBlocks int
+	// BlockTimeout hands a partly filled block to user space after this
+	// long, bounding the latency on a quiet link.
+	BlockTimeout time.Duration
+	Fanout       int
+	FanoutType   string
+	FanoutId     uint16
+	// QueueSize is the number of frames buffered between the socket
+	// goroutines and ReadPacketData.
+	QueueSize int
+}
+
+func DefaultAfPacketConfig(iface string) AfPacketConfig {
+	return AfPacketConfig{
+		Interface:    iface,
+		BlockSize:    1 << 20,
+		NumBlocks:    64,
+		BlockTimeout: 64 * time.Millisecond,
+		Fanout:       1,
+		FanoutType:   FanoutHash,
+		FanoutId:     uint16(os.Getpid()),
+		QueueSize:    4096,
+	}
+}
+
+func (c AfPacketConfig) check() error {
+	if c.Interface == "" {
+		return fmt.Errorf("afpacket needs an interface name")
+	}
+	if c.BlockSize <= 0 || c.NumBlocks <= 0 {
+		return fmt.Errorf("afpacket ring sizes must be positive")
+	}
+	if c.Fanout < 1 {
+		return fmt.Errorf("afpacket fanout %d must be at least 1", c.Fanout)
+	}
+	switch c.FanoutType {
+	case FanoutHash, FanoutLoadBalance, FanoutCpu, FanoutRollover, FanoutRandom:
+	default:
+		return fmt.Errorf("unknown fanout type %q", c.FanoutType)
+	}
+	return nil
+}
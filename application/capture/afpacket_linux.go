@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"github.com/google/gopacket/afpacket"
+	"github.com/google/gopacket/layers"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// pollTimeout bounds how long a socket goroutine blocks, so Close can stop
+// it before the ring is unmapped.
+const pollTimeout = 100 * time.Millisecond
+
+var fanoutTypes = map[string]afpacket.FanoutType{
+	FanoutHash:        afpacket.FanoutHash,
+	FanoutLoadBalance: afpacket.FanoutLoadBalance,
+	FanoutCpu:         afpacket.FanoutCPU,
+	FanoutRollover:    afpacket.FanoutRollover,
+	FanoutRandom:      afpacket.FanoutRandom,
+}
+
+// AfPacketSource captures from memory mapped TPACKET_V3 rings without
+// going through libpcap.
+type AfPacketSource struct {
+	name    string
+	sockets []*afpacket.TPacket
+	frames  chan Frame
+	stop    chan struct{}
+	readers sync.WaitGroup
+	once    sync.Once
+}
+
+func NewAfPacketSource(config AfPacketConfig) (*AfPacketSource, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	s := &AfPacketSource{
+		name:   config.Interface,
+		frames: make(chan Frame, config.QueueSize),
+		stop:   make(chan struct{}),
+	}
+	for i := 0; i < config.Fanout; i++ {
+		socket, err := afpacket.NewTPacket(
+			afpacket.OptInterface(config.Interface),
+			afpacket.TPacketVersion3,
+			afpacket.OptFrameSize(os.Getpagesize()),
+			afpacket.OptBlockSize(config.BlockSize),
+			afpacket.OptNumBlocks(config.NumBlocks),
+			afpacket.OptBlockTimeout(config.BlockTimeout),
+			afpacket.OptPollTimeout(pollTimeout),
+		)
+		if err != nil {
+			s.closeSockets()
+			return nil, err
+		}
+		s.sockets = append(s.sockets, socket)
+		if config.Fanout > 1 {
+			if err := socket.SetFanout(fanoutTypes[config.FanoutType], config.FanoutId); err != nil {
+				s.closeSockets()
+				return nil, err
+			}
+		}
+	}
+	for _, socket := range s.sockets {
+		s.readers.Add(1)
+		go s.read(socket)
+	}
+	go func() {
+		s.readers.Wait()
+		close(s.frames)
+	}()
+	return s, nil
+}
+
+func (s *AfPacketSource) read(socket *afpacket.TPacket) {
+	defer s.readers.Done()
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		data, info, err := socket.ReadPacketData()
+		if err == afpacket.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case s.frames <- Frame{
+			Data:           data,
+			Timestamp:      info.Timestamp,
+			CaptureLength:  info.CaptureLength,
+			Length:         info.Length,
+			InterfaceIndex: info.InterfaceIndex,
+			Interface:      s.name,
+			LinkType:       layers.LinkTypeEthernet,
+		}:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *AfPacketSource) Next() (Frame, error) {
+	frame, ok := <-s.frames
+	if !ok {
+		return Frame{}, io.EOF
+	}
+	return frame, nil
+}
+
+// Stats sums the kernel counters of every socket in the fanout group. The
+// kernel counts dropped frames in Received as well.
+func (s *AfPacketSource) Stats() (Stats, error) {
+	var stats Stats
+	for _, socket := range s.sockets {
+		_, v3, err := socket.SocketStats()
+		if err != nil {
+			return Stats{}, err
+		}
+		stats.Received += uint64(v3.Packets())
+		stats.Dropped += uint64(v3.Drops())
+		stats.QueueFreezes += uint64(v3.QueueFreezes())
+	}
+	return stats, nil
+}
+
+// Close stops the socket goroutines and releases the rings. Frames still
+// queued are discarded.
+func (s *AfPacketSource) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.readers.Wait()
+		s.closeSockets()
+	})
+}
+
+func (s *AfPacketSource) closeSockets() {
+	for _, socket := range s.sockets {
+		socket.Close()
+	}
+}
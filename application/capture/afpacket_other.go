@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package capture
+
+import (
+	"errors"
+)
+
+type AfPacketSource struct{}
+
+func NewAfPacketSource(config AfPacketConfig) (*AfPacketSource, error) {
+	return nil, errors.New("AF_PACKET capture is only supported on Linux")
+}
+
+func (s *AfPacketSource) Next() (Frame, error) {
+	return Frame{}, errors.New("AF_PACKET capture is only supported on Linux")
+}
+
+func (s *AfPacketSource) Stats() (Stats, error) {
+	return Stats{}, nil
+}
+
+func (s *AfPacketSource) Close() {
+}
@@ -0,0 +1,154 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/google/gopacket/pcapgo"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+const pcapngMagic = 0x0a0d0d0a
+
+// OpenFile opens a pcap or pcapng file, telling them apart by their magic.
+func OpenFile(path string) (CaptureSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(file)
+	magic, err := buffered.Peek(4)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(magic) == pcapngMagic {
+		return newPcapngFileSource(file, buffered, path)
+	}
+	return newPcapFileSource(file, buffered, path)
+}
+
+// PcapFileSource reads a classic pcap file. Frames are tagged with the
+// file name, since the format does not record the interface.
+type PcapFileSource struct {
+	received uint64
+	file     *os.File
+	reader   *pcapgo.Reader
+	name     string
+}
+
+func NewPcapFileSource(path string) (*PcapFileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return newPcapFileSource(file, bufio.NewReader(file), path)
+}
+
+func newPcapFileSource(file *os.File, buffered *bufio.Reader, path string) (*PcapFileSource, error) {
+	reader, err := pcapgo.NewReader(buffered)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &PcapFileSource{file: file, reader: reader, name: filepath.Base(path)}, nil
+}
+
+func (s *PcapFileSource) Next() (Frame, error) {
+	data, info, err := s.reader.ReadPacketData()
+	if err != nil {
+		return Frame{}, err
+	}
+	atomic.AddUint64(&s.received, 1)
+	return Frame{
+		Data:          data,
+		Timestamp:     info.Timestamp,
+		CaptureLength: info.CaptureLength,
+		Length:        info.Length,
+		Interface:     s.name,
+		LinkType:      s.reader.LinkType(),
+	}, nil
+}
+
+func (s *PcapFileSource) Stats() (Stats, error) {
+	return Stats{Received: atomic.LoadUint64(&s.received)}, nil
+}
+
+func (s *PcapFileSource) Close() {
+	s.file.Close()
+}
+
+// PcapngFileSource reads a pcapng file, which may hold frames from several
+// interfaces with different link types.
+type PcapngFileSource struct {
+	received uint64
+	dropped  uint64
+	file     *os.File
+	reader   *pcapgo.NgReader
+	name     string
+}
+
+func NewPcapngFileSource(path string) (*PcapngFileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return newPcapngFileSource(file, bufio.NewReader(file), path)
+}
+
+func newPcapngFileSource(file *os.File, buffered *bufio.Reader, path string) (*PcapngFileSource, error) {
+	reader, err := pcapgo.NewNgReader(buffered, pcapgo.NgReaderOptions{WantMixedLinkType: true})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &PcapngFileSource{file: file, reader: reader, name: filepath.Base(path)}, nil
+}
+
+func (s *PcapngFileSource) Next() (Frame, error) {
+	data, info, err := s.reader.ReadPacketData()
+	s.countDrops()
+	if err != nil {
+		return Frame{}, err
+	}
+	atomic.AddUint64(&s.received, 1)
+	frame := Frame{
+		Data:           data,
+		Timestamp:      info.Timestamp,
+		CaptureLength:  info.CaptureLength,
+		Length:         info.Length,
+		InterfaceIndex: info.InterfaceIndex,
+		Interface:      s.name,
+		LinkType:       s.reader.LinkType(),
+	}
+	if iface, err := s.reader.Interface(info.InterfaceIndex); err == nil {
+		frame.LinkType = iface.LinkType
+		if iface.Name != "" {
+			frame.Interface = iface.Name
+		}
+	}
+	return frame, nil
+}
+
+// countDrops adds up the interface statistics blocks read so far; writers
+// usually put them at the end of the file. It runs on the reading goroutine
+// so Stats does not race with the reader.
+func (s *PcapngFileSource) countDrops() {
+	var dropped uint64
+	for i := 0; i < s.reader.NInterfaces(); i++ {
+		iface, err := s.reader.Interface(i)
+		if err == nil && iface.Statistics.PacketsDropped != pcapgo.NgNoValue64 {
+			dropped += iface.Statistics.PacketsDropped
+		}
+	}
+	atomic.StoreUint64(&s.dropped, dropped)
+}
+
+func (s *PcapngFileSource) Stats() (Stats, error) {
+	return Stats{Received: atomic.LoadUint64(&s.received), Dropped: atomic.LoadUint64(&s.dropped)}, nil
+}
+
+func (s *PcapngFileSource) Close() {
+	s.file.Close()
+}
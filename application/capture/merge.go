@@ -0,0 +1,212 @@
+package capture
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultMergeWindow is how long a live merge waits for an interface that
+// has gone quiet before it stops holding frames back for it.
+const DefaultMergeWindow = 100 * time.Millisecond
+
+// mergeQueue is how many frames each source may read ahead of the merge.
+const mergeQueue = 1024
+
+type mergeArrival struct {
+	source int
+	frame  Frame
+	err    error
+}
+
+// MergedSource reads several sources at once and returns their frames in
+// timestamp order. A frame is held back while another source without a
+// frame queued was active within the window, since that source may still
+// deliver an older one; a source quiet for longer no longer holds up the
+// merge. With a window of zero the merge always waits, which is exact for
+// files but would block forever on an idle interface.
+type MergedSource struct {
+	sources  []CaptureSource
+	window   time.Duration
+	arrivals chan mergeArrival
+	// credits bounds the frames each source has read but the merge has not
+	// returned yet.
+	credits []chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+
+	queues [][]Frame
+	// active is when each source last delivered a frame.
+	active []time.Time
+	done   []bool
+	err    error
+}
+
+func NewMergedSource(window time.Duration, sources ...CaptureSource) *MergedSource {
+	m := &MergedSource{
+		sources:  sources,
+		window:   window,
+		arrivals: make(chan mergeArrival, len(sources)*mergeQueue),
+		credits:  make([]chan struct{}, len(sources)),
+		stop:     make(chan struct{}),
+		queues:   make([][]Frame, len(sources)),
+		active:   make([]time.Time, len(sources)),
+		done:     make([]bool, len(sources)),
+	}
+	now := time.Now()
+	for i := range sources {
+		m.credits[i] = make(chan struct{}, mergeQueue)
+		for j := 0; j < mergeQueue; j++ {
+			m.credits[i] <- struct{}{}
+		}
+		m.active[i] = now
+		go m.read(i)
+	}
+	return m
+}
+
+// read keeps at most mergeQueue frames of its source in flight, so a fast
+// source cannot run arbitrarily far ahead of the merge.
+func (m *MergedSource) read(index int) {
+	for {
+		select {
+		case <-m.credits[index]:
+		case <-m.stop:
+			return
+		}
+		frame, err := m.sources[index].Next()
+		select {
+		case m.arrivals <- mergeArrival{source: index, frame: frame, err: err}:
+		case <-m.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (m *MergedSource) accept(arrival mergeArrival) {
+	if arrival.err != nil {
+		m.done[arrival.source] = true
+		if arrival.err != io.EOF && m.err == nil {
+			m.err = arrival.err
+		}
+		return
+	}
+	m.queues[arrival.source] = append(m.queues[arrival.source], arrival.frame)
+	m.active[arrival.source] = time.Now()
+}
+
+func (m *MergedSource) Next() (Frame, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		// Take whatever has already arrived without blocking.
+		for drained := false; !drained; {
+			select {
+			case arrival := <-m.arrivals:
+				m.accept(arrival)
+			default:
+				drained = true
+			}
+		}
+
+		oldest := -1
+		for i, queue := range m.queues {
+			if len(queue) > 0 && (oldest < 0 || queue[0].Timestamp.Before(m.queues[oldest][0].Timestamp)) {
+				oldest = i
+			}
+		}
+		// Wait for every source that is still running, has nothing queued
+		// and was active within the window.
+		now := time.Now()
+		blocked := false
+		var wait time.Duration
+		for i, queue := range m.queues {
+			if len(queue) > 0 || m.done[i] {
+				continue
+			}
+			if m.window == 0 {
+				blocked = true
+				wait = 0
+				break
+			}
+			if remaining := m.window - now.Sub(m.active[i]); remaining > 0 {
+				if !blocked || remaining < wait {
+					wait = remaining
+				}
+				blocked = true
+			}
+		}
+		if oldest >= 0 && !blocked {
+			frame := m.queues[oldest][0]
+			m.queues[oldest] = m.queues[oldest][1:]
+			m.credits[oldest] <- struct{}{}
+			return frame, nil
+		}
+		if oldest < 0 && !blocked && m.allDone() {
+			if m.err != nil {
+				return Frame{}, m.err
+			}
+			return Frame{}, io.EOF
+		}
+
+		var expired <-chan time.Time
+		if oldest >= 0 && wait > 0 {
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			expired = timer.C
+		}
+		select {
+		case arrival := <-m.arrivals:
+			m.accept(arrival)
+			if timer != nil && !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-expired:
+		case <-m.stop:
+			return Frame{}, io.EOF
+		}
+	}
+}
+
+func (m *MergedSource) allDone() bool {
+	for _, done := range m.done {
+		if !done {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MergedSource) Stats() (Stats, error) {
+	var total Stats
+	for _, source := range m.sources {
+		stats, err := source.Stats()
+		if err != nil {
+			return Stats{}, err
+		}
+		total.add(stats)
+	}
+	return total, nil
+}
+
+func (m *MergedSource) Close() {
+	m.once.Do(func() {
+		close(m.stop)
+		for _, source := range m.sources {
+			source.Close()
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// idleSource never delivers a frame until it is closed.
+type idleSource struct {
+	closed chan struct{}
+}
+
+func (s *idleSource) Next() (Frame, error) {
+	<-s.closed
+	return Frame{}, io.EOF
+}
+
+func (s *idleSource) Stats() (Stats, error) {
+	return Stats{}, nil
+}
+
+func (s *idleSource) Close() {
+	close(s.closed)
+}
+
+func framesAt(start time.Time, step time.Duration, count int) []Frame {
+	frames := make([]Frame, count)
+	for i := range frames {
+		frames[i] = Frame{Data: []byte{byte(i)}, Timestamp: start.Add(time.Duration(i) * step)}
+	}
+	return frames
+}
+
+func TestMergedSourceOrdersByTimestamp(t *testing.T) {
+	start := time.Unix(1000, 0)
+	a := NewMemorySource(framesAt(start, 2*time.Millisecond, 50))
+	b := NewMemorySource(framesAt(start.Add(time.Millisecond), 2*time.Millisecond, 50))
+	m := NewMergedSource(0, a, b)
+	defer m.Close()
+
+	var last time.Time
+	count := 0
+	for {
+		frame, err := m.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Timestamp.Before(last) {
+			t.Fatalf("frame %d at %v came after %v", count, frame.Timestamp, last)
+		}
+		last = frame.Timestamp
+		count++
+	}
+	if count != 100 {
+		t.Fatalf("merged %d frames, want 100", count)
+	}
+}
+
+func TestMergedSourceDoesNotWaitForIdleSource(t *testing.T) {
+	busy := NewMemorySource(framesAt(time.Now(), time.Microsecond, 5000))
+	idle := &idleSource{closed: make(chan struct{})}
+	m := NewMergedSource(DefaultMergeWindow, busy, idle)
+	defer m.Close()
+
+	began := time.Now()
+	for i := 0; i < 5000; i++ {
+		if _, err := m.Next(); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+	// One window for the idle source to go quiet, plus slack.
+	if elapsed := time.Since(began); elapsed > 10*DefaultMergeWindow {
+		t.Fatalf("5000 frames took %v next to an idle source", elapsed)
+	}
+}
@@ -0,0 +1,73 @@
+package capture
+
+import (
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"net"
+)
+
+// PcapSource captures live through libpcap.
+type PcapSource struct {
+	handle *pcap.Handle
+	name   string
+	index  int
+}
+
+func NewPcapSource(device string, snapLength int, promiscuous bool) (*PcapSource, error) {
+	handle, err := pcap.OpenLive(device, int32(snapLength), promiscuous, pcap.BlockForever)
+	if err != nil {
+		return nil, err
+	}
+	s := &PcapSource{handle: handle, name: device}
+	if iface, err := net.InterfaceByName(device); err == nil {
+		s.index = iface.Index
+	}
+	return s, nil
+}
+
+// SetFilter installs a BPF filter in tcpdump syntax; "" captures everything.
+func (s *PcapSource) SetFilter(expression string) error {
+	return s.handle.SetBPFFilter(expression)
+}
+
+// SetInstructionFilter installs an already compiled BPF program, such as
+// one received from a remote client.
+func (s *PcapSource) SetInstructionFilter(instructions []pcap.BPFInstruction) error {
+	return s.handle.SetBPFInstructionFilter(instructions)
+}
+
+func (s *PcapSource) LinkType() layers.LinkType {
+	return s.handle.LinkType()
+}
+
+func (s *PcapSource) Next() (Frame, error) {
+	data, info, err := s.handle.ReadPacketData()
+	if err != nil {
+		return Frame{}, err
+	}
+	return Frame{
+		Data:           data,
+		Timestamp:      info.Timestamp,
+		CaptureLength:  info.CaptureLength,
+		Length:         info.Length,
+		InterfaceIndex: s.index,
+		Interface:      s.name,
+		LinkType:       s.LinkType(),
+	}, nil
+}
+
+func (s *PcapSource) Stats() (Stats, error) {
+	stats, err := s.handle.Stats()
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		Received:         uint64(stats.PacketsReceived),
+		Dropped:          uint64(stats.PacketsDropped),
+		InterfaceDropped: uint64(stats.PacketsIfDropped),
+	}, nil
+}
+
+func (s *PcapSource) Close() {
+	s.handle.Close()
+}
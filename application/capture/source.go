@@ -0,0 +1,58 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/google/gopacket/layers"
+	"time"
+)
+
+// Frame is one captured frame, tagged with where it came from.
+type Frame struct {
+	Data      []byte
+	Timestamp time.Time
+	// CaptureLength is len(Data); Length is the frame's size on the wire,
+	// which is larger when the capture was truncated.
+	CaptureLength int
+	Length        int
+	// InterfaceIndex is the kernel interface index for live sources and
+	// the interface id inside a pcapng file.
+	InterfaceIndex int
+	Interface      string
+	LinkType       layers.LinkType
+}
+
+// CaptureSource is anything frames can be read from: a live interface
+// through libpcap or AF_PACKET, a capture file, memory, or several of these
+// merged. The decoders do not care which one is in use.
+type CaptureSource interface {
+	// Next returns the next frame, or io.EOF once the source is exhausted
+	// or closed. Frame data is not reused by the source and may be kept.
+	Next() (Frame, error)
+	Stats() (Stats, error)
+	Close()
+}
+
+// Stats are the counters kept by the kernel or libpcap for a source.
+type Stats struct {
+	Received uint64
+	// Dropped counts frames the kernel discarded because the capture
+	// buffer was full.
+	Dropped uint64
+	// InterfaceDropped counts frames the network interface or its driver
+	// discarded, where the backend reports it.
+	InterfaceDropped uint64
+	// QueueFreezes counts how often a TPACKET_V3 ring filled up.
+	QueueFreezes uint64
+}
+
+func (s Stats) ToString() string {
+	return fmt.Sprintf("capture: %d received, %d dropped by the kernel, %d dropped by the interface, %d ring freezes\n",
+		s.Received, s.Dropped, s.InterfaceDropped, s.QueueFreezes)
+}
+
+func (s *Stats) add(other Stats) {
+	s.Received += other.Received
+	s.Dropped += other.Dropped
+	s.InterfaceDropped += other.InterfaceDropped
+	s.QueueFreezes += other.QueueFreezes
+}
@@ -12,7 +12,7 @@ func ByteSliceToString(bytes []byte) string  {
 	}
 	result := ""
 	for _, v := range bytes{
-		result += fmt.Sprintf("%x ", v)
+		result += fmt.Sprintf("%02x ", v)
 	}
 
 	return result
@@ -0,0 +1,27 @@
+package control
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Client calls the Sniffer service of one agent.
+type Client struct {
+	SnifferClient
+	conn *grpc.ClientConn
+}
+
+// NewClient connects to address, a host:port, lazily on the first call.
+func NewClient(address string, config *tls.Config) (*Client, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{SnifferClient: NewSnifferClient(conn), conn: conn}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,188 @@
+package control
+
+import (
+	"fmt"
+	"net"
+	"sniffer/application/capture"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"time"
+)
+
+func (s *CaptureStats) ToString() string {
+	return fmt.Sprintf("%d received, %d captured, %d kernel dropped, %d interface dropped, %d decode failed, %d dropped for slow streams",
+		s.Received, s.Captured, s.KernelDropped, s.InterfaceDropped, s.DecodeFailed, s.StreamDropped)
+}
+
+// newPacketMessage fills in the headers of a decoded frame, or just the
+// bytes for a raw stream.
+func newPacketMessage(number uint64, frame capture.Frame, decoded packet.Parsable, raw bool) *PacketMessage {
+	m := &PacketMessage{
+		Number:         number,
+		TimestampNanos: frame.Timestamp.UnixNano(),
+		Length:         uint32(frame.Length),
+		CaptureLength:  uint32(frame.CaptureLength),
+		Interface:      frame.Interface,
+	}
+	if raw {
+		m.Data = frame.Data
+		return m
+	}
+	ethernet, ok := decoded.(packet.EthernetPacket)
+	if !ok {
+		return m
+	}
+	h := ethernet.Header
+	m.Ethernet = &EthernetHeader{
+		Destination: h.DestMacAddr.Value,
+		Source:      h.SrcMacAddr.Value,
+		Type:        uint32(h.Type.Value),
+		TypeName:    h.Type.Name,
+	}
+	m.Payload = ethernet.RawPayload
+	if !ethernet.CanParseMore {
+		return m
+	}
+
+	switch network := ethernet.PacketParser.(type) {
+	case packet.ArpPacket:
+		h := network.Header
+		m.Network = &PacketMessage_Arp{Arp: &ArpHeader{
+			HardwareType:          uint32(h.HardwareType.Value),
+			ProtocolType:          uint32(h.ProtocolType.Value),
+			HardwareAddressLength: uint32(h.HardwareAddressLength),
+			ProtocolAddressLength: uint32(h.ProtocolAddressLength),
+			Operation:             uint32(h.Operation.Value),
+			OperationName:         h.Operation.Name,
+			SenderHardwareAddress: h.SrcHardwareAddr.Value,
+			SenderProtocolAddress: h.SrcAddress.Value,
+			TargetHardwareAddress: h.DstHardwareAddr.Value,
+			TargetProtocolAddress: h.DstAddress.Value,
+		}}
+		m.Payload = nil
+	case packet.Ipv4Packet:
+		h := network.Header
+		m.Network = &PacketMessage_Ipv4{Ipv4: &Ipv4Header{
+			Version:        uint32(h.Version),
+			Ihl:            uint32(h.Ihl),
+			Tos:            uint32(h.Tos),
+			TotalLength:    uint32(h.TotalLength),
+			Identification: uint32(h.Identification),
+			ReservedFlag:   h.ReservedFlag,
+			DontFragment:   h.DontFragmentFlag,
+			MoreFragments:  h.MoreFragmentFlag,
+			FragmentOffset: uint32(h.FragmentOffset),
+			Ttl:            uint32(h.Ttl),
+			Protocol:       uint32(h.PayloadProtocol.Value),
+			ProtocolName:   h.PayloadProtocol.PayloadProtocol.Name,
+			HeaderChecksum: uint32(h.HeaderChecksum),
+			Source:         h.SourceAddress.Value,
+			Destination:    h.DestinationAddress.Value,
+			Options:        h.Options,
+		}}
+		m.Payload = network.RawPayload
+		if network.CanParseMore {
+			m.addTransport(network.PacketParser)
+		}
+	}
+	return m
+}
+
+func (m *PacketMessage) addTransport(transport packet.Parsable) {
+	switch layer := transport.(type) {
+	case packet.TcpPacket:
+		h := layer.Header
+		m.Transport = &PacketMessage_Tcp{Tcp: &TcpHeader{
+			SourcePort:      uint32(h.SourcePort),
+			DestinationPort: uint32(h.DestinationPort),
+			SequenceNumber:  h.SequenceNumber,
+			AckNumber:       h.AckNumber,
+			DataOffset:      uint32(h.DataOffset),
+			Urg:             h.URG,
+			Ack:             h.ACK,
+			Psh:             h.PSH,
+			Rst:             h.RST,
+			Syn:             h.SYN,
+			Fin:             h.FIN,
+			Window:          uint32(h.Window),
+			Checksum:        uint32(h.Checksum),
+			UrgentPointer:   uint32(h.UrgentPointer),
+			Options:         h.RawOptions,
+		}}
+		m.Payload = layer.RawPayload
+	case packet.UdpPacket:
+		h := layer.Header
+		m.Transport = &PacketMessage_Udp{Udp: &UdpHeader{
+			SourcePort:      uint32(h.SourcePort),
+			DestinationPort: uint32(h.DestinationPort),
+			Length:          uint32(h.Length),
+			Checksum:        uint32(h.Checksum),
+		}}
+		m.Payload = layer.RawPayload
+	case packet.IcmpV4Packet:
+		h := layer.Header
+		m.Transport = &PacketMessage_Icmpv4{Icmpv4: &IcmpV4Header{
+			Type:     uint32(h.Type.Value),
+			TypeName: h.Type.Name,
+			Code:     uint32(h.Detail.Value),
+			CodeName: h.Detail.Name,
+			Checksum: uint32(h.Checksum),
+		}}
+		m.Payload = layer.RawPayload
+	}
+}
+
+// ToString renders the message on one line, tcpdump style.
+func (m *PacketMessage) ToString() string {
+	timestamp := time.Unix(0, m.TimestampNanos).Format("15:04:05.000000")
+	result := fmt.Sprintf("%s #%d %d bytes", timestamp, m.Number, m.Length)
+	if m.Interface != "" {
+		result = fmt.Sprintf("%s [%s]", result, m.Interface)
+	}
+	if ipv4 := m.GetIpv4(); ipv4 != nil {
+		result += fmt.Sprintf(" %s > %s %s", net.IP(ipv4.Source), net.IP(ipv4.Destination), ipv4.ProtocolName)
+	} else if arp := m.GetArp(); arp != nil {
+		result += fmt.Sprintf(" ARP %s %s > %s", arp.OperationName,
+			net.IP(arp.SenderProtocolAddress), net.IP(arp.TargetProtocolAddress))
+	} else if m.Ethernet != nil {
+		result += fmt.Sprintf(" %s > %s type 0x%04x", net.HardwareAddr(m.Ethernet.Source),
+			net.HardwareAddr(m.Ethernet.Destination), m.Ethernet.Type)
+	}
+	switch transport := m.Transport.(type) {
+	case *PacketMessage_Tcp:
+		tcp := transport.Tcp
+		result += fmt.Sprintf(" %d > %d seq %d ack %d win %d", tcp.SourcePort, tcp.DestinationPort,
+			tcp.SequenceNumber, tcp.AckNumber, tcp.Window)
+	case *PacketMessage_Udp:
+		result += fmt.Sprintf(" %d > %d", transport.Udp.SourcePort, transport.Udp.DestinationPort)
+	case *PacketMessage_Icmpv4:
+		result += " " + transport.Icmpv4.TypeName
+	}
+	if m.Data != nil {
+		result += fmt.Sprintf(" (%d raw bytes)", len(m.Data))
+	}
+	return result
+}
+
+func newFlowStats(f flow.Flow) *FlowStats {
+	return &FlowStats{
+		AddressA:       append([]byte(nil), f.Key.A.Address[:]...),
+		PortA:          uint32(f.Key.A.Port),
+		AddressB:       append([]byte(nil), f.Key.B.Address[:]...),
+		PortB:          uint32(f.Key.B.Port),
+		Protocol:       uint32(f.Key.Protocol),
+		PacketsAToB:    f.AtoB.Packets,
+		BytesAToB:      f.AtoB.Bytes,
+		PacketsBToA:    f.BtoA.Packets,
+		BytesBToA:      f.BtoA.Bytes,
+		FirstSeenNanos: f.FirstSeen.UnixNano(),
+		LastSeenNanos:  f.LastSeen.UnixNano(),
+		TcpState:       string(f.TcpState),
+	}
+}
+
+func (f *FlowStats) ToString() string {
+	return fmt.Sprintf("%s:%d <-> %s:%d [%s] %d/%d packets %d/%d bytes %s",
+		net.IP(f.AddressA), f.PortA, net.IP(f.AddressB), f.PortB, flow.ProtocolName(byte(f.Protocol)),
+		f.PacketsAToB, f.PacketsBToA, f.BytesAToB, f.BytesBToA, f.TcpState)
+}
@@ -0,0 +1,332 @@
+package control
+
+import (
+	"context"
+	"sniffer/application/capture"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sniffer/application/pipeline"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket/pcap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative sniffer.proto
+
+const (
+	DefaultSnapLength = 65535
+	// expireInterval is how often a capture evicts idle flows.
+	expireInterval = 5 * time.Second
+)
+
+type Config struct {
+	// MaxCaptures caps the captures running at once.
+	MaxCaptures int
+	// StreamQueue is how many packets may wait for a slow stream before
+	// they are dropped for it.
+	StreamQueue int
+	// Open opens a live capture; it defaults to libpcap.
+	Open func(device string, snapLength int, promiscuous bool, filter string) (capture.CaptureSource, error)
+	// Interfaces lists capture devices; it defaults to libpcap.
+	Interfaces func() ([]*Interface, error)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxCaptures: 8,
+		StreamQueue: 4096,
+		Open:        openPcap,
+		Interfaces:  pcapInterfaces,
+	}
+}
+
+func openPcap(device string, snapLength int, promiscuous bool, filter string) (capture.CaptureSource, error) {
+	source, err := capture.NewPcapSource(device, snapLength, promiscuous)
+	if err != nil {
+		return nil, err
+	}
+	if err := source.SetFilter(filter); err != nil {
+		source.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+func pcapInterfaces() ([]*Interface, error) {
+	devices, err := pcap.FindAllDevs()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Interface, 0, len(devices))
+	for _, device := range devices {
+		i := &Interface{Name: device.Name, Description: device.Description}
+		for _, address := range device.Addresses {
+			i.Addresses = append(i.Addresses, address.IP.String())
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// decodedFrame is a captured frame on its way to the streams.
+type decodedFrame struct {
+	number  uint64
+	frame   capture.Frame
+	decoded packet.Parsable
+}
+
+type stream struct {
+	packets chan decodedFrame
+}
+
+// remoteCapture is one capture started over the API. Its goroutine reads
+// the source, decodes, tracks flows and hands frames to the streams.
+type remoteCapture struct {
+	id     string
+	source capture.CaptureSource
+	flows  *flow.Table
+	done   chan struct{}
+
+	captured      uint64
+	decodeFailed  uint64
+	streamDropped uint64
+
+	mutex   sync.Mutex
+	streams map[*stream]struct{}
+}
+
+func (c *remoteCapture) run() {
+	defer close(c.done)
+	lastExpire := time.Now()
+	var number uint64
+	for {
+		frame, err := c.source.Next()
+		if err != nil {
+			return
+		}
+		number++
+		atomic.AddUint64(&c.captured, 1)
+		decoded := decode(frame)
+		if decoded == nil {
+			atomic.AddUint64(&c.decodeFailed, 1)
+		} else if observation, ok := flow.Observe(decoded); ok {
+			c.flows.Add(observation, frame.Timestamp)
+		}
+		if time.Since(lastExpire) > expireInterval {
+			c.flows.Expire(time.Now())
+			lastExpire = time.Now()
+		}
+
+		c.mutex.Lock()
+		for s := range c.streams {
+			select {
+			case s.packets <- decodedFrame{number: number, frame: frame, decoded: decoded}:
+			default:
+				atomic.AddUint64(&c.streamDropped, 1)
+			}
+		}
+		c.mutex.Unlock()
+	}
+}
+
+func decode(frame capture.Frame) (result packet.Parsable) {
+	defer func() {
+		if recover() != nil {
+			result = nil
+		}
+	}()
+	return pipeline.DecodeLink(frame.Data, frame.LinkType)
+}
+
+func (c *remoteCapture) stats() *CaptureStats {
+	result := &CaptureStats{
+		Captured:      atomic.LoadUint64(&c.captured),
+		DecodeFailed:  atomic.LoadUint64(&c.decodeFailed),
+		StreamDropped: atomic.LoadUint64(&c.streamDropped),
+	}
+	if stats, err := c.source.Stats(); err == nil {
+		result.Received = stats.Received
+		result.KernelDropped = stats.Dropped
+		result.InterfaceDropped = stats.InterfaceDropped
+	}
+	return result
+}
+
+// Service implements the Sniffer gRPC service of sniffer.proto. Serve it
+// over TLS with client certificates, see Serve.
+type Service struct {
+	UnimplementedSnifferServer
+	config Config
+
+	mutex    sync.Mutex
+	captures map[string]*remoteCapture
+	// starting counts captures being opened, which hold a slot too.
+	starting int
+	nextId   int
+}
+
+func NewService(config Config) *Service {
+	if config.Open == nil {
+		config.Open = openPcap
+	}
+	if config.Interfaces == nil {
+		config.Interfaces = pcapInterfaces
+	}
+	return &Service{config: config, captures: make(map[string]*remoteCapture)}
+}
+
+// Close stops every capture.
+func (s *Service) Close() {
+	s.mutex.Lock()
+	captures := s.captures
+	s.captures = make(map[string]*remoteCapture)
+	s.mutex.Unlock()
+
+	for _, c := range captures {
+		c.source.Close()
+		<-c.done
+	}
+}
+
+// remove frees the slot of a capture once its source ends, unless it was
+// stopped first.
+func (s *Service) remove(c *remoteCapture) {
+	<-c.done
+	s.mutex.Lock()
+	if s.captures[c.id] == c {
+		delete(s.captures, c.id)
+	}
+	s.mutex.Unlock()
+}
+
+func (s *Service) lookup(id string) (*remoteCapture, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.captures[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no capture %q", id)
+	}
+	return c, nil
+}
+
+func (s *Service) ListInterfaces(ctx context.Context, request *ListInterfacesRequest) (*ListInterfacesResponse, error) {
+	interfaces, err := s.config.Interfaces()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "%v", err)
+	}
+	return &ListInterfacesResponse{Interfaces: interfaces}, nil
+}
+
+func (s *Service) StartCapture(ctx context.Context, request *StartCaptureRequest) (*StartCaptureResponse, error) {
+	if request.Interface == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "interface is required")
+	}
+	snapLength := int(request.SnapLength)
+	if snapLength == 0 {
+		snapLength = DefaultSnapLength
+	}
+
+	s.mutex.Lock()
+	if s.config.MaxCaptures > 0 && len(s.captures)+s.starting >= s.config.MaxCaptures {
+		s.mutex.Unlock()
+		return nil, status.Errorf(codes.ResourceExhausted, "%d captures are already running", s.config.MaxCaptures)
+	}
+	s.starting++
+	s.nextId++
+	id := strconv.Itoa(s.nextId)
+	s.mutex.Unlock()
+
+	source, err := s.config.Open(request.Interface, snapLength, request.Promiscuous, request.Filter)
+	if err != nil {
+		s.mutex.Lock()
+		s.starting--
+		s.mutex.Unlock()
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %v", request.Interface, err)
+	}
+	c := &remoteCapture{
+		id:      id,
+		source:  source,
+		flows:   flow.NewTable(flow.Config{}),
+		done:    make(chan struct{}),
+		streams: make(map[*stream]struct{}),
+	}
+	s.mutex.Lock()
+	s.starting--
+	s.captures[id] = c
+	s.mutex.Unlock()
+	go c.run()
+	go s.remove(c)
+
+	return &StartCaptureResponse{CaptureId: id}, nil
+}
+
+func (s *Service) StopCapture(ctx context.Context, request *StopCaptureRequest) (*StopCaptureResponse, error) {
+	s.mutex.Lock()
+	c, ok := s.captures[request.CaptureId]
+	delete(s.captures, request.CaptureId)
+	s.mutex.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no capture %q", request.CaptureId)
+	}
+
+	stats := c.stats()
+	c.source.Close()
+	<-c.done
+	return &StopCaptureResponse{Stats: stats}, nil
+}
+
+func (s *Service) StreamPackets(request *StreamPacketsRequest, server grpc.ServerStreamingServer[PacketMessage]) error {
+	c, err := s.lookup(request.CaptureId)
+	if err != nil {
+		return err
+	}
+
+	st := &stream{packets: make(chan decodedFrame, s.config.StreamQueue)}
+	c.mutex.Lock()
+	c.streams[st] = struct{}{}
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.streams, st)
+		c.mutex.Unlock()
+	}()
+
+	ctx := server.Context()
+	for {
+		select {
+		case f := <-st.packets:
+			if err := server.Send(newPacketMessage(f.number, f.frame, f.decoded, request.Raw)); err != nil {
+				return err
+			}
+		case <-c.done:
+			return nil
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func (s *Service) GetFlowStats(ctx context.Context, request *FlowStatsRequest) (*FlowStatsResponse, error) {
+	c, err := s.lookup(request.CaptureId)
+	if err != nil {
+		return nil, err
+	}
+	var flows []flow.Flow
+	if request.Top > 0 {
+		flows = c.flows.Top(int(request.Top))
+	} else {
+		flows = c.flows.Snapshot()
+	}
+	response := &FlowStatsResponse{Active: uint32(c.flows.Len())}
+	for _, f := range flows {
+		response.Flows = append(response.Flows, newFlowStats(f))
+	}
+	return response, nil
+}
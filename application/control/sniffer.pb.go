@@ -0,0 +1,1844 @@
+// Remote capture control API served by "sniffer agent". sniffer.pb.go and
+// sniffer_grpc.pb.go are generated from this file, see go:generate in
+// service.go.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: sniffer.proto
+
+package control
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListInterfacesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInterfacesRequest) Reset() {
+	*x = ListInterfacesRequest{}
+	mi := &file_sniffer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInterfacesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInterfacesRequest) ProtoMessage() {}
+
+func (x *ListInterfacesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInterfacesRequest.ProtoReflect.Descriptor instead.
+func (*ListInterfacesRequest) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{0}
+}
+
+type Interface struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Addresses     []string               `protobuf:"bytes,3,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Interface) Reset() {
+	*x = Interface{}
+	mi := &file_sniffer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Interface) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Interface) ProtoMessage() {}
+
+func (x *Interface) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Interface.ProtoReflect.Descriptor instead.
+func (*Interface) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Interface) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Interface) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Interface) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type ListInterfacesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interfaces    []*Interface           `protobuf:"bytes,1,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInterfacesResponse) Reset() {
+	*x = ListInterfacesResponse{}
+	mi := &file_sniffer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInterfacesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInterfacesResponse) ProtoMessage() {}
+
+func (x *ListInterfacesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInterfacesResponse.ProtoReflect.Descriptor instead.
+func (*ListInterfacesResponse) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListInterfacesResponse) GetInterfaces() []*Interface {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+type StartCaptureRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Interface string                 `protobuf:"bytes,1,opt,name=interface,proto3" json:"interface,omitempty"`
+	// filter is a BPF expression in tcpdump syntax.
+	Filter string `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	// snap_length defaults to 65535.
+	SnapLength    uint32 `protobuf:"varint,3,opt,name=snap_length,json=snapLength,proto3" json:"snap_length,omitempty"`
+	Promiscuous   bool   `protobuf:"varint,4,opt,name=promiscuous,proto3" json:"promiscuous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCaptureRequest) Reset() {
+	*x = StartCaptureRequest{}
+	mi := &file_sniffer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCaptureRequest) ProtoMessage() {}
+
+func (x *StartCaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCaptureRequest.ProtoReflect.Descriptor instead.
+func (*StartCaptureRequest) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StartCaptureRequest) GetInterface() string {
+	if x != nil {
+		return x.Interface
+	}
+	return ""
+}
+
+func (x *StartCaptureRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *StartCaptureRequest) GetSnapLength() uint32 {
+	if x != nil {
+		return x.SnapLength
+	}
+	return 0
+}
+
+func (x *StartCaptureRequest) GetPromiscuous() bool {
+	if x != nil {
+		return x.Promiscuous
+	}
+	return false
+}
+
+type StartCaptureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CaptureId     string                 `protobuf:"bytes,1,opt,name=capture_id,json=captureId,proto3" json:"capture_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCaptureResponse) Reset() {
+	*x = StartCaptureResponse{}
+	mi := &file_sniffer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCaptureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCaptureResponse) ProtoMessage() {}
+
+func (x *StartCaptureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCaptureResponse.ProtoReflect.Descriptor instead.
+func (*StartCaptureResponse) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StartCaptureResponse) GetCaptureId() string {
+	if x != nil {
+		return x.CaptureId
+	}
+	return ""
+}
+
+type StopCaptureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CaptureId     string                 `protobuf:"bytes,1,opt,name=capture_id,json=captureId,proto3" json:"capture_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopCaptureRequest) Reset() {
+	*x = StopCaptureRequest{}
+	mi := &file_sniffer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopCaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopCaptureRequest) ProtoMessage() {}
+
+func (x *StopCaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopCaptureRequest.ProtoReflect.Descriptor instead.
+func (*StopCaptureRequest) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StopCaptureRequest) GetCaptureId() string {
+	if x != nil {
+		return x.CaptureId
+	}
+	return ""
+}
+
+type CaptureStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Received         uint64                 `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"`
+	KernelDropped    uint64                 `protobuf:"varint,2,opt,name=kernel_dropped,json=kernelDropped,proto3" json:"kernel_dropped,omitempty"`
+	InterfaceDropped uint64                 `protobuf:"varint,3,opt,name=interface_dropped,json=interfaceDropped,proto3" json:"interface_dropped,omitempty"`
+	Captured         uint64                 `protobuf:"varint,4,opt,name=captured,proto3" json:"captured,omitempty"`
+	DecodeFailed     uint64                 `protobuf:"varint,5,opt,name=decode_failed,json=decodeFailed,proto3" json:"decode_failed,omitempty"`
+	StreamDropped    uint64                 `protobuf:"varint,6,opt,name=stream_dropped,json=streamDropped,proto3" json:"stream_dropped,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CaptureStats) Reset() {
+	*x = CaptureStats{}
+	mi := &file_sniffer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CaptureStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureStats) ProtoMessage() {}
+
+func (x *CaptureStats) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureStats.ProtoReflect.Descriptor instead.
+func (*CaptureStats) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CaptureStats) GetReceived() uint64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetKernelDropped() uint64 {
+	if x != nil {
+		return x.KernelDropped
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetInterfaceDropped() uint64 {
+	if x != nil {
+		return x.InterfaceDropped
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetCaptured() uint64 {
+	if x != nil {
+		return x.Captured
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetDecodeFailed() uint64 {
+	if x != nil {
+		return x.DecodeFailed
+	}
+	return 0
+}
+
+func (x *CaptureStats) GetStreamDropped() uint64 {
+	if x != nil {
+		return x.StreamDropped
+	}
+	return 0
+}
+
+type StopCaptureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stats         *CaptureStats          `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopCaptureResponse) Reset() {
+	*x = StopCaptureResponse{}
+	mi := &file_sniffer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopCaptureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopCaptureResponse) ProtoMessage() {}
+
+func (x *StopCaptureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopCaptureResponse.ProtoReflect.Descriptor instead.
+func (*StopCaptureResponse) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StopCaptureResponse) GetStats() *CaptureStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+type StreamPacketsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	CaptureId string                 `protobuf:"bytes,1,opt,name=capture_id,json=captureId,proto3" json:"capture_id,omitempty"`
+	// raw sends the frame bytes instead of decoded headers.
+	Raw           bool `protobuf:"varint,2,opt,name=raw,proto3" json:"raw,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamPacketsRequest) Reset() {
+	*x = StreamPacketsRequest{}
+	mi := &file_sniffer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamPacketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamPacketsRequest) ProtoMessage() {}
+
+func (x *StreamPacketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamPacketsRequest.ProtoReflect.Descriptor instead.
+func (*StreamPacketsRequest) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamPacketsRequest) GetCaptureId() string {
+	if x != nil {
+		return x.CaptureId
+	}
+	return ""
+}
+
+func (x *StreamPacketsRequest) GetRaw() bool {
+	if x != nil {
+		return x.Raw
+	}
+	return false
+}
+
+type EthernetHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Destination   []byte                 `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	Source        []byte                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Type          uint32                 `protobuf:"varint,3,opt,name=type,proto3" json:"type,omitempty"`
+	TypeName      string                 `protobuf:"bytes,4,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EthernetHeader) Reset() {
+	*x = EthernetHeader{}
+	mi := &file_sniffer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EthernetHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EthernetHeader) ProtoMessage() {}
+
+func (x *EthernetHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EthernetHeader.ProtoReflect.Descriptor instead.
+func (*EthernetHeader) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *EthernetHeader) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *EthernetHeader) GetSource() []byte {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *EthernetHeader) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *EthernetHeader) GetTypeName() string {
+	if x != nil {
+		return x.TypeName
+	}
+	return ""
+}
+
+type Ipv4Header struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Version        uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Ihl            uint32                 `protobuf:"varint,2,opt,name=ihl,proto3" json:"ihl,omitempty"`
+	Tos            uint32                 `protobuf:"varint,3,opt,name=tos,proto3" json:"tos,omitempty"`
+	TotalLength    uint32                 `protobuf:"varint,4,opt,name=total_length,json=totalLength,proto3" json:"total_length,omitempty"`
+	Identification uint32                 `protobuf:"varint,5,opt,name=identification,proto3" json:"identification,omitempty"`
+	ReservedFlag   bool                   `protobuf:"varint,6,opt,name=reserved_flag,json=reservedFlag,proto3" json:"reserved_flag,omitempty"`
+	DontFragment   bool                   `protobuf:"varint,7,opt,name=dont_fragment,json=dontFragment,proto3" json:"dont_fragment,omitempty"`
+	MoreFragments  bool                   `protobuf:"varint,8,opt,name=more_fragments,json=moreFragments,proto3" json:"more_fragments,omitempty"`
+	FragmentOffset uint32                 `protobuf:"varint,9,opt,name=fragment_offset,json=fragmentOffset,proto3" json:"fragment_offset,omitempty"`
+	Ttl            uint32                 `protobuf:"varint,10,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Protocol       uint32                 `protobuf:"varint,11,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	ProtocolName   string                 `protobuf:"bytes,12,opt,name=protocol_name,json=protocolName,proto3" json:"protocol_name,omitempty"`
+	HeaderChecksum uint32                 `protobuf:"varint,13,opt,name=header_checksum,json=headerChecksum,proto3" json:"header_checksum,omitempty"`
+	Source         []byte                 `protobuf:"bytes,14,opt,name=source,proto3" json:"source,omitempty"`
+	Destination    []byte                 `protobuf:"bytes,15,opt,name=destination,proto3" json:"destination,omitempty"`
+	Options        []byte                 `protobuf:"bytes,16,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Ipv4Header) Reset() {
+	*x = Ipv4Header{}
+	mi := &file_sniffer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ipv4Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ipv4Header) ProtoMessage() {}
+
+func (x *Ipv4Header) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ipv4Header.ProtoReflect.Descriptor instead.
+func (*Ipv4Header) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Ipv4Header) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetIhl() uint32 {
+	if x != nil {
+		return x.Ihl
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetTos() uint32 {
+	if x != nil {
+		return x.Tos
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetTotalLength() uint32 {
+	if x != nil {
+		return x.TotalLength
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetIdentification() uint32 {
+	if x != nil {
+		return x.Identification
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetReservedFlag() bool {
+	if x != nil {
+		return x.ReservedFlag
+	}
+	return false
+}
+
+func (x *Ipv4Header) GetDontFragment() bool {
+	if x != nil {
+		return x.DontFragment
+	}
+	return false
+}
+
+func (x *Ipv4Header) GetMoreFragments() bool {
+	if x != nil {
+		return x.MoreFragments
+	}
+	return false
+}
+
+func (x *Ipv4Header) GetFragmentOffset() uint32 {
+	if x != nil {
+		return x.FragmentOffset
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetTtl() uint32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetProtocol() uint32 {
+	if x != nil {
+		return x.Protocol
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetProtocolName() string {
+	if x != nil {
+		return x.ProtocolName
+	}
+	return ""
+}
+
+func (x *Ipv4Header) GetHeaderChecksum() uint32 {
+	if x != nil {
+		return x.HeaderChecksum
+	}
+	return 0
+}
+
+func (x *Ipv4Header) GetSource() []byte {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *Ipv4Header) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *Ipv4Header) GetOptions() []byte {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type ArpHeader struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	HardwareType          uint32                 `protobuf:"varint,1,opt,name=hardware_type,json=hardwareType,proto3" json:"hardware_type,omitempty"`
+	ProtocolType          uint32                 `protobuf:"varint,2,opt,name=protocol_type,json=protocolType,proto3" json:"protocol_type,omitempty"`
+	HardwareAddressLength uint32                 `protobuf:"varint,3,opt,name=hardware_address_length,json=hardwareAddressLength,proto3" json:"hardware_address_length,omitempty"`
+	ProtocolAddressLength uint32                 `protobuf:"varint,4,opt,name=protocol_address_length,json=protocolAddressLength,proto3" json:"protocol_address_length,omitempty"`
+	Operation             uint32                 `protobuf:"varint,5,opt,name=operation,proto3" json:"operation,omitempty"`
+	OperationName         string                 `protobuf:"bytes,6,opt,name=operation_name,json=operationName,proto3" json:"operation_name,omitempty"`
+	SenderHardwareAddress []byte                 `protobuf:"bytes,7,opt,name=sender_hardware_address,json=senderHardwareAddress,proto3" json:"sender_hardware_address,omitempty"`
+	SenderProtocolAddress []byte                 `protobuf:"bytes,8,opt,name=sender_protocol_address,json=senderProtocolAddress,proto3" json:"sender_protocol_address,omitempty"`
+	TargetHardwareAddress []byte                 `protobuf:"bytes,9,opt,name=target_hardware_address,json=targetHardwareAddress,proto3" json:"target_hardware_address,omitempty"`
+	TargetProtocolAddress []byte                 `protobuf:"bytes,10,opt,name=target_protocol_address,json=targetProtocolAddress,proto3" json:"target_protocol_address,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *ArpHeader) Reset() {
+	*x = ArpHeader{}
+	mi := &file_sniffer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArpHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArpHeader) ProtoMessage() {}
+
+func (x *ArpHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArpHeader.ProtoReflect.Descriptor instead.
+func (*ArpHeader) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ArpHeader) GetHardwareType() uint32 {
+	if x != nil {
+		return x.HardwareType
+	}
+	return 0
+}
+
+func (x *ArpHeader) GetProtocolType() uint32 {
+	if x != nil {
+		return x.ProtocolType
+	}
+	return 0
+}
+
+func (x *ArpHeader) GetHardwareAddressLength() uint32 {
+	if x != nil {
+		return x.HardwareAddressLength
+	}
+	return 0
+}
+
+func (x *ArpHeader) GetProtocolAddressLength() uint32 {
+	if x != nil {
+		return x.ProtocolAddressLength
+	}
+	return 0
+}
+
+func (x *ArpHeader) GetOperation() uint32 {
+	if x != nil {
+		return x.Operation
+	}
+	return 0
+}
+
+func (x *ArpHeader) GetOperationName() string {
+	if x != nil {
+		return x.OperationName
+	}
+	return ""
+}
+
+func (x *ArpHeader) GetSenderHardwareAddress() []byte {
+	if x != nil {
+		return x.SenderHardwareAddress
+	}
+	return nil
+}
+
+func (x *ArpHeader) GetSenderProtocolAddress() []byte {
+	if x != nil {
+		return x.SenderProtocolAddress
+	}
+	return nil
+}
+
+func (x *ArpHeader) GetTargetHardwareAddress() []byte {
+	if x != nil {
+		return x.TargetHardwareAddress
+	}
+	return nil
+}
+
+func (x *ArpHeader) GetTargetProtocolAddress() []byte {
+	if x != nil {
+		return x.TargetProtocolAddress
+	}
+	return nil
+}
+
+type TcpHeader struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SourcePort      uint32                 `protobuf:"varint,1,opt,name=source_port,json=sourcePort,proto3" json:"source_port,omitempty"`
+	DestinationPort uint32                 `protobuf:"varint,2,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
+	SequenceNumber  uint32                 `protobuf:"varint,3,opt,name=sequence_number,json=sequenceNumber,proto3" json:"sequence_number,omitempty"`
+	AckNumber       uint32                 `protobuf:"varint,4,opt,name=ack_number,json=ackNumber,proto3" json:"ack_number,omitempty"`
+	DataOffset      uint32                 `protobuf:"varint,5,opt,name=data_offset,json=dataOffset,proto3" json:"data_offset,omitempty"`
+	Urg             bool                   `protobuf:"varint,6,opt,name=urg,proto3" json:"urg,omitempty"`
+	Ack             bool                   `protobuf:"varint,7,opt,name=ack,proto3" json:"ack,omitempty"`
+	Psh             bool                   `protobuf:"varint,8,opt,name=psh,proto3" json:"psh,omitempty"`
+	Rst             bool                   `protobuf:"varint,9,opt,name=rst,proto3" json:"rst,omitempty"`
+	Syn             bool                   `protobuf:"varint,10,opt,name=syn,proto3" json:"syn,omitempty"`
+	Fin             bool                   `protobuf:"varint,11,opt,name=fin,proto3" json:"fin,omitempty"`
+	Window          uint32                 `protobuf:"varint,12,opt,name=window,proto3" json:"window,omitempty"`
+	Checksum        uint32                 `protobuf:"varint,13,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	UrgentPointer   uint32                 `protobuf:"varint,14,opt,name=urgent_pointer,json=urgentPointer,proto3" json:"urgent_pointer,omitempty"`
+	Options         []byte                 `protobuf:"bytes,15,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TcpHeader) Reset() {
+	*x = TcpHeader{}
+	mi := &file_sniffer_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TcpHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TcpHeader) ProtoMessage() {}
+
+func (x *TcpHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TcpHeader.ProtoReflect.Descriptor instead.
+func (*TcpHeader) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TcpHeader) GetSourcePort() uint32 {
+	if x != nil {
+		return x.SourcePort
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetDestinationPort() uint32 {
+	if x != nil {
+		return x.DestinationPort
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetSequenceNumber() uint32 {
+	if x != nil {
+		return x.SequenceNumber
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetAckNumber() uint32 {
+	if x != nil {
+		return x.AckNumber
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetDataOffset() uint32 {
+	if x != nil {
+		return x.DataOffset
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetUrg() bool {
+	if x != nil {
+		return x.Urg
+	}
+	return false
+}
+
+func (x *TcpHeader) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *TcpHeader) GetPsh() bool {
+	if x != nil {
+		return x.Psh
+	}
+	return false
+}
+
+func (x *TcpHeader) GetRst() bool {
+	if x != nil {
+		return x.Rst
+	}
+	return false
+}
+
+func (x *TcpHeader) GetSyn() bool {
+	if x != nil {
+		return x.Syn
+	}
+	return false
+}
+
+func (x *TcpHeader) GetFin() bool {
+	if x != nil {
+		return x.Fin
+	}
+	return false
+}
+
+func (x *TcpHeader) GetWindow() uint32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetUrgentPointer() uint32 {
+	if x != nil {
+		return x.UrgentPointer
+	}
+	return 0
+}
+
+func (x *TcpHeader) GetOptions() []byte {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type UdpHeader struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SourcePort      uint32                 `protobuf:"varint,1,opt,name=source_port,json=sourcePort,proto3" json:"source_port,omitempty"`
+	DestinationPort uint32                 `protobuf:"varint,2,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
+	Length          uint32                 `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	Checksum        uint32                 `protobuf:"varint,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UdpHeader) Reset() {
+	*x = UdpHeader{}
+	mi := &file_sniffer_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UdpHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UdpHeader) ProtoMessage() {}
+
+func (x *UdpHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UdpHeader.ProtoReflect.Descriptor instead.
+func (*UdpHeader) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UdpHeader) GetSourcePort() uint32 {
+	if x != nil {
+		return x.SourcePort
+	}
+	return 0
+}
+
+func (x *UdpHeader) GetDestinationPort() uint32 {
+	if x != nil {
+		return x.DestinationPort
+	}
+	return 0
+}
+
+func (x *UdpHeader) GetLength() uint32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *UdpHeader) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+type IcmpV4Header struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          uint32                 `protobuf:"varint,1,opt,name=type,proto3" json:"type,omitempty"`
+	TypeName      string                 `protobuf:"bytes,2,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
+	Code          uint32                 `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	CodeName      string                 `protobuf:"bytes,4,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
+	Checksum      uint32                 `protobuf:"varint,5,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IcmpV4Header) Reset() {
+	*x = IcmpV4Header{}
+	mi := &file_sniffer_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IcmpV4Header) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IcmpV4Header) ProtoMessage() {}
+
+func (x *IcmpV4Header) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IcmpV4Header.ProtoReflect.Descriptor instead.
+func (*IcmpV4Header) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *IcmpV4Header) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *IcmpV4Header) GetTypeName() string {
+	if x != nil {
+		return x.TypeName
+	}
+	return ""
+}
+
+func (x *IcmpV4Header) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *IcmpV4Header) GetCodeName() string {
+	if x != nil {
+		return x.CodeName
+	}
+	return ""
+}
+
+func (x *IcmpV4Header) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+type PacketMessage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Number         uint64                 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	TimestampNanos int64                  `protobuf:"varint,2,opt,name=timestamp_nanos,json=timestampNanos,proto3" json:"timestamp_nanos,omitempty"`
+	Length         uint32                 `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	CaptureLength  uint32                 `protobuf:"varint,4,opt,name=capture_length,json=captureLength,proto3" json:"capture_length,omitempty"`
+	Interface      string                 `protobuf:"bytes,5,opt,name=interface,proto3" json:"interface,omitempty"`
+	// data is only set for raw streams.
+	Data     []byte          `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	Ethernet *EthernetHeader `protobuf:"bytes,7,opt,name=ethernet,proto3" json:"ethernet,omitempty"`
+	// Types that are valid to be assigned to Network:
+	//
+	//	*PacketMessage_Ipv4
+	//	*PacketMessage_Arp
+	Network isPacketMessage_Network `protobuf_oneof:"network"`
+	// Types that are valid to be assigned to Transport:
+	//
+	//	*PacketMessage_Tcp
+	//	*PacketMessage_Udp
+	//	*PacketMessage_Icmpv4
+	Transport isPacketMessage_Transport `protobuf_oneof:"transport"`
+	// payload is what follows the last decoded header.
+	Payload       []byte `protobuf:"bytes,13,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketMessage) Reset() {
+	*x = PacketMessage{}
+	mi := &file_sniffer_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketMessage) ProtoMessage() {}
+
+func (x *PacketMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketMessage.ProtoReflect.Descriptor instead.
+func (*PacketMessage) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PacketMessage) GetNumber() uint64 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *PacketMessage) GetTimestampNanos() int64 {
+	if x != nil {
+		return x.TimestampNanos
+	}
+	return 0
+}
+
+func (x *PacketMessage) GetLength() uint32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *PacketMessage) GetCaptureLength() uint32 {
+	if x != nil {
+		return x.CaptureLength
+	}
+	return 0
+}
+
+func (x *PacketMessage) GetInterface() string {
+	if x != nil {
+		return x.Interface
+	}
+	return ""
+}
+
+func (x *PacketMessage) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetEthernet() *EthernetHeader {
+	if x != nil {
+		return x.Ethernet
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetNetwork() isPacketMessage_Network {
+	if x != nil {
+		return x.Network
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetIpv4() *Ipv4Header {
+	if x != nil {
+		if x, ok := x.Network.(*PacketMessage_Ipv4); ok {
+			return x.Ipv4
+		}
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetArp() *ArpHeader {
+	if x != nil {
+		if x, ok := x.Network.(*PacketMessage_Arp); ok {
+			return x.Arp
+		}
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetTransport() isPacketMessage_Transport {
+	if x != nil {
+		return x.Transport
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetTcp() *TcpHeader {
+	if x != nil {
+		if x, ok := x.Transport.(*PacketMessage_Tcp); ok {
+			return x.Tcp
+		}
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetUdp() *UdpHeader {
+	if x != nil {
+		if x, ok := x.Transport.(*PacketMessage_Udp); ok {
+			return x.Udp
+		}
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetIcmpv4() *IcmpV4Header {
+	if x != nil {
+		if x, ok := x.Transport.(*PacketMessage_Icmpv4); ok {
+			return x.Icmpv4
+		}
+	}
+	return nil
+}
+
+func (x *PacketMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type isPacketMessage_Network interface {
+	isPacketMessage_Network()
+}
+
+type PacketMessage_Ipv4 struct {
+	Ipv4 *Ipv4Header `protobuf:"bytes,8,opt,name=ipv4,proto3,oneof"`
+}
+
+type PacketMessage_Arp struct {
+	Arp *ArpHeader `protobuf:"bytes,9,opt,name=arp,proto3,oneof"`
+}
+
+func (*PacketMessage_Ipv4) isPacketMessage_Network() {}
+
+func (*PacketMessage_Arp) isPacketMessage_Network() {}
+
+type isPacketMessage_Transport interface {
+	isPacketMessage_Transport()
+}
+
+type PacketMessage_Tcp struct {
+	Tcp *TcpHeader `protobuf:"bytes,10,opt,name=tcp,proto3,oneof"`
+}
+
+type PacketMessage_Udp struct {
+	Udp *UdpHeader `protobuf:"bytes,11,opt,name=udp,proto3,oneof"`
+}
+
+type PacketMessage_Icmpv4 struct {
+	Icmpv4 *IcmpV4Header `protobuf:"bytes,12,opt,name=icmpv4,proto3,oneof"`
+}
+
+func (*PacketMessage_Tcp) isPacketMessage_Transport() {}
+
+func (*PacketMessage_Udp) isPacketMessage_Transport() {}
+
+func (*PacketMessage_Icmpv4) isPacketMessage_Transport() {}
+
+type FlowStatsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	CaptureId string                 `protobuf:"bytes,1,opt,name=capture_id,json=captureId,proto3" json:"capture_id,omitempty"`
+	// top limits the answer to the busiest flows, 0 for all.
+	Top           uint32 `protobuf:"varint,2,opt,name=top,proto3" json:"top,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlowStatsRequest) Reset() {
+	*x = FlowStatsRequest{}
+	mi := &file_sniffer_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlowStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowStatsRequest) ProtoMessage() {}
+
+func (x *FlowStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowStatsRequest.ProtoReflect.Descriptor instead.
+func (*FlowStatsRequest) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FlowStatsRequest) GetCaptureId() string {
+	if x != nil {
+		return x.CaptureId
+	}
+	return ""
+}
+
+func (x *FlowStatsRequest) GetTop() uint32 {
+	if x != nil {
+		return x.Top
+	}
+	return 0
+}
+
+type FlowStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AddressA       []byte                 `protobuf:"bytes,1,opt,name=address_a,json=addressA,proto3" json:"address_a,omitempty"`
+	PortA          uint32                 `protobuf:"varint,2,opt,name=port_a,json=portA,proto3" json:"port_a,omitempty"`
+	AddressB       []byte                 `protobuf:"bytes,3,opt,name=address_b,json=addressB,proto3" json:"address_b,omitempty"`
+	PortB          uint32                 `protobuf:"varint,4,opt,name=port_b,json=portB,proto3" json:"port_b,omitempty"`
+	Protocol       uint32                 `protobuf:"varint,5,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	PacketsAToB    uint64                 `protobuf:"varint,6,opt,name=packets_a_to_b,json=packetsAToB,proto3" json:"packets_a_to_b,omitempty"`
+	BytesAToB      uint64                 `protobuf:"varint,7,opt,name=bytes_a_to_b,json=bytesAToB,proto3" json:"bytes_a_to_b,omitempty"`
+	PacketsBToA    uint64                 `protobuf:"varint,8,opt,name=packets_b_to_a,json=packetsBToA,proto3" json:"packets_b_to_a,omitempty"`
+	BytesBToA      uint64                 `protobuf:"varint,9,opt,name=bytes_b_to_a,json=bytesBToA,proto3" json:"bytes_b_to_a,omitempty"`
+	FirstSeenNanos int64                  `protobuf:"varint,10,opt,name=first_seen_nanos,json=firstSeenNanos,proto3" json:"first_seen_nanos,omitempty"`
+	LastSeenNanos  int64                  `protobuf:"varint,11,opt,name=last_seen_nanos,json=lastSeenNanos,proto3" json:"last_seen_nanos,omitempty"`
+	TcpState       string                 `protobuf:"bytes,12,opt,name=tcp_state,json=tcpState,proto3" json:"tcp_state,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *FlowStats) Reset() {
+	*x = FlowStats{}
+	mi := &file_sniffer_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlowStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowStats) ProtoMessage() {}
+
+func (x *FlowStats) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowStats.ProtoReflect.Descriptor instead.
+func (*FlowStats) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FlowStats) GetAddressA() []byte {
+	if x != nil {
+		return x.AddressA
+	}
+	return nil
+}
+
+func (x *FlowStats) GetPortA() uint32 {
+	if x != nil {
+		return x.PortA
+	}
+	return 0
+}
+
+func (x *FlowStats) GetAddressB() []byte {
+	if x != nil {
+		return x.AddressB
+	}
+	return nil
+}
+
+func (x *FlowStats) GetPortB() uint32 {
+	if x != nil {
+		return x.PortB
+	}
+	return 0
+}
+
+func (x *FlowStats) GetProtocol() uint32 {
+	if x != nil {
+		return x.Protocol
+	}
+	return 0
+}
+
+func (x *FlowStats) GetPacketsAToB() uint64 {
+	if x != nil {
+		return x.PacketsAToB
+	}
+	return 0
+}
+
+func (x *FlowStats) GetBytesAToB() uint64 {
+	if x != nil {
+		return x.BytesAToB
+	}
+	return 0
+}
+
+func (x *FlowStats) GetPacketsBToA() uint64 {
+	if x != nil {
+		return x.PacketsBToA
+	}
+	return 0
+}
+
+func (x *FlowStats) GetBytesBToA() uint64 {
+	if x != nil {
+		return x.BytesBToA
+	}
+	return 0
+}
+
+func (x *FlowStats) GetFirstSeenNanos() int64 {
+	if x != nil {
+		return x.FirstSeenNanos
+	}
+	return 0
+}
+
+func (x *FlowStats) GetLastSeenNanos() int64 {
+	if x != nil {
+		return x.LastSeenNanos
+	}
+	return 0
+}
+
+func (x *FlowStats) GetTcpState() string {
+	if x != nil {
+		return x.TcpState
+	}
+	return ""
+}
+
+type FlowStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Active        uint32                 `protobuf:"varint,1,opt,name=active,proto3" json:"active,omitempty"`
+	Flows         []*FlowStats           `protobuf:"bytes,2,rep,name=flows,proto3" json:"flows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlowStatsResponse) Reset() {
+	*x = FlowStatsResponse{}
+	mi := &file_sniffer_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlowStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowStatsResponse) ProtoMessage() {}
+
+func (x *FlowStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sniffer_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowStatsResponse.ProtoReflect.Descriptor instead.
+func (*FlowStatsResponse) Descriptor() ([]byte, []int) {
+	return file_sniffer_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *FlowStatsResponse) GetActive() uint32 {
+	if x != nil {
+		return x.Active
+	}
+	return 0
+}
+
+func (x *FlowStatsResponse) GetFlows() []*FlowStats {
+	if x != nil {
+		return x.Flows
+	}
+	return nil
+}
+
+var File_sniffer_proto protoreflect.FileDescriptor
+
+const file_sniffer_proto_rawDesc = "" +
+	"\n" +
+	"\rsniffer.proto\x12\x12sniffer.control.v1\"\x17\n" +
+	"\x15ListInterfacesRequest\"_\n" +
+	"\tInterface\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1c\n" +
+	"\taddresses\x18\x03 \x03(\tR\taddresses\"W\n" +
+	"\x16ListInterfacesResponse\x12=\n" +
+	"\n" +
+	"interfaces\x18\x01 \x03(\v2\x1d.sniffer.control.v1.InterfaceR\n" +
+	"interfaces\"\x8e\x01\n" +
+	"\x13StartCaptureRequest\x12\x1c\n" +
+	"\tinterface\x18\x01 \x01(\tR\tinterface\x12\x16\n" +
+	"\x06filter\x18\x02 \x01(\tR\x06filter\x12\x1f\n" +
+	"\vsnap_length\x18\x03 \x01(\rR\n" +
+	"snapLength\x12 \n" +
+	"\vpromiscuous\x18\x04 \x01(\bR\vpromiscuous\"5\n" +
+	"\x14StartCaptureResponse\x12\x1d\n" +
+	"\n" +
+	"capture_id\x18\x01 \x01(\tR\tcaptureId\"3\n" +
+	"\x12StopCaptureRequest\x12\x1d\n" +
+	"\n" +
+	"capture_id\x18\x01 \x01(\tR\tcaptureId\"\xe6\x01\n" +
+	"\fCaptureStats\x12\x1a\n" +
+	"\breceived\x18\x01 \x01(\x04R\breceived\x12%\n" +
+	"\x0ekernel_dropped\x18\x02 \x01(\x04R\rkernelDropped\x12+\n" +
+	"\x11interface_dropped\x18\x03 \x01(\x04R\x10interfaceDropped\x12\x1a\n" +
+	"\bcaptured\x18\x04 \x01(\x04R\bcaptured\x12#\n" +
+	"\rdecode_failed\x18\x05 \x01(\x04R\fdecodeFailed\x12%\n" +
+	"\x0estream_dropped\x18\x06 \x01(\x04R\rstreamDropped\"M\n" +
+	"\x13StopCaptureResponse\x126\n" +
+	"\x05stats\x18\x01 \x01(\v2 .sniffer.control.v1.CaptureStatsR\x05stats\"G\n" +
+	"\x14StreamPacketsRequest\x12\x1d\n" +
+	"\n" +
+	"capture_id\x18\x01 \x01(\tR\tcaptureId\x12\x10\n" +
+	"\x03raw\x18\x02 \x01(\bR\x03raw\"{\n" +
+	"\x0eEthernetHeader\x12 \n" +
+	"\vdestination\x18\x01 \x01(\fR\vdestination\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\fR\x06source\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\rR\x04type\x12\x1b\n" +
+	"\ttype_name\x18\x04 \x01(\tR\btypeName\"\xff\x03\n" +
+	"\n" +
+	"Ipv4Header\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12\x10\n" +
+	"\x03ihl\x18\x02 \x01(\rR\x03ihl\x12\x10\n" +
+	"\x03tos\x18\x03 \x01(\rR\x03tos\x12!\n" +
+	"\ftotal_length\x18\x04 \x01(\rR\vtotalLength\x12&\n" +
+	"\x0eidentification\x18\x05 \x01(\rR\x0eidentification\x12#\n" +
+	"\rreserved_flag\x18\x06 \x01(\bR\freservedFlag\x12#\n" +
+	"\rdont_fragment\x18\a \x01(\bR\fdontFragment\x12%\n" +
+	"\x0emore_fragments\x18\b \x01(\bR\rmoreFragments\x12'\n" +
+	"\x0ffragment_offset\x18\t \x01(\rR\x0efragmentOffset\x12\x10\n" +
+	"\x03ttl\x18\n" +
+	" \x01(\rR\x03ttl\x12\x1a\n" +
+	"\bprotocol\x18\v \x01(\rR\bprotocol\x12#\n" +
+	"\rprotocol_name\x18\f \x01(\tR\fprotocolName\x12'\n" +
+	"\x0fheader_checksum\x18\r \x01(\rR\x0eheaderChecksum\x12\x16\n" +
+	"\x06source\x18\x0e \x01(\fR\x06source\x12 \n" +
+	"\vdestination\x18\x0f \x01(\fR\vdestination\x12\x18\n" +
+	"\aoptions\x18\x10 \x01(\fR\aoptions\"\xea\x03\n" +
+	"\tArpHeader\x12#\n" +
+	"\rhardware_type\x18\x01 \x01(\rR\fhardwareType\x12#\n" +
+	"\rprotocol_type\x18\x02 \x01(\rR\fprotocolType\x126\n" +
+	"\x17hardware_address_length\x18\x03 \x01(\rR\x15hardwareAddressLength\x126\n" +
+	"\x17protocol_address_length\x18\x04 \x01(\rR\x15protocolAddressLength\x12\x1c\n" +
+	"\toperation\x18\x05 \x01(\rR\toperation\x12%\n" +
+	"\x0eoperation_name\x18\x06 \x01(\tR\roperationName\x126\n" +
+	"\x17sender_hardware_address\x18\a \x01(\fR\x15senderHardwareAddress\x126\n" +
+	"\x17sender_protocol_address\x18\b \x01(\fR\x15senderProtocolAddress\x126\n" +
+	"\x17target_hardware_address\x18\t \x01(\fR\x15targetHardwareAddress\x126\n" +
+	"\x17target_protocol_address\x18\n" +
+	" \x01(\fR\x15targetProtocolAddress\"\xa1\x03\n" +
+	"\tTcpHeader\x12\x1f\n" +
+	"\vsource_port\x18\x01 \x01(\rR\n" +
+	"sourcePort\x12)\n" +
+	"\x10destination_port\x18\x02 \x01(\rR\x0fdestinationPort\x12'\n" +
+	"\x0fsequence_number\x18\x03 \x01(\rR\x0esequenceNumber\x12\x1d\n" +
+	"\n" +
+	"ack_number\x18\x04 \x01(\rR\tackNumber\x12\x1f\n" +
+	"\vdata_offset\x18\x05 \x01(\rR\n" +
+	"dataOffset\x12\x10\n" +
+	"\x03urg\x18\x06 \x01(\bR\x03urg\x12\x10\n" +
+	"\x03ack\x18\a \x01(\bR\x03ack\x12\x10\n" +
+	"\x03psh\x18\b \x01(\bR\x03psh\x12\x10\n" +
+	"\x03rst\x18\t \x01(\bR\x03rst\x12\x10\n" +
+	"\x03syn\x18\n" +
+	" \x01(\bR\x03syn\x12\x10\n" +
+	"\x03fin\x18\v \x01(\bR\x03fin\x12\x16\n" +
+	"\x06window\x18\f \x01(\rR\x06window\x12\x1a\n" +
+	"\bchecksum\x18\r \x01(\rR\bchecksum\x12%\n" +
+	"\x0eurgent_pointer\x18\x0e \x01(\rR\rurgentPointer\x12\x18\n" +
+	"\aoptions\x18\x0f \x01(\fR\aoptions\"\x8b\x01\n" +
+	"\tUdpHeader\x12\x1f\n" +
+	"\vsource_port\x18\x01 \x01(\rR\n" +
+	"sourcePort\x12)\n" +
+	"\x10destination_port\x18\x02 \x01(\rR\x0fdestinationPort\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\rR\x06length\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\rR\bchecksum\"\x8c\x01\n" +
+	"\fIcmpV4Header\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\rR\x04type\x12\x1b\n" +
+	"\ttype_name\x18\x02 \x01(\tR\btypeName\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\rR\x04code\x12\x1b\n" +
+	"\tcode_name\x18\x04 \x01(\tR\bcodeName\x12\x1a\n" +
+	"\bchecksum\x18\x05 \x01(\rR\bchecksum\"\xbe\x04\n" +
+	"\rPacketMessage\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\x04R\x06number\x12'\n" +
+	"\x0ftimestamp_nanos\x18\x02 \x01(\x03R\x0etimestampNanos\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\rR\x06length\x12%\n" +
+	"\x0ecapture_length\x18\x04 \x01(\rR\rcaptureLength\x12\x1c\n" +
+	"\tinterface\x18\x05 \x01(\tR\tinterface\x12\x12\n" +
+	"\x04data\x18\x06 \x01(\fR\x04data\x12>\n" +
+	"\bethernet\x18\a \x01(\v2\".sniffer.control.v1.EthernetHeaderR\bethernet\x124\n" +
+	"\x04ipv4\x18\b \x01(\v2\x1e.sniffer.control.v1.Ipv4HeaderH\x00R\x04ipv4\x121\n" +
+	"\x03arp\x18\t \x01(\v2\x1d.sniffer.control.v1.ArpHeaderH\x00R\x03arp\x121\n" +
+	"\x03tcp\x18\n" +
+	" \x01(\v2\x1d.sniffer.control.v1.TcpHeaderH\x01R\x03tcp\x121\n" +
+	"\x03udp\x18\v \x01(\v2\x1d.sniffer.control.v1.UdpHeaderH\x01R\x03udp\x12:\n" +
+	"\x06icmpv4\x18\f \x01(\v2 .sniffer.control.v1.IcmpV4HeaderH\x01R\x06icmpv4\x12\x18\n" +
+	"\apayload\x18\r \x01(\fR\apayloadB\t\n" +
+	"\anetworkB\v\n" +
+	"\ttransport\"C\n" +
+	"\x10FlowStatsRequest\x12\x1d\n" +
+	"\n" +
+	"capture_id\x18\x01 \x01(\tR\tcaptureId\x12\x10\n" +
+	"\x03top\x18\x02 \x01(\rR\x03top\"\x8a\x03\n" +
+	"\tFlowStats\x12\x1b\n" +
+	"\taddress_a\x18\x01 \x01(\fR\baddressA\x12\x15\n" +
+	"\x06port_a\x18\x02 \x01(\rR\x05portA\x12\x1b\n" +
+	"\taddress_b\x18\x03 \x01(\fR\baddressB\x12\x15\n" +
+	"\x06port_b\x18\x04 \x01(\rR\x05portB\x12\x1a\n" +
+	"\bprotocol\x18\x05 \x01(\rR\bprotocol\x12#\n" +
+	"\x0epackets_a_to_b\x18\x06 \x01(\x04R\vpacketsAToB\x12\x1f\n" +
+	"\fbytes_a_to_b\x18\a \x01(\x04R\tbytesAToB\x12#\n" +
+	"\x0epackets_b_to_a\x18\b \x01(\x04R\vpacketsBToA\x12\x1f\n" +
+	"\fbytes_b_to_a\x18\t \x01(\x04R\tbytesBToA\x12(\n" +
+	"\x10first_seen_nanos\x18\n" +
+	" \x01(\x03R\x0efirstSeenNanos\x12&\n" +
+	"\x0flast_seen_nanos\x18\v \x01(\x03R\rlastSeenNanos\x12\x1b\n" +
+	"\ttcp_state\x18\f \x01(\tR\btcpState\"`\n" +
+	"\x11FlowStatsResponse\x12\x16\n" +
+	"\x06active\x18\x01 \x01(\rR\x06active\x123\n" +
+	"\x05flows\x18\x02 \x03(\v2\x1d.sniffer.control.v1.FlowStatsR\x05flows2\xf2\x03\n" +
+	"\aSniffer\x12g\n" +
+	"\x0eListInterfaces\x12).sniffer.control.v1.ListInterfacesRequest\x1a*.sniffer.control.v1.ListInterfacesResponse\x12a\n" +
+	"\fStartCapture\x12'.sniffer.control.v1.StartCaptureRequest\x1a(.sniffer.control.v1.StartCaptureResponse\x12^\n" +
+	"\vStopCapture\x12&.sniffer.control.v1.StopCaptureRequest\x1a'.sniffer.control.v1.StopCaptureResponse\x12^\n" +
+	"\rStreamPackets\x12(.sniffer.control.v1.StreamPacketsRequest\x1a!.sniffer.control.v1.PacketMessage0\x01\x12[\n" +
+	"\fGetFlowStats\x12$.sniffer.control.v1.FlowStatsRequest\x1a%.sniffer.control.v1.FlowStatsResponseB\x1dZ\x1bsniffer/application/controlb\x06proto3"
+
+var (
+	file_sniffer_proto_rawDescOnce sync.Once
+	file_sniffer_proto_rawDescData []byte
+)
+
+func file_sniffer_proto_rawDescGZIP() []byte {
+	file_sniffer_proto_rawDescOnce.Do(func() {
+		file_sniffer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sniffer_proto_rawDesc), len(file_sniffer_proto_rawDesc)))
+	})
+	return file_sniffer_proto_rawDescData
+}
+
+var file_sniffer_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_sniffer_proto_goTypes = []any{
+	(*ListInterfacesRequest)(nil),  // 0: sniffer.control.v1.ListInterfacesRequest
+	(*Interface)(nil),              // 1: sniffer.control.v1.Interface
+	(*ListInterfacesResponse)(nil), // 2: sniffer.control.v1.ListInterfacesResponse
+	(*StartCaptureRequest)(nil),    // 3: sniffer.control.v1.StartCaptureRequest
+	(*StartCaptureResponse)(nil),   // 4: sniffer.control.v1.StartCaptureResponse
+	(*StopCaptureRequest)(nil),     // 5: sniffer.control.v1.StopCaptureRequest
+	(*CaptureStats)(nil),           // 6: sniffer.control.v1.CaptureStats
+	(*StopCaptureResponse)(nil),    // 7: sniffer.control.v1.StopCaptureResponse
+	(*StreamPacketsRequest)(nil),   // 8: sniffer.control.v1.StreamPacketsRequest
+	(*EthernetHeader)(nil),         // 9: sniffer.control.v1.EthernetHeader
+	(*Ipv4Header)(nil),             // 10: sniffer.control.v1.Ipv4Header
+	(*ArpHeader)(nil),              // 11: sniffer.control.v1.ArpHeader
+	(*TcpHeader)(nil),              // 12: sniffer.control.v1.TcpHeader
+	(*UdpHeader)(nil),              // 13: sniffer.control.v1.UdpHeader
+	(*IcmpV4Header)(nil),           // 14: sniffer.control.v1.IcmpV4Header
+	(*PacketMessage)(nil),          // 15: sniffer.control.v1.PacketMessage
+	(*FlowStatsRequest)(nil),       // 16: sniffer.control.v1.FlowStatsRequest
+	(*FlowStats)(nil),              // 17: sniffer.control.v1.FlowStats
+	(*FlowStatsResponse)(nil),      // 18: sniffer.control.v1.FlowStatsResponse
+}
+var file_sniffer_proto_depIdxs = []int32{
+	1,  // 0: sniffer.control.v1.ListInterfacesResponse.interfaces:type_name -> sniffer.control.v1.Interface
+	6,  // 1: sniffer.control.v1.StopCaptureResponse.stats:type_name -> sniffer.control.v1.CaptureStats
+	9,  // 2: sniffer.control.v1.PacketMessage.ethernet:type_name -> sniffer.control.v1.EthernetHeader
+	10, // 3: sniffer.control.v1.PacketMessage.ipv4:type_name -> sniffer.control.v1.Ipv4Header
+	11, // 4: sniffer.control.v1.PacketMessage.arp:type_name -> sniffer.control.v1.ArpHeader
+	12, // 5: sniffer.control.v1.PacketMessage.tcp:type_name -> sniffer.control.v1.TcpHeader
+	13, // 6: sniffer.control.v1.PacketMessage.udp:type_name -> sniffer.control.v1.UdpHeader
+	14, // 7: sniffer.control.v1.PacketMessage.icmpv4:type_name -> sniffer.control.v1.IcmpV4Header
+	17, // 8: sniffer.control.v1.FlowStatsResponse.flows:type_name -> sniffer.control.v1.FlowStats
+	0,  // 9: sniffer.control.v1.Sniffer.ListInterfaces:input_type -> sniffer.control.v1.ListInterfacesRequest
+	3,  // 10: sniffer.control.v1.Sniffer.StartCapture:input_type -> sniffer.control.v1.StartCaptureRequest
+	5,  // 11: sniffer.control.v1.Sniffer.StopCapture:input_type -> sniffer.control.v1.StopCaptureRequest
+	8,  // 12: sniffer.control.v1.Sniffer.StreamPackets:input_type -> sniffer.control.v1.StreamPacketsRequest
+	16, // 13: sniffer.control.v1.Sniffer.GetFlowStats:input_type -> sniffer.control.v1.FlowStatsRequest
+	2,  // 14: sniffer.control.v1.Sniffer.ListInterfaces:output_type -> sniffer.control.v1.ListInterfacesResponse
+	4,  // 15: sniffer.control.v1.Sniffer.StartCapture:output_type -> sniffer.control.v1.StartCaptureResponse
+	7,  // 16: sniffer.control.v1.Sniffer.StopCapture:output_type -> sniffer.control.v1.StopCaptureResponse
+	15, // 17: sniffer.control.v1.Sniffer.StreamPackets:output_type -> sniffer.control.v1.PacketMessage
+	18, // 18: sniffer.control.v1.Sniffer.GetFlowStats:output_type -> sniffer.control.v1.FlowStatsResponse
+	14, // [14:19] is the sub-list for method output_type
+	9,  // [9:14] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_sniffer_proto_init() }
+func file_sniffer_proto_init() {
+	if File_sniffer_proto != nil {
+		return
+	}
+	file_sniffer_proto_msgTypes[15].OneofWrappers = []any{
+		(*PacketMessage_Ipv4)(nil),
+		(*PacketMessage_Arp)(nil),
+		(*PacketMessage_Tcp)(nil),
+		(*PacketMessage_Udp)(nil),
+		(*PacketMessage_Icmpv4)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sniffer_proto_rawDesc), len(file_sniffer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sniffer_proto_goTypes,
+		DependencyIndexes: file_sniffer_proto_depIdxs,
+		MessageInfos:      file_sniffer_proto_msgTypes,
+	}.Build()
+	File_sniffer_proto = out.File
+	file_sniffer_proto_goTypes = nil
+	file_sniffer_proto_depIdxs = nil
+}
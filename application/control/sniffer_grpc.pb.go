@@ -0,0 +1,287 @@
+// Remote capture control API served by "sniffer agent". sniffer.pb.go and
+// sniffer_grpc.pb.go are generated from this file, see go:generate in
+// service.go.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: sniffer.proto
+
+package control
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Sniffer_ListInterfaces_FullMethodName = "/sniffer.control.v1.Sniffer/ListInterfaces"
+	Sniffer_StartCapture_FullMethodName   = "/sniffer.control.v1.Sniffer/StartCapture"
+	Sniffer_StopCapture_FullMethodName    = "/sniffer.control.v1.Sniffer/StopCapture"
+	Sniffer_StreamPackets_FullMethodName  = "/sniffer.control.v1.Sniffer/StreamPackets"
+	Sniffer_GetFlowStats_FullMethodName   = "/sniffer.control.v1.Sniffer/GetFlowStats"
+)
+
+// SnifferClient is the client API for Sniffer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SnifferClient interface {
+	ListInterfaces(ctx context.Context, in *ListInterfacesRequest, opts ...grpc.CallOption) (*ListInterfacesResponse, error)
+	StartCapture(ctx context.Context, in *StartCaptureRequest, opts ...grpc.CallOption) (*StartCaptureResponse, error)
+	StopCapture(ctx context.Context, in *StopCaptureRequest, opts ...grpc.CallOption) (*StopCaptureResponse, error)
+	// StreamPackets follows a running capture until it is stopped or the
+	// client goes away. Packets the client is too slow for are dropped and
+	// counted in CaptureStats.stream_dropped.
+	StreamPackets(ctx context.Context, in *StreamPacketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PacketMessage], error)
+	GetFlowStats(ctx context.Context, in *FlowStatsRequest, opts ...grpc.CallOption) (*FlowStatsResponse, error)
+}
+
+type snifferClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSnifferClient(cc grpc.ClientConnInterface) SnifferClient {
+	return &snifferClient{cc}
+}
+
+func (c *snifferClient) ListInterfaces(ctx context.Context, in *ListInterfacesRequest, opts ...grpc.CallOption) (*ListInterfacesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInterfacesResponse)
+	err := c.cc.Invoke(ctx, Sniffer_ListInterfaces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snifferClient) StartCapture(ctx context.Context, in *StartCaptureRequest, opts ...grpc.CallOption) (*StartCaptureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartCaptureResponse)
+	err := c.cc.Invoke(ctx, Sniffer_StartCapture_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snifferClient) StopCapture(ctx context.Context, in *StopCaptureRequest, opts ...grpc.CallOption) (*StopCaptureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopCaptureResponse)
+	err := c.cc.Invoke(ctx, Sniffer_StopCapture_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snifferClient) StreamPackets(ctx context.Context, in *StreamPacketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PacketMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Sniffer_ServiceDesc.Streams[0], Sniffer_StreamPackets_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamPacketsRequest, PacketMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sniffer_StreamPacketsClient = grpc.ServerStreamingClient[PacketMessage]
+
+func (c *snifferClient) GetFlowStats(ctx context.Context, in *FlowStatsRequest, opts ...grpc.CallOption) (*FlowStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FlowStatsResponse)
+	err := c.cc.Invoke(ctx, Sniffer_GetFlowStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SnifferServer is the server API for Sniffer service.
+// All implementations must embed UnimplementedSnifferServer
+// for forward compatibility.
+type SnifferServer interface {
+	ListInterfaces(context.Context, *ListInterfacesRequest) (*ListInterfacesResponse, error)
+	StartCapture(context.Context, *StartCaptureRequest) (*StartCaptureResponse, error)
+	StopCapture(context.Context, *StopCaptureRequest) (*StopCaptureResponse, error)
+	// StreamPackets follows a running capture until it is stopped or the
+	// client goes away. Packets the client is too slow for are dropped and
+	// counted in CaptureStats.stream_dropped.
+	StreamPackets(*StreamPacketsRequest, grpc.ServerStreamingServer[PacketMessage]) error
+	GetFlowStats(context.Context, *FlowStatsRequest) (*FlowStatsResponse, error)
+	mustEmbedUnimplementedSnifferServer()
+}
+
+// UnimplementedSnifferServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSnifferServer struct{}
+
+func (UnimplementedSnifferServer) ListInterfaces(context.Context, *ListInterfacesRequest) (*ListInterfacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInterfaces not implemented")
+}
+func (UnimplementedSnifferServer) StartCapture(context.Context, *StartCaptureRequest) (*StartCaptureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartCapture not implemented")
+}
+func (UnimplementedSnifferServer) StopCapture(context.Context, *StopCaptureRequest) (*StopCaptureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopCapture not implemented")
+}
+func (UnimplementedSnifferServer) StreamPackets(*StreamPacketsRequest, grpc.ServerStreamingServer[PacketMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPackets not implemented")
+}
+func (UnimplementedSnifferServer) GetFlowStats(context.Context, *FlowStatsRequest) (*FlowStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFlowStats not implemented")
+}
+func (UnimplementedSnifferServer) mustEmbedUnimplementedSnifferServer() {}
+func (UnimplementedSnifferServer) testEmbeddedByValue()                 {}
+
+// UnsafeSnifferServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SnifferServer will
+// result in compilation errors.
+type UnsafeSnifferServer interface {
+	mustEmbedUnimplementedSnifferServer()
+}
+
+func RegisterSnifferServer(s grpc.ServiceRegistrar, srv SnifferServer) {
+	// If the following call pancis, it indicates UnimplementedSnifferServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Sniffer_ServiceDesc, srv)
+}
+
+func _Sniffer_ListInterfaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInterfacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnifferServer).ListInterfaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sniffer_ListInterfaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnifferServer).ListInterfaces(ctx, req.(*ListInterfacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sniffer_StartCapture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartCaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnifferServer).StartCapture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sniffer_StartCapture_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnifferServer).StartCapture(ctx, req.(*StartCaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sniffer_StopCapture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopCaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnifferServer).StopCapture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sniffer_StopCapture_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnifferServer).StopCapture(ctx, req.(*StopCaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sniffer_StreamPackets_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPacketsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnifferServer).StreamPackets(m, &grpc.GenericServerStream[StreamPacketsRequest, PacketMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sniffer_StreamPacketsServer = grpc.ServerStreamingServer[PacketMessage]
+
+func _Sniffer_GetFlowStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlowStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnifferServer).GetFlowStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sniffer_GetFlowStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnifferServer).GetFlowStats(ctx, req.(*FlowStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Sniffer_ServiceDesc is the grpc.ServiceDesc for Sniffer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sniffer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sniffer.control.v1.Sniffer",
+	HandlerType: (*SnifferServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListInterfaces",
+			Handler:    _Sniffer_ListInterfaces_Handler,
+		},
+		{
+			MethodName: "StartCapture",
+			Handler:    _Sniffer_StartCapture_Handler,
+		},
+		{
+			MethodName: "StopCapture",
+			Handler:    _Sniffer_StopCapture_Handler,
+		},
+		{
+			MethodName: "GetFlowStats",
+			Handler:    _Sniffer_GetFlowStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPackets",
+			Handler:       _Sniffer_StreamPackets_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sniffer.proto",
+}
@@ -0,0 +1,71 @@
+package control
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func loadTls(certFile string, keyFile string, caFile string) (tls.Certificate, *x509.CertPool, error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return tls.Certificate{}, nil, errors.New("a certificate, its key and a CA are required for mutual TLS")
+	}
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return tls.Certificate{}, nil, fmt.Errorf("%s: no PEM certificates", caFile)
+	}
+	return certificate, pool, nil
+}
+
+// ServerTls loads the agent's certificate and the CA its clients'
+// certificates must be signed by. Clients without one are refused during
+// the handshake.
+func ServerTls(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+	certificate, pool, err := loadTls(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+	}, nil
+}
+
+// ClientTls loads the client certificate presented to agents and the CA
+// their certificates must be signed by.
+func ClientTls(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+	certificate, pool, err := loadTls(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+	}, nil
+}
+
+// Serve runs the service over TLS on listener until it fails.
+func Serve(listener net.Listener, s *Service, config *tls.Config) error {
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)))
+	RegisterSnifferServer(server, s)
+	return server.Serve(listener)
+}
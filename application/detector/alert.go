@@ -0,0 +1,35 @@
+package detector
+
+import (
+	"fmt"
+	"time"
+)
+
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+func (s Severity) ToString() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	}
+	return "critical"
+}
+
+type Alert struct {
+	Time     time.Time
+	Severity Severity
+	Kind     string
+	Message  string
+}
+
+func (a Alert) ToString() string {
+	return fmt.Sprintf("%s [%s] %s: %s", a.Time.Format("15:04:05.000"), a.Severity.ToString(), a.Kind, a.Message)
+}
@@ -0,0 +1,221 @@
+package detector
+
+import (
+	"bytes"
+	"fmt"
+	"sniffer/application/packet"
+	"sync"
+	"time"
+)
+
+const (
+	ArpBindingChanged    = "ARP binding changed"
+	ArpDuplicateAddress  = "duplicate IP address"
+	ArpGratuitousFlood   = "gratuitous ARP flood"
+	ArpUnsolicitedReply  = "ARP reply without request"
+	ArpEthernetMismatch  = "ARP sender MAC differs from Ethernet source"
+	ArpOperationRequest  = 1
+	ArpOperationReply    = 2
+	arpPendingLimit      = 4096
+	arpGratuitousHistory = 64
+)
+
+type ArpConfig struct {
+	// ConflictWindow is how recently the previous owner must have been seen
+	// for a new MAC to count as a duplicate address rather than a move.
+	ConflictWindow time.Duration
+	// RequestTimeout is how long a request waits for its reply.
+	RequestTimeout time.Duration
+	// GratuitousLimit gratuitous announcements per GratuitousWindow from one
+	// MAC raise a flood alert.
+	GratuitousLimit  int
+	GratuitousWindow time.Duration
+}
+
+func DefaultArpConfig() ArpConfig {
+	return ArpConfig{
+		ConflictWindow:   10 * time.Second,
+		RequestTimeout:   5 * time.Second,
+		GratuitousLimit:  10,
+		GratuitousWindow: 10 * time.Second,
+	}
+}
+
+type ArpBinding struct {
+	Mac       [6]byte
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type ArpMonitor struct {
+	config     ArpConfig
+	mutex      sync.Mutex
+	bindings   map[[4]byte]*ArpBinding
+	pending    map[[4]byte]time.Time
+	gratuitous map[[6]byte][]time.Time
+}
+
+func NewArpMonitor(config ArpConfig) *ArpMonitor {
+	return &ArpMonitor{
+		config:     config,
+		bindings:   make(map[[4]byte]*ArpBinding),
+		pending:    make(map[[4]byte]time.Time),
+		gratuitous: make(map[[6]byte][]time.Time),
+	}
+}
+
+// Observe checks one frame and returns the alerts it raised. Frames that do
+// not carry ARP are ignored.
+func (m *ArpMonitor) Observe(p packet.Parsable, timestamp time.Time) []Alert {
+	ethernet, ok := p.(packet.EthernetPacket)
+	if !ok || !ethernet.CanParseMore {
+		return nil
+	}
+	arp, ok := ethernet.PacketParser.(packet.ArpPacket)
+	if !ok {
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var alerts []Alert
+	alert := func(severity Severity, kind string, format string, args ...interface{}) {
+		alerts = append(alerts, Alert{Time: timestamp, Severity: severity, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	header := arp.Header
+	var senderIp, targetIp [4]byte
+	var senderMac [6]byte
+	copy(senderIp[:], header.SrcAddress.Value)
+	copy(targetIp[:], header.DstAddress.Value)
+	copy(senderMac[:], header.SrcHardwareAddr.Value)
+
+	if !bytes.Equal(ethernet.Header.SrcMacAddr.Value, header.SrcHardwareAddr.Value) {
+		alert(Warning, ArpEthernetMismatch, "frame from %s claims sender %s for %s",
+			ethernet.Header.SrcMacAddr.ToString(), header.SrcHardwareAddr.ToString(), header.SrcAddress.ToString())
+	}
+
+	gratuitous := senderIp == targetIp && senderIp != [4]byte{}
+	if gratuitous && m.countGratuitous(senderMac, timestamp) == m.config.GratuitousLimit {
+		alert(Warning, ArpGratuitousFlood, "%s sent %d gratuitous ARPs within %s",
+			header.SrcHardwareAddr.ToString(), m.config.GratuitousLimit, m.config.GratuitousWindow)
+	}
+
+	switch header.Operation.Value {
+	case ArpOperationRequest:
+		if senderIp == [4]byte{} {
+			// An RFC 5227 probe, the sender has no address yet.
+			if binding, ok := m.bindings[targetIp]; ok && binding.Mac != senderMac &&
+				timestamp.Sub(binding.LastSeen) < m.config.ConflictWindow {
+				alert(Warning, ArpDuplicateAddress, "%s probes for %s which is in use by %s",
+					header.SrcHardwareAddr.ToString(), header.DstAddress.ToString(), MacToString(binding.Mac))
+			}
+			return alerts
+		}
+		if !gratuitous {
+			m.addPending(targetIp, timestamp)
+		}
+
+	case ArpOperationReply:
+		requested, ok := m.pending[senderIp]
+		if ok {
+			delete(m.pending, senderIp)
+		}
+		if !gratuitous && (!ok || timestamp.Sub(requested) > m.config.RequestTimeout) {
+			alert(Warning, ArpUnsolicitedReply, "%s announces %s without a matching request",
+				header.SrcHardwareAddr.ToString(), header.SrcAddress.ToString())
+		}
+
+	default:
+		return alerts
+	}
+
+	if senderIp != [4]byte{} {
+		alerts = append(alerts, m.bind(senderIp, senderMac, timestamp)...)
+	}
+	return alerts
+}
+
+func (m *ArpMonitor) bind(ip [4]byte, mac [6]byte, timestamp time.Time) []Alert {
+	binding, ok := m.bindings[ip]
+	if !ok {
+		m.bindings[ip] = &ArpBinding{Mac: mac, FirstSeen: timestamp, LastSeen: timestamp}
+		return nil
+	}
+	if binding.Mac == mac {
+		binding.LastSeen = timestamp
+		return nil
+	}
+
+	var result Alert
+	address := IpToString(ip)
+	if timestamp.Sub(binding.LastSeen) < m.config.ConflictWindow {
+		result = Alert{Time: timestamp, Severity: Critical, Kind: ArpDuplicateAddress,
+			Message: fmt.Sprintf("%s is claimed by %s and %s", address, MacToString(binding.Mac), MacToString(mac))}
+	} else {
+		result = Alert{Time: timestamp, Severity: Warning, Kind: ArpBindingChanged,
+			Message: fmt.Sprintf("%s moved from %s to %s", address, MacToString(binding.Mac), MacToString(mac))}
+	}
+
+	m.bindings[ip] = &ArpBinding{Mac: mac, FirstSeen: timestamp, LastSeen: timestamp}
+	return []Alert{result}
+}
+
+func (m *ArpMonitor) addPending(ip [4]byte, timestamp time.Time) {
+	if len(m.pending) >= arpPendingLimit {
+		for key, requested := range m.pending {
+			if timestamp.Sub(requested) > m.config.RequestTimeout {
+				delete(m.pending, key)
+			}
+		}
+	}
+	m.pending[ip] = timestamp
+}
+
+// countGratuitous records an announcement and returns how many fell inside
+// the window, so the flood alert fires once when the limit is crossed.
+func (m *ArpMonitor) countGratuitous(mac [6]byte, timestamp time.Time) int {
+	history := m.gratuitous[mac]
+	kept := history[:0]
+	for _, t := range history {
+		if timestamp.Sub(t) <= m.config.GratuitousWindow {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) < arpGratuitousHistory {
+		kept = append(kept, timestamp)
+	}
+	m.gratuitous[mac] = kept
+	return len(kept)
+}
+
+// Bindings returns a copy of the current IP to MAC table.
+func (m *ArpMonitor) Bindings() map[[4]byte]ArpBinding {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make(map[[4]byte]ArpBinding, len(m.bindings))
+	for ip, binding := range m.bindings {
+		result[ip] = *binding
+	}
+	return result
+}
+
+func (m *ArpMonitor) ToString() string {
+	bindings := m.Bindings()
+	result := fmt.Sprintf("ARP bindings - %d addresses\n", len(bindings))
+	for ip, binding := range bindings {
+		result += fmt.Sprintf("  %-15s %s - first seen %s - last seen %s\n", IpToString(ip), MacToString(binding.Mac),
+			binding.FirstSeen.Format("15:04:05"), binding.LastSeen.Format("15:04:05"))
+	}
+	return result
+}
+
+func IpToString(ip [4]byte) string {
+	return packet.IpAddress{Value: ip[:]}.ToString()
+}
+
+func MacToString(mac [6]byte) string {
+	return packet.MacAddress{Value: mac[:]}.ToString()
+}
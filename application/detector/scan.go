@@ -0,0 +1,406 @@
+package detector
+
+import (
+	"fmt"
+	"sniffer/application/common"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sync"
+	"time"
+)
+
+const (
+	HorizontalScan = "horizontal port scan"
+	VerticalScan   = "vertical port scan"
+	SynFlood       = "SYN flood"
+	HalfOpenFlood  = "half-open connection exhaustion"
+	UdpScan        = "UDP port scan"
+	IcmpSweep      = "ICMP sweep"
+
+	icmpEchoRequest       = 8
+	icmpDestUnreachable   = 3
+	icmpPortUnreachable   = 3
+	icmpUnreachableUnused = 4
+	halfOpenTrackLimit    = 65536
+	// udpServicePortLimit bounds the ports a UDP datagram must target to
+	// count as a probe; replies to ephemeral client ports never do.
+	udpServicePortLimit = 1024
+	rateBuckets         = 10
+)
+
+type ScanConfig struct {
+	// Window is the sliding window every threshold is measured over.
+	Window time.Duration
+	// HorizontalThreshold distinct hosts probed on one port by one source.
+	HorizontalThreshold int
+	// VerticalThreshold distinct ports probed on one host by one source.
+	VerticalThreshold int
+	// SynFloodThreshold SYNs received by one host.
+	SynFloodThreshold int
+	// HalfOpenThreshold handshakes left incomplete towards one host.
+	HalfOpenThreshold int
+	HalfOpenTimeout   time.Duration
+	// UdpScanThreshold distinct closed UDP ports reported back to one source.
+	UdpScanThreshold int
+	// IcmpSweepThreshold distinct hosts pinged by one source.
+	IcmpSweepThreshold int
+}
+
+func DefaultScanConfig() ScanConfig {
+	return ScanConfig{
+		Window:              60 * time.Second,
+		HorizontalThreshold: 20,
+		VerticalThreshold:   50,
+		SynFloodThreshold:   1000,
+		HalfOpenThreshold:   500,
+		HalfOpenTimeout:     30 * time.Second,
+		UdpScanThreshold:    20,
+		IcmpSweepThreshold:  20,
+	}
+}
+
+// distinctWindow counts distinct values seen within the sliding window.
+// Values only age out in Expire, so the count may include values up to one
+// Expire interval older than the window.
+type distinctWindow struct {
+	seen map[uint64]time.Time
+}
+
+func (d *distinctWindow) add(value uint64, timestamp time.Time) int {
+	if d.seen == nil {
+		d.seen = make(map[uint64]time.Time)
+	}
+	d.seen[value] = timestamp
+	return len(d.seen)
+}
+
+func (d *distinctWindow) prune(now time.Time, window time.Duration) int {
+	for v, t := range d.seen {
+		if now.Sub(t) > window {
+			delete(d.seen, v)
+		}
+	}
+	return len(d.seen)
+}
+
+// rateWindow counts events within the sliding window in a ring of
+// rateBuckets time buckets, so the window slides a bucket at a time.
+type rateWindow struct {
+	counts [rateBuckets]int
+	// buckets holds the bucket number each slot counts for, the time
+	// divided by the bucket width.
+	buckets  [rateBuckets]int64
+	lastSeen time.Time
+}
+
+func (r *rateWindow) add(timestamp time.Time, window time.Duration) int {
+	width := int64(window) / rateBuckets
+	if width <= 0 {
+		width = 1
+	}
+	bucket := timestamp.UnixNano() / width
+	slot := bucket % rateBuckets
+	if r.buckets[slot] != bucket {
+		r.buckets[slot] = bucket
+		r.counts[slot] = 0
+	}
+	r.counts[slot]++
+	r.lastSeen = timestamp
+
+	total := 0
+	for i, b := range r.buckets {
+		if age := bucket - b; age >= 0 && age < rateBuckets {
+			total += r.counts[i]
+		}
+	}
+	return total
+}
+
+// halfOpen is a SYN without the handshake completing yet.
+type halfOpen struct {
+	target  [4]byte
+	started time.Time
+}
+
+type scanKey struct {
+	Address [4]byte
+	Value   uint32
+}
+
+type ScanDetector struct {
+	config ScanConfig
+	mutex  sync.Mutex
+
+	// horizontal: source+port -> distinct destination hosts
+	horizontal map[scanKey]*distinctWindow
+	// vertical: source+destination -> distinct destination ports
+	vertical map[scanKey]*distinctWindow
+	// closedTcp, closedUdp: scanner -> distinct host:port answered with RST or ICMP
+	closedTcp map[[4]byte]*distinctWindow
+	closedUdp map[[4]byte]*distinctWindow
+	sweep     map[[4]byte]*distinctWindow
+	syns      map[[4]byte]*rateWindow
+	halfOpen  map[flow.Key]halfOpen
+	// halfOpenCount counts the halfOpen entries of each target.
+	halfOpenCount map[[4]byte]int
+
+	alerted map[string]time.Time
+}
+
+func NewScanDetector(config ScanConfig) *ScanDetector {
+	return &ScanDetector{
+		config:        config,
+		horizontal:    make(map[scanKey]*distinctWindow),
+		vertical:      make(map[scanKey]*distinctWindow),
+		closedTcp:     make(map[[4]byte]*distinctWindow),
+		closedUdp:     make(map[[4]byte]*distinctWindow),
+		sweep:         make(map[[4]byte]*distinctWindow),
+		syns:          make(map[[4]byte]*rateWindow),
+		halfOpen:      make(map[flow.Key]halfOpen),
+		halfOpenCount: make(map[[4]byte]int),
+		alerted:       make(map[string]time.Time),
+	}
+}
+
+func (d *ScanDetector) Observe(p packet.Parsable, timestamp time.Time) []Alert {
+	o, ok := flow.Observe(p)
+	if !ok {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var alerts []Alert
+	switch o.Protocol {
+	case flow.IpProtocolTcp:
+		alerts = d.observeTcp(o, timestamp)
+	case flow.IpProtocolUdp:
+		// Most datagrams are ordinary traffic, replies to ephemeral client
+		// ports included. UDP scans are caught by the port unreachable
+		// messages they draw, see observeIcmp, or by the number of service
+		// ports they target.
+		if o.Destination.Port < udpServicePortLimit {
+			alerts = d.countVertical(nil, o, timestamp)
+		}
+	case flow.IpProtocolIcmp:
+		alerts = d.observeIcmp(o, timestamp)
+	}
+	return alerts
+}
+
+func (d *ScanDetector) observeTcp(o flow.Observation, timestamp time.Time) []Alert {
+	flags := o.TcpFlags
+	syn := flags&packet.TcpFlagSyn != 0
+	ack := flags&packet.TcpFlagAck != 0
+	rst := flags&packet.TcpFlagRst != 0
+	key, _ := o.Key()
+
+	var alerts []Alert
+	switch {
+	case syn && !ack:
+		alerts = d.countHorizontal(alerts, o, timestamp)
+		alerts = d.countVertical(alerts, o, timestamp)
+
+		target := o.Destination.Address
+		if d.window(d.syns, target).add(timestamp, d.config.Window) >= d.config.SynFloodThreshold {
+			alerts = d.raise(alerts, timestamp, Critical, SynFlood, target,
+				"%s received %d SYNs within %s", IpToString(target), d.config.SynFloodThreshold, d.config.Window)
+		}
+
+		if entry, ok := d.halfOpen[key]; ok {
+			entry.started = timestamp
+			d.halfOpen[key] = entry
+		} else if len(d.halfOpen) < halfOpenTrackLimit {
+			d.halfOpen[key] = halfOpen{target: target, started: timestamp}
+			d.halfOpenCount[target]++
+		}
+		if d.halfOpenCount[target] >= d.config.HalfOpenThreshold {
+			alerts = d.raise(alerts, timestamp, Critical, HalfOpenFlood, target,
+				"%s has %d incomplete handshakes", IpToString(target), d.config.HalfOpenThreshold)
+		}
+
+	case rst:
+		d.completeHalfOpen(key)
+		if !ack {
+			// Scanners tear down half-open probes with a bare RST.
+			break
+		}
+		// A RST/ACK from the probed side tells the scanner the port is closed.
+		scanner := o.Destination.Address
+		value := uint64(addressValue(o.Source.Address))<<16 | uint64(o.Source.Port)
+		if d.distinct(d.closedTcp, scanner).add(value, timestamp) >= d.config.VerticalThreshold {
+			alerts = d.raise(alerts, timestamp, Warning, VerticalScan, scanner,
+				"%s hit %d closed TCP ports within %s", IpToString(scanner), d.config.VerticalThreshold, d.config.Window)
+		}
+
+	case ack && !syn:
+		d.completeHalfOpen(key)
+	}
+	return alerts
+}
+
+func (d *ScanDetector) completeHalfOpen(key flow.Key) {
+	if entry, ok := d.halfOpen[key]; ok {
+		delete(d.halfOpen, key)
+		d.dropHalfOpen(entry.target)
+	}
+}
+
+func (d *ScanDetector) dropHalfOpen(target [4]byte) {
+	d.halfOpenCount[target]--
+	if d.halfOpenCount[target] <= 0 {
+		delete(d.halfOpenCount, target)
+	}
+}
+
+// countHorizontal accounts a probe towards the number of hosts its source
+// probed on the same port.
+func (d *ScanDetector) countHorizontal(alerts []Alert, o flow.Observation, timestamp time.Time) []Alert {
+	source := o.Source.Address
+	port := o.Destination.Port
+	horizontal := d.distinctFor(d.horizontal, scanKey{source, uint32(port) | uint32(o.Protocol)<<16})
+	if horizontal.add(uint64(addressValue(o.Destination.Address)), timestamp) >= d.config.HorizontalThreshold {
+		alerts = d.raise(alerts, timestamp, Warning, HorizontalScan, source,
+			"%s probed %d hosts on %s port %d within %s", IpToString(source), d.config.HorizontalThreshold, flow.ProtocolName(o.Protocol), port, d.config.Window)
+	}
+	return alerts
+}
+
+// countVertical accounts a probe towards the number of ports its source
+// probed on the same host.
+func (d *ScanDetector) countVertical(alerts []Alert, o flow.Observation, timestamp time.Time) []Alert {
+	source := o.Source.Address
+	vertical := d.distinctFor(d.vertical, scanKey{source, addressValue(o.Destination.Address)})
+	if vertical.add(uint64(o.Destination.Port)|uint64(o.Protocol)<<16, timestamp) >= d.config.VerticalThreshold {
+		alerts = d.raise(alerts, timestamp, Warning, VerticalScan, source,
+			"%s probed %d ports on %s within %s", IpToString(source), d.config.VerticalThreshold, IpToString(o.Destination.Address), d.config.Window)
+	}
+	return alerts
+}
+
+func (d *ScanDetector) observeIcmp(o flow.Observation, timestamp time.Time) []Alert {
+	var alerts []Alert
+
+	switch {
+	case o.IcmpType == icmpEchoRequest:
+		source := o.Source.Address
+		if d.distinct(d.sweep, source).add(uint64(addressValue(o.Destination.Address)), timestamp) >= d.config.IcmpSweepThreshold {
+			alerts = d.raise(alerts, timestamp, Warning, IcmpSweep, source,
+				"%s pinged %d hosts within %s", IpToString(source), d.config.IcmpSweepThreshold, d.config.Window)
+		}
+
+	case o.IcmpType == icmpDestUnreachable && o.IcmpCode == icmpPortUnreachable:
+		icmp, ok := o.Transport.(packet.IcmpV4Packet)
+		if !ok {
+			break
+		}
+		port, ok := unreachablePort(icmp.RawPayload)
+		if !ok {
+			break
+		}
+		scanner := o.Destination.Address
+		value := uint64(addressValue(o.Source.Address))<<16 | uint64(port)
+		if d.distinct(d.closedUdp, scanner).add(value, timestamp) >= d.config.UdpScanThreshold {
+			alerts = d.raise(alerts, timestamp, Warning, UdpScan, scanner,
+				"%s hit %d closed UDP ports within %s", IpToString(scanner), d.config.UdpScanThreshold, d.config.Window)
+		}
+	}
+	return alerts
+}
+
+// unreachablePort digs the destination port of the offending UDP datagram out
+// of an ICMP destination unreachable body.
+func unreachablePort(body []byte) (uint16, bool) {
+	if len(body) < icmpUnreachableUnused+packet.Ipv4MinHeaderSize {
+		return 0, false
+	}
+	original := body[icmpUnreachableUnused:]
+	headerLength := int(original[packet.Ipv4VersionAndIhlOffset]&15) * 4
+	if original[packet.Ipv4ProtocolOffset] != flow.IpProtocolUdp || len(original) < headerLength+packet.UdpHeaderSize {
+		return 0, false
+	}
+	udp := original[headerLength:]
+	return common.GetUint16FromBytes(udp[packet.UdpDestinationPortOffset:]), true
+}
+
+// raise appends an alert unless the same kind was already raised for the
+// address within the window.
+func (d *ScanDetector) raise(alerts []Alert, timestamp time.Time, severity Severity, kind string, address [4]byte, format string, args ...interface{}) []Alert {
+	id := kind + IpToString(address)
+	if last, ok := d.alerted[id]; ok && timestamp.Sub(last) < d.config.Window {
+		return alerts
+	}
+	d.alerted[id] = timestamp
+	return append(alerts, Alert{Time: timestamp, Severity: severity, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *ScanDetector) distinctFor(m map[scanKey]*distinctWindow, key scanKey) *distinctWindow {
+	w, ok := m[key]
+	if !ok {
+		w = &distinctWindow{}
+		m[key] = w
+	}
+	return w
+}
+
+func (d *ScanDetector) distinct(m map[[4]byte]*distinctWindow, key [4]byte) *distinctWindow {
+	w, ok := m[key]
+	if !ok {
+		w = &distinctWindow{}
+		m[key] = w
+	}
+	return w
+}
+
+func (d *ScanDetector) window(m map[[4]byte]*rateWindow, key [4]byte) *rateWindow {
+	w, ok := m[key]
+	if !ok {
+		w = &rateWindow{}
+		m[key] = w
+	}
+	return w
+}
+
+// Expire drops per-source state that saw no activity within the window
+// and handshakes older than HalfOpenTimeout. Counts only shrink here, so it
+// should run several times per window.
+func (d *ScanDetector) Expire(now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, m := range []map[scanKey]*distinctWindow{d.horizontal, d.vertical} {
+		for key, w := range m {
+			if w.prune(now, d.config.Window) == 0 {
+				delete(m, key)
+			}
+		}
+	}
+	for _, m := range []map[[4]byte]*distinctWindow{d.closedTcp, d.closedUdp, d.sweep} {
+		for key, w := range m {
+			if w.prune(now, d.config.Window) == 0 {
+				delete(m, key)
+			}
+		}
+	}
+	for key, w := range d.syns {
+		if now.Sub(w.lastSeen) > d.config.Window {
+			delete(d.syns, key)
+		}
+	}
+	for key, entry := range d.halfOpen {
+		if now.Sub(entry.started) > d.config.HalfOpenTimeout {
+			delete(d.halfOpen, key)
+			d.dropHalfOpen(entry.target)
+		}
+	}
+	for id, t := range d.alerted {
+		if now.Sub(t) > d.config.Window {
+			delete(d.alerted, id)
+		}
+	}
+}
+
+func addressValue(address [4]byte) uint32 {
+	return uint32(address[0])<<24 | uint32(address[1])<<16 | uint32(address[2])<<8 | uint32(address[3])
+}
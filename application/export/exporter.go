@@ -0,0 +1,229 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sniffer/application/flow"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTemplateRefresh = time.Minute
+	// maxMessageSize keeps export packets below a typical path MTU.
+	maxMessageSize = 1400
+)
+
+type Config struct {
+	// Version is NetflowV9 or Ipfix.
+	Version int
+	// Collector is a host:port reached over UDP, empty to disable.
+	Collector string
+	// File receives the same messages back to back, empty to disable.
+	File              string
+	ObservationDomain uint32
+	TemplateRefresh   time.Duration
+}
+
+type Exporter struct {
+	config   Config
+	template template
+	mutex    sync.Mutex
+	writers  []io.Writer
+	closers  []io.Closer
+	pending  []Record
+
+	// bootTime is the NetFlow v9 sysUpTime origin, see SetOrigin.
+	bootTime time.Time
+	// clock is the newest flow end exported. It stands in for the current
+	// time, so flows read from a file keep the times they were captured at.
+	clock        time.Time
+	lastTemplate time.Time
+	// sequence counts export packets for NetFlow v9 and data records for IPFIX.
+	sequence    uint32
+	messages    uint64
+	records     uint64
+	writeErrors uint64
+}
+
+func NewExporter(config Config) (*Exporter, error) {
+	if config.Version != NetflowV9 && config.Version != Ipfix {
+		return nil, fmt.Errorf("unsupported flow export version %d", config.Version)
+	}
+	if config.Collector == "" && config.File == "" {
+		return nil, errors.New("flow exporter needs a collector or a file")
+	}
+	if config.TemplateRefresh == 0 {
+		config.TemplateRefresh = DefaultTemplateRefresh
+	}
+
+	e := &Exporter{
+		config:   config,
+		template: newTemplate(config.Version),
+		bootTime: time.Now(),
+	}
+
+	if config.Collector != "" {
+		conn, err := net.Dial("udp", config.Collector)
+		if err != nil {
+			return nil, err
+		}
+		e.writers = append(e.writers, conn)
+		e.closers = append(e.closers, conn)
+	}
+
+	if config.File != "" {
+		file, err := os.Create(config.File)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.writers = append(e.writers, file)
+		e.closers = append(e.closers, file)
+	}
+
+	return e, nil
+}
+
+// SetOrigin makes first, the timestamp of the first packet captured, the
+// sysUpTime origin. Without it the origin is when the exporter was created,
+// which only suits live capture. Call it before the first Export.
+func (e *Exporter) SetOrigin(first time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.bootTime = first
+}
+
+// Export queues the records of a finished flow and sends every full message.
+// It fits flow.Config.OnEvict.
+func (e *Exporter) Export(f *flow.Flow) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pending = append(e.pending, RecordsFromFlow(f)...)
+	var err error
+	for len(e.pending) >= e.recordsPerMessage() {
+		if sendErr := e.send(e.recordsPerMessage()); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+func (e *Exporter) Flush() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var err error
+	for len(e.pending) > 0 {
+		n := e.recordsPerMessage()
+		if n > len(e.pending) {
+			n = len(e.pending)
+		}
+		if sendErr := e.send(n); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+func (e *Exporter) Close() error {
+	err := e.Flush()
+	for _, c := range e.closers {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (e *Exporter) ToString() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return fmt.Sprintf("Flow export v%d - messages %d - records %d - pending %d - write errors %d",
+		e.config.Version, e.messages, e.records, len(e.pending), e.writeErrors)
+}
+
+func (e *Exporter) headerSize() int {
+	if e.config.Version == Ipfix {
+		return ipfixHeaderSize
+	}
+	return netflowV9HeaderSize
+}
+
+func (e *Exporter) recordsPerMessage() int {
+	templateSize := setHeaderSize + 4 + 4*len(e.template.fields)
+	room := maxMessageSize - e.headerSize() - templateSize - setHeaderSize - 3
+	return room / e.template.recordLength()
+}
+
+// send writes the first n pending records as one message to every writer.
+// The records are done with even when a writer fails, so a collector that
+// is down neither holds the others back nor lets pending grow; the first
+// error is returned.
+func (e *Exporter) send(n int) error {
+	records := e.pending[:n]
+	for _, r := range records {
+		if r.End.After(e.clock) {
+			e.clock = r.End
+		}
+	}
+	now := e.clock
+	withTemplate := e.messages == 0 || now.Sub(e.lastTemplate) >= e.config.TemplateRefresh
+
+	message := make([]byte, e.headerSize(), maxMessageSize)
+	if withTemplate {
+		message = e.template.appendTemplateSet(message)
+		e.lastTemplate = now
+	}
+	message = e.template.appendDataSet(message, records, e.bootTime)
+	e.writeHeader(message, now, len(records), withTemplate)
+
+	var err error
+	for _, w := range e.writers {
+		if _, writeErr := w.Write(message); writeErr != nil {
+			e.writeErrors++
+			if err == nil {
+				err = writeErr
+			}
+		}
+	}
+
+	e.pending = e.pending[n:]
+	e.messages++
+	e.records += uint64(len(records))
+	if e.config.Version == Ipfix {
+		e.sequence += uint32(len(records))
+	} else {
+		e.sequence++
+	}
+	return err
+}
+
+func (e *Exporter) writeHeader(message []byte, now time.Time, recordCount int, withTemplate bool) {
+	header := make([]byte, 0, e.headerSize())
+	if e.config.Version == Ipfix {
+		header = appendUint16(header, Ipfix)
+		header = appendUint16(header, uint16(len(message)))
+		header = appendUint32(header, uint32(now.Unix()))
+		header = appendUint32(header, e.sequence)
+		header = appendUint32(header, e.config.ObservationDomain)
+	} else {
+		// NetFlow v9 counts template records as well as data records.
+		if withTemplate {
+			recordCount++
+		}
+		header = appendUint16(header, NetflowV9)
+		header = appendUint16(header, uint16(recordCount))
+		header = appendUint32(header, uptimeMillis(e.bootTime, now))
+		header = appendUint32(header, uint32(now.Unix()))
+		header = appendUint32(header, e.sequence)
+		header = appendUint32(header, e.config.ObservationDomain)
+	}
+	copy(message, header)
+}
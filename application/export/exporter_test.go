@@ -0,0 +1,221 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sniffer/application/flow"
+	"testing"
+	"time"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("collector down")
+}
+
+func testFlow(i int, start time.Time) *flow.Flow {
+	return &flow.Flow{
+		Key: flow.Key{
+			A:        flow.Endpoint{Address: [4]byte{10, 0, byte(i >> 8), byte(i)}, Port: 1024},
+			B:        flow.Endpoint{Address: [4]byte{192, 168, 0, 1}, Port: 80},
+			Protocol: flow.IpProtocolTcp,
+		},
+		AtoB: flow.Counters{Packets: 3, Bytes: 180, TcpFlags: 0x02, FirstSeen: start, LastSeen: start.Add(time.Second)},
+	}
+}
+
+// splitIpfix cuts a stream of IPFIX messages at their length fields.
+func splitIpfix(t *testing.T, stream []byte) [][]byte {
+	t.Helper()
+	var messages [][]byte
+	for len(stream) > 0 {
+		if len(stream) < ipfixHeaderSize {
+			t.Fatalf("%d bytes left over", len(stream))
+		}
+		length := int(binary.BigEndian.Uint16(stream[2:]))
+		messages = append(messages, stream[:length])
+		stream = stream[length:]
+	}
+	return messages
+}
+
+func TestExportSurvivesFailingWriter(t *testing.T) {
+	var file bytes.Buffer
+	e := &Exporter{
+		config:   Config{Version: Ipfix, TemplateRefresh: DefaultTemplateRefresh},
+		template: newTemplate(Ipfix),
+		writers:  []io.Writer{failingWriter{}, &file},
+	}
+	start := time.Unix(1000, 0)
+	failures := 0
+	for i := 0; i < 500; i++ {
+		if err := e.Export(testFlow(i, start)); err != nil {
+			failures++
+		}
+		if len(e.pending) >= e.recordsPerMessage() {
+			t.Fatalf("%d records pending after a failed write", len(e.pending))
+		}
+	}
+	if err := e.Flush(); err == nil {
+		t.Fatal("Flush did not report the failing collector")
+	}
+	if failures == 0 {
+		t.Fatal("Export did not report the failing collector")
+	}
+
+	messages := splitIpfix(t, file.Bytes())
+	if uint64(len(messages)) != e.messages {
+		t.Fatalf("file got %d messages, %d were sent", len(messages), e.messages)
+	}
+	if e.records != 500 {
+		t.Fatalf("%d records exported, want 500", e.records)
+	}
+}
+
+func TestExportToCollector(t *testing.T) {
+	for _, version := range []int{NetflowV9, Ipfix} {
+		listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		e, err := NewExporter(Config{Version: version, Collector: listener.LocalAddr().String(), ObservationDomain: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Unix(1000, 0)
+		e.SetOrigin(start.Add(-time.Second))
+		f := testFlow(1, start)
+		f.BtoA = flow.Counters{Packets: 2, Bytes: 120, TcpFlags: 0x12, FirstSeen: start, LastSeen: start.Add(2 * time.Second)}
+		if err := e.Export(f); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		buffer := make([]byte, 65535)
+		listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := listener.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMessage(t, version, buffer[:n], start)
+	}
+}
+
+// checkMessage decodes an export message holding the template set and the
+// two records of the flow built in TestExportToCollector.
+func checkMessage(t *testing.T, version int, message []byte, start time.Time) {
+	t.Helper()
+	headerSize, templateSetId := netflowV9HeaderSize, uint16(netflowV9TemplateSetId)
+	if version == Ipfix {
+		headerSize, templateSetId = ipfixHeaderSize, ipfixTemplateSetId
+	}
+	if got := binary.BigEndian.Uint16(message); got != uint16(version) {
+		t.Fatalf("version %d, want %d", got, version)
+	}
+	if version == Ipfix {
+		if got := binary.BigEndian.Uint16(message[2:]); int(got) != len(message) {
+			t.Fatalf("IPFIX length %d, message is %d bytes", got, len(message))
+		}
+	} else if got := binary.BigEndian.Uint16(message[2:]); got != 3 {
+		t.Fatalf("NetFlow v9 count %d, want 1 template and 2 data records", got)
+	}
+	if got := binary.BigEndian.Uint32(message[headerSize-4:]); got != 7 {
+		t.Fatalf("observation domain %d, want 7", got)
+	}
+
+	sets := message[headerSize:]
+	if got := binary.BigEndian.Uint16(sets); got != templateSetId {
+		t.Fatalf("first set id %d, want template set %d", got, templateSetId)
+	}
+	templateLength := int(binary.BigEndian.Uint16(sets[2:]))
+	templateSet := sets[setHeaderSize:templateLength]
+	if got := binary.BigEndian.Uint16(templateSet); got != TemplateId {
+		t.Fatalf("template id %d, want %d", got, TemplateId)
+	}
+	fieldCount := int(binary.BigEndian.Uint16(templateSet[2:]))
+	offsets := map[uint16]int{}
+	lengths := map[uint16]int{}
+	recordLength := 0
+	for i := 0; i < fieldCount; i++ {
+		id := binary.BigEndian.Uint16(templateSet[4+4*i:])
+		length := int(binary.BigEndian.Uint16(templateSet[6+4*i:]))
+		offsets[id], lengths[id] = recordLength, length
+		recordLength += length
+	}
+
+	dataSet := sets[templateLength:]
+	if got := binary.BigEndian.Uint16(dataSet); got != TemplateId {
+		t.Fatalf("data set id %d, want %d", got, TemplateId)
+	}
+	dataLength := int(binary.BigEndian.Uint16(dataSet[2:]))
+	if dataLength != len(dataSet) || dataLength%4 != 0 {
+		t.Fatalf("data set length %d, %d bytes left in the message", dataLength, len(dataSet))
+	}
+	records := dataSet[setHeaderSize:]
+	if len(records)/recordLength != 2 {
+		t.Fatalf("%d records, want 2", len(records)/recordLength)
+	}
+
+	value := func(record []byte, id uint16) uint64 {
+		field := record[offsets[id] : offsets[id]+lengths[id]]
+		var v uint64
+		for _, b := range field {
+			v = v<<8 | uint64(b)
+		}
+		return v
+	}
+	tests := []struct {
+		source, destination uint64
+		sourcePort          uint64
+		flags               uint64
+		packets, bytes      uint64
+		end                 time.Duration
+	}{
+		{0x0a000001, 0xc0a80001, 1024, 0x02, 3, 180, time.Second},
+		{0xc0a80001, 0x0a000001, 80, 0x12, 2, 120, 2 * time.Second},
+	}
+	for i, test := range tests {
+		record := records[i*recordLength:]
+		if got := value(record, ieSourceIpv4Address); got != test.source {
+			t.Errorf("record %d: source %#x, want %#x", i, got, test.source)
+		}
+		if got := value(record, ieDestinationIpv4Address); got != test.destination {
+			t.Errorf("record %d: destination %#x, want %#x", i, got, test.destination)
+		}
+		if got := value(record, ieSourceTransportPort); got != test.sourcePort {
+			t.Errorf("record %d: source port %d, want %d", i, got, test.sourcePort)
+		}
+		if got := value(record, ieProtocolIdentifier); got != flow.IpProtocolTcp {
+			t.Errorf("record %d: protocol %d, want %d", i, got, flow.IpProtocolTcp)
+		}
+		if got := value(record, ieTcpControlBits); got != test.flags {
+			t.Errorf("record %d: flags %#x, want %#x", i, got, test.flags)
+		}
+		if got := value(record, iePacketDeltaCount); got != test.packets {
+			t.Errorf("record %d: packets %d, want %d", i, got, test.packets)
+		}
+		if got := value(record, ieOctetDeltaCount); got != test.bytes {
+			t.Errorf("record %d: bytes %d, want %d", i, got, test.bytes)
+		}
+		if version == Ipfix {
+			want := uint64(start.Add(test.end).UnixNano() / int64(time.Millisecond))
+			if got := value(record, ieFlowEndMilliseconds); got != want {
+				t.Errorf("record %d: flow end %d, want %d", i, got, want)
+			}
+		} else {
+			// The origin is one second before the flow started.
+			want := uint64((time.Second + test.end) / time.Millisecond)
+			if got := value(record, ieLastSwitched); got != want {
+				t.Errorf("record %d: last switched %d, want %d", i, got, want)
+			}
+		}
+	}
+}
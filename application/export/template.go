@@ -0,0 +1,199 @@
+package export
+
+import (
+	"encoding/binary"
+	"sniffer/application/flow"
+	"time"
+)
+
+const (
+	NetflowV9 = 9
+	Ipfix     = 10
+
+	netflowV9HeaderSize = 20
+	ipfixHeaderSize     = 16
+	setHeaderSize       = 4
+
+	netflowV9TemplateSetId = 0
+	ipfixTemplateSetId     = 2
+	TemplateId             = 256
+)
+
+// Information elements shared by NetFlow v9 (RFC 3954) and IPFIX (RFC 7012).
+const (
+	ieOctetDeltaCount          = 1
+	iePacketDeltaCount         = 2
+	ieProtocolIdentifier       = 4
+	ieTcpControlBits           = 6
+	ieSourceTransportPort      = 7
+	ieSourceIpv4Address        = 8
+	ieDestinationTransportPort = 11
+	ieDestinationIpv4Address   = 12
+	ieLastSwitched             = 21
+	ieFirstSwitched            = 22
+	ieIcmpTypeCodeIpv4         = 32
+	ieFlowStartMilliseconds    = 152
+	ieFlowEndMilliseconds      = 153
+)
+
+type field struct {
+	Id     uint16
+	Length uint16
+}
+
+// Record is one unidirectional flow, NetFlow and IPFIX have no notion of a
+// conversation so every flow.Flow produces up to two of these.
+type Record struct {
+	Source      flow.Endpoint
+	Destination flow.Endpoint
+	Protocol    byte
+	TcpFlags    byte
+	IcmpType    byte
+	IcmpCode    byte
+	Packets     uint64
+	Bytes       uint64
+	Start       time.Time
+	End         time.Time
+}
+
+func RecordsFromFlow(f *flow.Flow) []Record {
+	var records []Record
+	if f.AtoB.Packets != 0 {
+		records = append(records, newRecord(f, f.Key.A, f.Key.B, f.AtoB))
+	}
+	if f.BtoA.Packets != 0 {
+		records = append(records, newRecord(f, f.Key.B, f.Key.A, f.BtoA))
+	}
+	return records
+}
+
+// newRecord takes flags and times from the counters of its own direction.
+func newRecord(f *flow.Flow, source flow.Endpoint, destination flow.Endpoint, c flow.Counters) Record {
+	return Record{
+		Source:      source,
+		Destination: destination,
+		Protocol:    f.Key.Protocol,
+		TcpFlags:    c.TcpFlags,
+		IcmpType:    f.IcmpType,
+		IcmpCode:    f.IcmpCode,
+		Packets:     c.Packets,
+		Bytes:       c.Bytes,
+		Start:       c.FirstSeen,
+		End:         c.LastSeen,
+	}
+}
+
+type template struct {
+	version int
+	fields  []field
+}
+
+func newTemplate(version int) template {
+	fields := []field{
+		{ieSourceIpv4Address, 4},
+		{ieDestinationIpv4Address, 4},
+		{ieSourceTransportPort, 2},
+		{ieDestinationTransportPort, 2},
+		{ieProtocolIdentifier, 1},
+		{ieTcpControlBits, 1},
+		{ieIcmpTypeCodeIpv4, 2},
+		{iePacketDeltaCount, 8},
+		{ieOctetDeltaCount, 8},
+	}
+	if version == Ipfix {
+		fields = append(fields, field{ieFlowStartMilliseconds, 8}, field{ieFlowEndMilliseconds, 8})
+	} else {
+		fields = append(fields, field{ieFirstSwitched, 4}, field{ieLastSwitched, 4})
+	}
+	return template{version: version, fields: fields}
+}
+
+func (t template) recordLength() int {
+	length := 0
+	for _, f := range t.fields {
+		length += int(f.Length)
+	}
+	return length
+}
+
+func (t template) setId() uint16 {
+	if t.version == Ipfix {
+		return ipfixTemplateSetId
+	}
+	return netflowV9TemplateSetId
+}
+
+// appendTemplateSet writes the template set announcing TemplateId.
+func (t template) appendTemplateSet(buffer []byte) []byte {
+	length := setHeaderSize + 4 + 4*len(t.fields)
+	buffer = appendUint16(buffer, t.setId())
+	buffer = appendUint16(buffer, uint16(length))
+	buffer = appendUint16(buffer, TemplateId)
+	buffer = appendUint16(buffer, uint16(len(t.fields)))
+	for _, f := range t.fields {
+		buffer = appendUint16(buffer, f.Id)
+		buffer = appendUint16(buffer, f.Length)
+	}
+	return buffer
+}
+
+// appendDataSet writes records as one data set padded to a 4 byte boundary.
+// bootTime is the NetFlow v9 sysUpTime origin.
+func (t template) appendDataSet(buffer []byte, records []Record, bootTime time.Time) []byte {
+	length := setHeaderSize + len(records)*t.recordLength()
+	padding := (4 - length%4) % 4
+	buffer = appendUint16(buffer, TemplateId)
+	buffer = appendUint16(buffer, uint16(length+padding))
+
+	for _, r := range records {
+		buffer = append(buffer, r.Source.Address[:]...)
+		buffer = append(buffer, r.Destination.Address[:]...)
+		buffer = appendUint16(buffer, r.Source.Port)
+		buffer = appendUint16(buffer, r.Destination.Port)
+		buffer = append(buffer, r.Protocol, r.TcpFlags)
+		if r.Protocol == flow.IpProtocolIcmp {
+			buffer = append(buffer, r.IcmpType, r.IcmpCode)
+		} else {
+			buffer = append(buffer, 0, 0)
+		}
+		buffer = appendUint64(buffer, r.Packets)
+		buffer = appendUint64(buffer, r.Bytes)
+		if t.version == Ipfix {
+			buffer = appendUint64(buffer, uint64(r.Start.UnixNano()/int64(time.Millisecond)))
+			buffer = appendUint64(buffer, uint64(r.End.UnixNano()/int64(time.Millisecond)))
+		} else {
+			buffer = appendUint32(buffer, uptimeMillis(bootTime, r.Start))
+			buffer = appendUint32(buffer, uptimeMillis(bootTime, r.End))
+		}
+	}
+
+	for i := 0; i < padding; i++ {
+		buffer = append(buffer, 0)
+	}
+	return buffer
+}
+
+func uptimeMillis(bootTime time.Time, t time.Time) uint32 {
+	if t.Before(bootTime) {
+		return 0
+	}
+	return uint32(t.Sub(bootTime) / time.Millisecond)
+}
+
+func appendUint16(buffer []byte, v uint16) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return append(buffer, b[:]...)
+}
+
+func appendUint32(buffer []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buffer, b[:]...)
+}
+
+func appendUint64(buffer []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buffer, b[:]...)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// extcapPrefix marks the interfaces we offer Wireshark, keeping them apart
+// from the ones Wireshark captures on itself.
+const extcapPrefix = "sniffer-"
+
+// isExtcap reports whether Wireshark started us as an extcap, see
+// https://www.wireshark.org/docs/wsdg_html_chunked/ChCaptureExtcap.html.
+func isExtcap(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--extcap-") || arg == "--capture" {
+			return true
+		}
+	}
+	return false
+}
+
+// runExtcap answers Wireshark's queries. For --capture it returns the
+// command line the capture runs with instead: the chosen interface,
+// streamed as pcapng to Wireshark's fifo. Install by linking this binary
+// into Wireshark's extcap folder.
+func runExtcap(args []string) ([]string, int, bool) {
+	flags := flag.NewFlagSet("extcap", flag.ExitOnError)
+	listInterfaces := flags.Bool("extcap-interfaces", false, "list the interfaces offered to Wireshark")
+	flags.String("extcap-version", "", "Wireshark version")
+	iface := flags.String("extcap-interface", "", "interface the other options apply to")
+	listDlts := flags.Bool("extcap-dlts", false, "list the link types of -extcap-interface")
+	listConfig := flags.Bool("extcap-config", false, "list the options of -extcap-interface")
+	doCapture := flags.Bool("capture", false, "capture on -extcap-interface")
+	fifo := flags.String("fifo", "", "capture: pipe Wireshark reads pcapng from")
+	filter := flags.String("extcap-capture-filter", "", "capture: BPF filter")
+	flags.String("extcap-control-in", "", "unused")
+	flags.String("extcap-control-out", "", "unused")
+	flags.Bool("debug", false, "unused")
+	flags.String("debug-file", "", "unused")
+	grep := flags.String("grep", "", "capture: keep only frames whose payload contains this pattern")
+	anonymize := flags.String("anonymize", "", "capture: anonymize frames with this passphrase")
+	comments := flags.Bool("comments", false, "capture: attach the decoded packet as a comment")
+	tcpAnalysis := flags.Bool("tcp-analysis", false, "capture: annotate TCP segments")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer --extcap-interfaces | --extcap-interface NAME --extcap-dlts | --extcap-config | --capture --fifo PATH")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	switch {
+	case *listInterfaces:
+		fmt.Println("extcap {version=1.0}{display=Packet sniffer}")
+		devices, err := pcap.FindAllDevs()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "extcap:", err)
+			return nil, 1, true
+		}
+		for _, device := range devices {
+			fmt.Printf("interface {value=%s%s}{display=Packet sniffer: %s}\n", extcapPrefix, device.Name, device.Name)
+		}
+		return nil, 0, true
+
+	case *listDlts:
+		fmt.Println("dlt {number=1}{name=EN10MB}{display=Ethernet}")
+		return nil, 0, true
+
+	case *listConfig:
+		fmt.Println("arg {number=0}{call=--grep}{display=Payload pattern}{type=string}{tooltip=Keep only frames whose payload contains this pattern, |hex| blocks allowed}")
+		fmt.Println("arg {number=1}{call=--anonymize}{display=Anonymization passphrase}{type=password}{tooltip=Anonymize addresses with Crypto-PAn before frames reach Wireshark}")
+		fmt.Println("arg {number=2}{call=--comments}{display=Decoded packet as comment}{type=boolflag}{default=true}{tooltip=Attach this sniffer's decoding to each frame}")
+		fmt.Println("arg {number=3}{call=--tcp-analysis}{display=TCP analysis}{type=boolflag}{default=false}{tooltip=Add retransmission, RTT and window notes to the comments}")
+		return nil, 0, true
+
+	case *doCapture:
+		if *fifo == "" || !strings.HasPrefix(*iface, extcapPrefix) {
+			flags.Usage()
+			return nil, 2, true
+		}
+		capture := []string{
+			"-interface", strings.TrimPrefix(*iface, extcapPrefix),
+			"-pcapng-out", *fifo,
+			"-pcapng-comments=" + strconv.FormatBool(*comments),
+		}
+		if *filter != "" {
+			capture = append(capture, "-filter", *filter)
+		}
+		if *grep != "" {
+			capture = append(capture, "-grep", *grep)
+		}
+		if *anonymize != "" {
+			capture = append(capture, "-anonymize", *anonymize)
+		}
+		if *tcpAnalysis {
+			capture = append(capture, "-tcp-analysis")
+		}
+		return capture, 0, false
+	}
+	flags.Usage()
+	return nil, 2, true
+}
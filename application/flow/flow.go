@@ -0,0 +1,182 @@
+package flow
+
+import (
+	"container/list"
+	"fmt"
+	"sniffer/application/packet"
+	"time"
+)
+
+type TcpState string
+
+const (
+	TcpStateNone        TcpState = ""
+	TcpStateSynSent     TcpState = "SYN_SENT"
+	TcpStateSynReceived TcpState = "SYN_RECEIVED"
+	TcpStateEstablished TcpState = "ESTABLISHED"
+	TcpStateFinWait     TcpState = "FIN_WAIT"
+	TcpStateClosed      TcpState = "CLOSED"
+	TcpStateReset       TcpState = "RESET"
+)
+
+// Counters cover the packets of one direction.
+type Counters struct {
+	Packets   uint64
+	Bytes     uint64
+	TcpFlags  byte
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+func (c *Counters) add(o Observation, timestamp time.Time) {
+	if c.Packets == 0 || timestamp.Before(c.FirstSeen) {
+		c.FirstSeen = timestamp
+	}
+	if c.Packets == 0 || timestamp.After(c.LastSeen) {
+		c.LastSeen = timestamp
+	}
+	c.Packets++
+	c.Bytes += uint64(o.Bytes)
+	c.TcpFlags |= o.TcpFlags
+}
+
+type Flow struct {
+	Key Key
+	// Initiator is the endpoint the first observed packet came from.
+	Initiator Endpoint
+	AtoB      Counters
+	BtoA      Counters
+	FirstSeen time.Time
+	LastSeen  time.Time
+	TcpFlags  byte
+	TcpState  TcpState
+	IcmpType  byte
+	IcmpCode  byte
+
+	finFromA bool
+	finFromB bool
+	// recent is the flow's place in the table's least recently used list.
+	recent *list.Element
+}
+
+func newFlow(key Key, o Observation, timestamp time.Time) *Flow {
+	return &Flow{
+		Key:       key,
+		Initiator: o.Source,
+		FirstSeen: timestamp,
+		LastSeen:  timestamp,
+	}
+}
+
+func (f *Flow) Packets() uint64 {
+	return f.AtoB.Packets + f.BtoA.Packets
+}
+
+func (f *Flow) Bytes() uint64 {
+	return f.AtoB.Bytes + f.BtoA.Bytes
+}
+
+func (f *Flow) Duration() time.Duration {
+	return f.LastSeen.Sub(f.FirstSeen)
+}
+
+// Finished reports whether the TCP conversation was torn down by FIN or RST.
+func (f *Flow) Finished() bool {
+	return f.TcpState == TcpStateClosed || f.TcpState == TcpStateReset
+}
+
+func (f *Flow) update(o Observation, aToB bool, timestamp time.Time) {
+	if aToB {
+		f.AtoB.add(o, timestamp)
+	} else {
+		f.BtoA.add(o, timestamp)
+	}
+
+	if timestamp.Before(f.FirstSeen) {
+		f.FirstSeen = timestamp
+	}
+	if timestamp.After(f.LastSeen) {
+		f.LastSeen = timestamp
+	}
+
+	switch o.Protocol {
+	case IpProtocolTcp:
+		f.TcpFlags |= o.TcpFlags
+		f.updateTcpState(o.TcpFlags, aToB)
+	case IpProtocolIcmp:
+		f.IcmpType = o.IcmpType
+		f.IcmpCode = o.IcmpCode
+	}
+}
+
+func (f *Flow) updateTcpState(flags byte, aToB bool) {
+	syn := flags&packet.TcpFlagSyn != 0
+	ack := flags&packet.TcpFlagAck != 0
+
+	if flags&packet.TcpFlagRst != 0 {
+		f.TcpState = TcpStateReset
+		return
+	}
+
+	if flags&packet.TcpFlagFin != 0 {
+		if aToB {
+			f.finFromA = true
+		} else {
+			f.finFromB = true
+		}
+		if f.finFromA && f.finFromB {
+			f.TcpState = TcpStateClosed
+		} else {
+			f.TcpState = TcpStateFinWait
+		}
+		return
+	}
+
+	switch {
+	case syn && !ack:
+		f.TcpState = TcpStateSynSent
+	case syn && ack:
+		f.TcpState = TcpStateSynReceived
+	case f.TcpState == TcpStateSynReceived || f.TcpState == TcpStateNone:
+		// Either the handshake completed or we joined a conversation midway.
+		f.TcpState = TcpStateEstablished
+	}
+}
+
+func TcpFlagsToString(flags byte) string {
+	names := []struct {
+		bit  byte
+		name string
+	}{
+		{packet.TcpFlagUrg, "U"},
+		{packet.TcpFlagAck, "A"},
+		{packet.TcpFlagPsh, "P"},
+		{packet.TcpFlagRst, "R"},
+		{packet.TcpFlagSyn, "S"},
+		{packet.TcpFlagFin, "F"},
+	}
+
+	result := ""
+	for _, v := range names {
+		if flags&v.bit != 0 {
+			result += v.name
+		} else {
+			result += "."
+		}
+	}
+	return result
+}
+
+func (f *Flow) ToString() string {
+	result := fmt.Sprintf("%s - packets %d/%d - bytes %d/%d - duration %s",
+		f.Key.ToString(), f.AtoB.Packets, f.BtoA.Packets, f.AtoB.Bytes, f.BtoA.Bytes, f.Duration().Round(time.Millisecond))
+
+	switch f.Key.Protocol {
+	case IpProtocolTcp:
+		result += fmt.Sprintf(" - flags %s - state %s", TcpFlagsToString(f.TcpFlags), f.TcpState)
+	case IpProtocolIcmp:
+		result += fmt.Sprintf(" - icmp type %d code %d", f.IcmpType, f.IcmpCode)
+	}
+
+	return result
+}
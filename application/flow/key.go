@@ -0,0 +1,122 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+)
+
+const (
+	IpProtocolIcmp = 1
+	IpProtocolTcp  = 6
+	IpProtocolUdp  = 17
+)
+
+type Endpoint struct {
+	Address [4]byte
+	Port    uint16
+}
+
+func (e Endpoint) ToString() string {
+	return fmt.Sprintf("%d.%d.%d.%d:%d", e.Address[0], e.Address[1], e.Address[2], e.Address[3], e.Port)
+}
+
+func (e Endpoint) less(other Endpoint) bool {
+	c := bytes.Compare(e.Address[:], other.Address[:])
+	if c != 0 {
+		return c < 0
+	}
+	return e.Port < other.Port
+}
+
+// Key identifies a conversation regardless of direction: the lower endpoint is
+// always stored in A so both halves of a conversation hash to the same key.
+type Key struct {
+	A        Endpoint
+	B        Endpoint
+	Protocol byte
+}
+
+func (k Key) ToString() string {
+	return fmt.Sprintf("%s <-> %s [%s]", k.A.ToString(), k.B.ToString(), ProtocolName(k.Protocol))
+}
+
+func ProtocolName(p byte) string {
+	switch p {
+	case IpProtocolIcmp:
+		return protocol.IcmpV4.Name
+	case IpProtocolTcp:
+		return protocol.Tcp.Name
+	case IpProtocolUdp:
+		return protocol.Udp.Name
+	}
+	return fmt.Sprintf("%d", p)
+}
+
+// Observation is everything the flow table needs from one decoded packet.
+type Observation struct {
+	Source      Endpoint
+	Destination Endpoint
+	Protocol    byte
+	Bytes       int
+	TcpFlags    byte
+	IcmpType    byte
+	IcmpCode    byte
+	Ipv4        packet.Ipv4Packet
+	Transport   packet.Parsable
+}
+
+// Key returns the direction-independent key and whether the packet travelled
+// from A to B.
+func (o Observation) Key() (Key, bool) {
+	if o.Destination.less(o.Source) {
+		return Key{A: o.Destination, B: o.Source, Protocol: o.Protocol}, false
+	}
+	return Key{A: o.Source, B: o.Destination, Protocol: o.Protocol}, true
+}
+
+// Observe walks a decoded packet chain down to its IPv4 layer and extracts the
+// 5-tuple. It returns false for frames that do not carry IPv4.
+func Observe(p packet.Parsable) (Observation, bool) {
+	ipv4, ok := FindIpv4(p)
+	if !ok {
+		return Observation{}, false
+	}
+
+	var o Observation
+	copy(o.Source.Address[:], ipv4.Header.SourceAddress.Value)
+	copy(o.Destination.Address[:], ipv4.Header.DestinationAddress.Value)
+	o.Protocol = ipv4.Header.PayloadProtocol.Value
+	o.Bytes = int(ipv4.Header.TotalLength)
+	o.Ipv4 = ipv4
+	o.Transport = ipv4.PacketParser
+
+	switch transport := ipv4.PacketParser.(type) {
+	case packet.TcpPacket:
+		o.Source.Port = transport.Header.SourcePort
+		o.Destination.Port = transport.Header.DestinationPort
+		o.TcpFlags = transport.Header.Flags()
+	case packet.UdpPacket:
+		o.Source.Port = transport.Header.SourcePort
+		o.Destination.Port = transport.Header.DestinationPort
+	case packet.IcmpV4Packet:
+		o.IcmpType = transport.Header.Type.Value
+		o.IcmpCode = transport.RawHeader[packet.IcmpV4CodeOffset]
+	}
+
+	return o, true
+}
+
+func FindIpv4(p packet.Parsable) (packet.Ipv4Packet, bool) {
+	switch layer := p.(type) {
+	case packet.Ipv4Packet:
+		return layer, true
+	case packet.EthernetPacket:
+		if layer.CanParseMore {
+			ipv4, ok := layer.PacketParser.(packet.Ipv4Packet)
+			return ipv4, ok
+		}
+	}
+	return packet.Ipv4Packet{}, false
+}
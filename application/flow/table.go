@@ -0,0 +1,224 @@
+package flow
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultIdleTimeout   = 30 * time.Second
+	DefaultActiveTimeout = 30 * time.Minute
+	DefaultMaxFlows      = 65536
+)
+
+type EvictReason string
+
+const (
+	EvictIdle     EvictReason = "idle timeout"
+	EvictActive   EvictReason = "active timeout"
+	EvictFinished EvictReason = "end of flow"
+	EvictCapacity EvictReason = "table full"
+	EvictFlush    EvictReason = "flush"
+)
+
+type Config struct {
+	IdleTimeout   time.Duration
+	ActiveTimeout time.Duration
+	MaxFlows      int
+	// OnEvict is called with the table lock held for every flow leaving the table.
+	OnEvict func(f *Flow, reason EvictReason)
+}
+
+type Table struct {
+	config Config
+	mutex  sync.Mutex
+	flows  map[Key]*Flow
+	// recent orders the keys of active flows from the most recently seen
+	// to the least, so the table evicts from its back when full.
+	recent *list.List
+
+	totalFlows   uint64
+	totalPackets uint64
+	totalBytes   uint64
+	evicted      map[EvictReason]uint64
+	// largest keeps the heaviest finished conversations for the final summary.
+	largest []Flow
+}
+
+func NewTable(config Config) *Table {
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = DefaultIdleTimeout
+	}
+	if config.ActiveTimeout == 0 {
+		config.ActiveTimeout = DefaultActiveTimeout
+	}
+	if config.MaxFlows == 0 {
+		config.MaxFlows = DefaultMaxFlows
+	}
+
+	return &Table{
+		config:  config,
+		flows:   make(map[Key]*Flow),
+		recent:  list.New(),
+		evicted: make(map[EvictReason]uint64),
+	}
+}
+
+// Add accounts one observed packet and returns the flow it belongs to. The
+// returned flow may be evicted by a concurrent Expire, so callers should only
+// read it on the goroutine that feeds the table.
+func (t *Table) Add(o Observation, timestamp time.Time) *Flow {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key, aToB := o.Key()
+	f, ok := t.flows[key]
+	if !ok {
+		if len(t.flows) >= t.config.MaxFlows {
+			t.evictOldest()
+		}
+		f = newFlow(key, o, timestamp)
+		f.recent = t.recent.PushFront(key)
+		t.flows[key] = f
+		t.totalFlows++
+	} else {
+		t.recent.MoveToFront(f.recent)
+	}
+
+	f.update(o, aToB, timestamp)
+	t.totalPackets++
+	t.totalBytes += uint64(o.Bytes)
+
+	return f
+}
+
+// Expire evicts flows that went idle, exceeded the active timeout or were
+// closed by TCP. now should come from packet timestamps when reading files.
+func (t *Table) Expire(now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for key, f := range t.flows {
+		switch {
+		case f.Finished():
+			t.evict(key, f, EvictFinished)
+		case now.Sub(f.LastSeen) > t.config.IdleTimeout:
+			t.evict(key, f, EvictIdle)
+		case now.Sub(f.FirstSeen) > t.config.ActiveTimeout:
+			t.evict(key, f, EvictActive)
+		}
+	}
+}
+
+func (t *Table) Flush() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for key, f := range t.flows {
+		t.evict(key, f, EvictFlush)
+	}
+}
+
+// evictOldest evicts the flow that has gone longest without a packet.
+func (t *Table) evictOldest() {
+	if oldest := t.recent.Back(); oldest != nil {
+		key := oldest.Value.(Key)
+		t.evict(key, t.flows[key], EvictCapacity)
+	}
+}
+
+func (t *Table) evict(key Key, f *Flow, reason EvictReason) {
+	delete(t.flows, key)
+	t.recent.Remove(f.recent)
+	t.evicted[reason]++
+	t.rememberLargest(*f)
+	if t.config.OnEvict != nil {
+		t.config.OnEvict(f, reason)
+	}
+}
+
+func (t *Table) rememberLargest(f Flow) {
+	const keep = 100
+	t.largest = append(t.largest, f)
+	if len(t.largest) > 2*keep {
+		sortByBytes(t.largest)
+		t.largest = t.largest[:keep]
+	}
+}
+
+func (t *Table) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.flows)
+}
+
+// Top returns copies of the n active conversations with the most bytes.
+func (t *Table) Top(n int) []Flow {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return top(t.activeFlows(), n)
+}
+
+// Snapshot returns copies of all active flows in no particular order.
+func (t *Table) Snapshot() []Flow {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.activeFlows()
+}
+
+func (t *Table) activeFlows() []Flow {
+	result := make([]Flow, 0, len(t.flows))
+	for _, f := range t.flows {
+		result = append(result, *f)
+	}
+	return result
+}
+
+func top(flows []Flow, n int) []Flow {
+	sortByBytes(flows)
+	if n > 0 && len(flows) > n {
+		flows = flows[:n]
+	}
+	return flows
+}
+
+func sortByBytes(flows []Flow) {
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Bytes() > flows[j].Bytes()
+	})
+}
+
+func (t *Table) TopToString(n int) string {
+	flows := t.Top(n)
+	result := fmt.Sprintf("Top %d of %d active conversations\n", len(flows), t.Len())
+	for i, f := range flows {
+		result += fmt.Sprintf("%3d. %s\n", i+1, f.ToString())
+	}
+	return result
+}
+
+// Summary reports totals and the heaviest conversations seen during the run,
+// both still active and already evicted.
+func (t *Table) Summary(n int) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	all := append(t.activeFlows(), t.largest...)
+	result := fmt.Sprintf("Flow summary - flows %d - packets %d - bytes %d - active %d\n",
+		t.totalFlows, t.totalPackets, t.totalBytes, len(t.flows))
+	for _, reason := range []EvictReason{EvictFinished, EvictIdle, EvictActive, EvictCapacity, EvictFlush} {
+		if t.evicted[reason] != 0 {
+			result += fmt.Sprintf("  evicted by %s: %d\n", reason, t.evicted[reason])
+		}
+	}
+	for i, f := range top(all, n) {
+		result += fmt.Sprintf("%3d. %s\n", i+1, f.ToString())
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"fmt"
+	"sniffer/application/capture"
+	"sniffer/application/pipeline"
+	"sync"
+	"time"
+)
+
+// Snapshot is every counter that tells whether a capture can be trusted,
+// from the kernel down to our own decoder.
+type Snapshot struct {
+	Time time.Time
+	// Received is what the kernel or libpcap saw, Captured what reached us.
+	Received         uint64
+	Captured         uint64
+	KernelDropped    uint64
+	InterfaceDropped uint64
+	QueueDropped     uint64
+	DecodeFailed     uint64
+	Truncated        uint64
+}
+
+// Dropped is the number of frames lost anywhere before decoding.
+func (s Snapshot) Dropped() uint64 {
+	return s.KernelDropped + s.InterfaceDropped + s.QueueDropped
+}
+
+// DropRate is the fraction of frames seen on the interface that were lost.
+func (s Snapshot) DropRate() float64 {
+	// Kernel counters include the frames the kernel dropped; fall back to
+	// our own count when the backend has none.
+	seen := s.Received
+	if seen < s.Captured+s.KernelDropped {
+		seen = s.Captured + s.KernelDropped
+	}
+	seen += s.InterfaceDropped
+	if seen == 0 {
+		return 0
+	}
+	return float64(s.Dropped()) / float64(seen)
+}
+
+// Sub returns the counters accumulated since previous. A counter that went
+// backwards, as when a backend restarts its count, shows as zero.
+func (s Snapshot) Sub(previous Snapshot) Snapshot {
+	return Snapshot{
+		Time:             s.Time,
+		Received:         delta(s.Received, previous.Received),
+		Captured:         delta(s.Captured, previous.Captured),
+		KernelDropped:    delta(s.KernelDropped, previous.KernelDropped),
+		InterfaceDropped: delta(s.InterfaceDropped, previous.InterfaceDropped),
+		QueueDropped:     delta(s.QueueDropped, previous.QueueDropped),
+		DecodeFailed:     delta(s.DecodeFailed, previous.DecodeFailed),
+		Truncated:        delta(s.Truncated, previous.Truncated),
+	}
+}
+
+func delta(current uint64, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+func (s Snapshot) ToString() string {
+	return fmt.Sprintf("health: %d received, %d captured, %d kernel dropped, %d interface dropped, %d queue dropped (%.2f%% lost), %d decode failed, %d truncated\n",
+		s.Received, s.Captured, s.KernelDropped, s.InterfaceDropped, s.QueueDropped, s.DropRate()*100, s.DecodeFailed, s.Truncated)
+}
+
+// Monitor collects a Snapshot from the capture source and the decode
+// pipeline. It is safe to use from any goroutine, so other outputs can
+// poll it.
+type Monitor struct {
+	source capture.CaptureSource
+	pipe   *pipeline.Pipeline
+
+	mutex sync.Mutex
+	// lastSource is the last source counters read successfully.
+	lastSource capture.Stats
+}
+
+func NewMonitor(source capture.CaptureSource, pipe *pipeline.Pipeline) *Monitor {
+	return &Monitor{source: source, pipe: pipe}
+}
+
+// Snapshot reads the counters now. When the backend cannot report its
+// counters, for example after it was closed, the last ones it reported
+// are used, or zero if it never did.
+func (m *Monitor) Snapshot() Snapshot {
+	pipeStats := m.pipe.Stats()
+	snapshot := Snapshot{
+		Time:         time.Now(),
+		Captured:     pipeStats.Received,
+		QueueDropped: pipeStats.QueueDropped,
+		DecodeFailed: pipeStats.DecodeFailed,
+		Truncated:    pipeStats.Truncated,
+	}
+	m.mutex.Lock()
+	if sourceStats, err := m.source.Stats(); err == nil {
+		m.lastSource = sourceStats
+	}
+	sourceStats := m.lastSource
+	m.mutex.Unlock()
+	snapshot.Received = sourceStats.Received
+	snapshot.KernelDropped = sourceStats.Dropped
+	snapshot.InterfaceDropped = sourceStats.InterfaceDropped
+	return snapshot
+}
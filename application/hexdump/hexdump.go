@@ -0,0 +1,183 @@
+package hexdump
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sniffer/application/packet"
+	"strings"
+)
+
+const bytesPerRow = 16
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Region is a run of frame bytes that belongs to one header, option block
+// or payload.
+type Region struct {
+	Name  string
+	Label string
+	Start int
+	End   int
+}
+
+var regionLabels = map[string]string{
+	"eth":         "Ethernet header",
+	"arp":         "ARP packet",
+	"ip":          "IPv4 header",
+	"ip.options":  "IPv4 options",
+	"tcp":         "TCP header",
+	"tcp.options": "TCP options",
+	"udp":         "UDP header",
+	"icmp":        "ICMP header",
+	"data":        "Payload",
+	"trailer":     "Trailer",
+}
+
+var regionColors = map[string]string{
+	"eth":         "34",
+	"arp":         "35",
+	"ip":          "32",
+	"ip.options":  "33",
+	"tcp":         "36",
+	"tcp.options": "33",
+	"udp":         "35",
+	"icmp":        "31",
+	"data":        "37",
+	"trailer":     "90",
+}
+
+// Regions splits a frame along the layer boundaries the decoders found.
+// Option blocks get their own region and bytes past the last layer are
+// reported as a trailer.
+func Regions(frame []byte, decoded packet.Parsable) []Region {
+	var regions []Region
+	add := func(name string, start int, end int) {
+		if end > len(frame) {
+			end = len(frame)
+		}
+		if start >= end {
+			return
+		}
+		label, ok := regionLabels[name]
+		if !ok {
+			label = name
+		}
+		regions = append(regions, Region{Name: name, Label: label, Start: start, End: end})
+	}
+
+	covered := 0
+	if decoded != nil {
+		for _, layer := range packet.Dissect(decoded) {
+			headerEnd := layer.End()
+			var options *packet.Field
+			for i := range layer.Children {
+				if strings.HasSuffix(layer.Children[i].Name, ".options") {
+					options = &layer.Children[i]
+					headerEnd = options.Offset
+				}
+			}
+			add(layer.Name, layer.Offset, headerEnd)
+			if options != nil {
+				add(options.Name, options.Offset, options.End())
+			}
+			if layer.End() > covered {
+				covered = layer.End()
+			}
+		}
+	}
+	add("trailer", covered, len(frame))
+	return regions
+}
+
+// Dump renders frame in the canonical offset, hex and ASCII layout. A '|'
+// in front of a byte marks where a new region starts and the regions that
+// start on a row are named at its end. With color each region is painted
+// in its own ANSI colour.
+func Dump(frame []byte, decoded packet.Parsable, color bool) string {
+	regions := Regions(frame, decoded)
+	var result strings.Builder
+
+	for _, r := range regions {
+		result.WriteString(paint(fmt.Sprintf("  %-16s %5d-%-5d (%d bytes)", r.Label, r.Start, r.End-1, r.End-r.Start), r.Name, color))
+		result.WriteString("\n")
+	}
+
+	owner := make([]int, len(frame))
+	starts := make(map[int]bool)
+	for i, r := range regions {
+		for j := r.Start; j < r.End; j++ {
+			owner[j] = i
+		}
+		starts[r.Start] = true
+	}
+
+	for offset := 0; offset < len(frame); offset += bytesPerRow {
+		var hex, ascii strings.Builder
+		var marks []string
+		for col := 0; col < bytesPerRow; col++ {
+			if col == bytesPerRow/2 {
+				hex.WriteString(" ")
+			}
+			i := offset + col
+			if i >= len(frame) {
+				hex.WriteString("   ")
+				continue
+			}
+			region := regions[owner[i]]
+			if starts[i] && i > 0 {
+				hex.WriteString("|")
+				marks = append(marks, fmt.Sprintf("%s@%d", region.Name, i))
+			} else {
+				hex.WriteString(" ")
+			}
+			hex.WriteString(paint(fmt.Sprintf("%02x", frame[i]), region.Name, color))
+
+			c := frame[i]
+			if c < 0x20 || c >= 0x7f {
+				c = '.'
+			}
+			ascii.WriteString(paint(string(c), region.Name, color))
+		}
+		line := fmt.Sprintf("%08x %s  |%s|", offset, hex.String(), ascii.String())
+		if len(marks) > 0 {
+			line += "  " + strings.Join(marks, " ")
+		}
+		result.WriteString(line)
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+func paint(text string, region string, color bool) string {
+	code, ok := regionColors[region]
+	if !color || !ok {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// UseColor resolves a -color setting. auto colours only when stdout is a
+// terminal and NO_COLOR is not set.
+func UseColor(mode string) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto:
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, errors.New("color must be auto, always or never")
+}
@@ -1,27 +1,771 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/google/gopacket"
 	"github.com/google/gopacket/pcap"
-	"sniffer/application/packet"
-	"sniffer/application/protocol"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"sniffer/application/analysis"
+	"sniffer/application/anonymize"
+	"sniffer/application/capture"
+	"sniffer/application/detector"
+	"sniffer/application/export"
+	"sniffer/application/flow"
+	"sniffer/application/health"
+	"sniffer/application/hexdump"
+	"sniffer/application/metrics"
+	"sniffer/application/pcapng"
+	"sniffer/application/pipeline"
+	"sniffer/application/rpcap"
+	"sniffer/application/rule"
+	"sniffer/application/search"
+	"sniffer/application/stats"
+	"sniffer/application/tui"
+	"sniffer/application/web"
+	"strings"
+	"syscall"
+	"time"
 )
 
+var (
+	deviceIndex   = flag.Int("device", 1, "index of the capture device")
+	backend       = flag.String("backend", "pcap", "capture backend: pcap or afpacket")
+	fanout        = flag.Int("fanout", 1, "afpacket: number of sockets in the fanout group")
+	fanoutType    = flag.String("fanout-type", capture.FanoutHash, "afpacket: how frames are spread: hash, lb, cpu, rollover or random")
+	blockSize     = flag.Int("block-size", 1<<20, "afpacket: ring block size in bytes")
+	numBlocks     = flag.Int("blocks", 64, "afpacket: ring blocks per socket")
+	showFlows     = flag.Bool("flows", false, "track conversations and print the busiest ones instead of every packet")
+	topFlows      = flag.Int("top", 10, "length of the top conversation and top talker lists")
+	flowInterval  = flag.Duration("flow-interval", 5*time.Second, "how often the conversation view is refreshed")
+	idleTimeout   = flag.Duration("idle-timeout", flow.DefaultIdleTimeout, "evict conversations idle for this long")
+	activeTimeout = flag.Duration("active-timeout", flow.DefaultActiveTimeout, "evict conversations older than this")
+	exportTo      = flag.String("export-collector", "", "send finished flows to this host:port over UDP")
+	exportFile    = flag.String("export-file", "", "write finished flows to this file")
+	exportVersion = flag.Int("export-version", export.Ipfix, "flow export format, 9 for NetFlow v9 or 10 for IPFIX")
+	showStats     = flag.Bool("stats", false, "print traffic statistics instead of every packet")
+	statsInterval = flag.Duration("stats-interval", 10*time.Second, "how often traffic statistics are printed")
+	watchArp      = flag.Bool("arp-monitor", false, "alert on ARP spoofing and IP address conflicts")
+	detectScans   = flag.Bool("scan-detect", false, "alert on port scans, SYN floods and ICMP sweeps")
+	ruleFile      = flag.String("rules", "", "match packets against this Suricata rule file")
+	eveFile       = flag.String("eve", "", "write rule alerts as EVE-JSON to this file instead of stdout, which then carries no packet lines")
+	grepFile      = flag.String("grep-file", "", "print only packets whose payload matches a pattern from this file")
+	grepNocase    = flag.Bool("grep-nocase", false, "match grep patterns case-insensitively")
+	analyzeTcp    = flag.Bool("tcp-analysis", false, "annotate TCP segments with retransmission and window events and report RTT per connection")
+	analyzeNtp    = flag.Bool("ntp-analysis", false, "pair NTP requests with replies and report clock offset and delay per server")
+	ntpMaxOffset  = flag.Duration("ntp-max-offset", 100*time.Millisecond, "with -ntp-analysis, alert on exchanges whose offset exceeds this, 0 to never alert")
+	interactive   = flag.Bool("tui", false, "browse packets in an interactive terminal UI")
+	showHexdump   = flag.Bool("hexdump", false, "print each frame as an offset/hex/ASCII dump with layer boundaries marked")
+	summary       = flag.Bool("summary", false, "print a one line summary of each packet instead of every decoded field")
+	colorMode     = flag.String("color", hexdump.ColorAuto, "colour hexdump layers: auto, always or never")
+	workers       = flag.Int("workers", runtime.NumCPU(), "number of goroutines decoding packets")
+	queueSize     = flag.Int("queue-size", pipeline.DefaultQueueSize, "frames buffered per decode worker")
+	healthEvery   = flag.Duration("health-interval", 30*time.Second, "how often capture drop and health counters are printed, 0 to only print them at exit")
+	metricsListen = flag.String("metrics-listen", "", "serve Prometheus metrics on this address, e.g. :9100")
+	queueDrop     = flag.Bool("queue-drop", false, "drop frames when a worker queue is full instead of slowing capture down")
+	webListen     = flag.String("web-listen", "", "serve the web UI on this address, e.g. localhost:8080")
+	remoteFilter  = flag.String("remote-filter", "", "BPF filter run by the rpcap server for -remote captures")
+	captureFilter = flag.String("filter", "", "BPF filter in tcpdump syntax for interfaces captured with the pcap backend")
+	pcapngOut     = flag.String("pcapng-out", "", "stream frames as pcapng to this file or named pipe, - for stdout, instead of printing them")
+	pcapngComment = flag.Bool("pcapng-comments", true, "attach the decoded packet to each -pcapng-out frame as a comment")
+	anonymizeKey  = flag.String("anonymize", "", "anonymize frames with this passphrase as they are captured, as \"sniffer anonymize\" does")
+)
+
+// patternList collects repeated string flags such as -grep.
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+var grepPatterns patternList
+var interfaceNames patternList
+var readFiles patternList
+var remoteUrls patternList
+
 func main() {
-	devices, _ := pcap.FindAllDevs()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "anonymize":
+			os.Exit(runAnonymize(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "remote":
+			os.Exit(runRemote(os.Args[2:]))
+		case "rpcapd":
+			os.Exit(runRpcapd(os.Args[2:]))
+		}
+	}
+
+	flag.Var(&grepPatterns, "grep", "print only packets whose payload contains this pattern, |hex| blocks allowed, may repeat")
+	flag.Var(&interfaceNames, "interface", "capture from this interface by name instead of -device, may repeat")
+	flag.Var(&readFiles, "read", "read frames from this pcap or pcapng file instead of capturing, may repeat")
+	flag.Var(&remoteUrls, "remote", "capture from rpcap://[user:password@]host[:port]/interface, may repeat; without an interface, list the server's interfaces")
+	arguments := os.Args[1:]
+	if isExtcap(arguments) {
+		var done bool
+		var status int
+		if arguments, status, done = runExtcap(arguments); done {
+			os.Exit(status)
+		}
+	}
+	flag.CommandLine.Parse(arguments)
+
+	// Text goes to stderr while stdout carries the capture.
+	pcapngOutput := os.Stdout
+	if *pcapngOut == "-" {
+		os.Stdout = os.Stderr
+	} else if *pcapngOut != "" {
+		var err error
+		pcapngOutput, err = os.OpenFile(*pcapngOut, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			fmt.Println("pcapng:", err)
+			os.Exit(1)
+		}
+		defer pcapngOutput.Close()
+	}
+
+	var sanitizer *anonymize.Sanitizer
+	if *anonymizeKey != "" {
+		var err error
+		sanitizer, err = anonymize.NewSanitizer(anonymize.Config{Key: anonymize.KeyFromPassphrase(*anonymizeKey)})
+		if err != nil {
+			fmt.Println("anonymize:", err)
+			os.Exit(1)
+		}
+	}
+
+	for _, remote := range remoteUrls {
+		if config, err := rpcap.ParseUrl(remote); err == nil && config.Device == "" {
+			if err := listRemote(config); err != nil {
+				fmt.Println("capture:", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
+	source, err := openSource()
+	if err != nil {
+		fmt.Println("capture:", err)
+		os.Exit(1)
+	}
+
+	// ui is started once the setup that may exit has run.
+	var ui *tui.UI
+
+	// report prints alerts, or shows them in the status line while the
+	// terminal UI owns the screen.
+	report := func(text string) {
+		if ui != nil {
+			ui.Notify(text)
+			return
+		}
+		fmt.Println(text)
+	}
+
+	var exporter *export.Exporter
+	if *exportTo != "" || *exportFile != "" {
+		var err error
+		exporter, err = export.NewExporter(export.Config{
+			Version:   *exportVersion,
+			Collector: *exportTo,
+			File:      *exportFile,
+		})
+		if err != nil {
+			fmt.Println("flow export:", err)
+			os.Exit(1)
+		}
+	}
+
+	var flowTable *flow.Table
+	if *showFlows || exporter != nil || *metricsListen != "" || *webListen != "" {
+		flowConfig := flow.Config{
+			IdleTimeout:   *idleTimeout,
+			ActiveTimeout: *activeTimeout,
+		}
+		if exporter != nil {
+			flowConfig.OnEvict = func(f *flow.Flow, reason flow.EvictReason) {
+				if err := exporter.Export(f); err != nil {
+					report("flow export: " + err.Error())
+				}
+			}
+		}
+		flowTable = flow.NewTable(flowConfig)
+	}
+
+	var statsCollector *stats.Collector
+	if *showStats || *webListen != "" {
+		statsCollector = stats.NewCollector()
+	}
+	var grepScanner *search.StreamScanner
+	if len(grepPatterns) > 0 || *grepFile != "" {
+		var patterns []search.Pattern
+		for _, text := range grepPatterns {
+			pattern, err := search.ParsePattern(text, *grepNocase)
+			if err != nil {
+				fmt.Println("grep:", err)
+				os.Exit(1)
+			}
+			patterns = append(patterns, pattern)
+		}
+		if *grepFile != "" {
+			filePatterns, err := search.LoadPatterns(*grepFile, *grepNocase)
+			if err != nil {
+				fmt.Println("grep:", err)
+				os.Exit(1)
+			}
+			patterns = append(patterns, filePatterns...)
+		}
+		grepScanner = search.NewStreamScanner(search.NewMatcher(patterns))
+	}
+
+	var uiDone <-chan struct{}
+	if *interactive {
+		ui = tui.NewUI(tui.DefaultCapacity)
+		uiDone = ui.Done()
+		go ui.Run()
+	}
+
+	useColor, err := hexdump.UseColor(*colorMode)
+	if err != nil {
+		fmt.Println("color:", err)
+		os.Exit(1)
+	}
+
+	// Alerts without -eve go to stdout as EVE-JSON, one object per line,
+	// which packet lines would break up.
+	eveToStdout := *ruleFile != "" && *eveFile == ""
+	printPackets := !*showFlows && !*showStats && grepScanner == nil && ui == nil && *pcapngOut == "" && !eveToStdout
+
+	var pcapngWriter *pcapng.Writer
+	if *pcapngOut != "" {
+		pcapngWriter, err = pcapng.NewWriter(pcapngOutput, "sniffer")
+		if err != nil {
+			fmt.Println("pcapng:", err)
+			os.Exit(1)
+		}
+	}
+	commentPackets := pcapngWriter != nil && *pcapngComment
+
+	var tcpAnalyzer *analysis.TcpAnalyzer
+	if *analyzeTcp {
+		tcpAnalyzer = analysis.NewTcpAnalyzer()
+	}
+
+	var ntpAnalyzer *analysis.NtpAnalyzer
+	if *analyzeNtp {
+		ntpAnalyzer = analysis.NewNtpAnalyzer()
+	}
+
+	var arpMonitor *detector.ArpMonitor
+	if *watchArp {
+		arpMonitor = detector.NewArpMonitor(detector.DefaultArpConfig())
+	}
+
+	var scanDetector *detector.ScanDetector
+	if *detectScans {
+		scanDetector = detector.NewScanDetector(detector.DefaultScanConfig())
+	}
+
+	var ruleEngine *rule.Engine
+	var eveWriter *rule.EveWriter
+	if *ruleFile != "" {
+		rules, err := rule.LoadFile(*ruleFile, rule.DefaultVariables())
+		if err != nil {
+			fmt.Println("rules:", err)
+			os.Exit(1)
+		}
+		ruleEngine = rule.NewEngine(rules)
+
+		eveOutput := os.Stdout
+		if *eveFile != "" {
+			eveOutput, err = os.OpenFile(*eveFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				fmt.Println("eve:", err)
+				os.Exit(1)
+			}
+			defer eveOutput.Close()
+		}
+		eveWriter = rule.NewEveWriter(eveOutput)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(*flowInterval)
+	defer ticker.Stop()
+	statsTicker := time.NewTicker(*statsInterval)
+	defer statsTicker.Stop()
+
+	// With -summary, printed and written frames make do with the one line
+	// summary of the worker's reused layers; everything else needs the
+	// linked tree.
+	summarize := *summary && !*showHexdump
+	needTree := !summarize || ui != nil || *webListen != "" || *metricsListen != "" ||
+		flowTable != nil || statsCollector != nil || grepScanner != nil || tcpAnalyzer != nil ||
+		ntpAnalyzer != nil || arpMonitor != nil || scanDetector != nil || ruleEngine != nil
+
+	render := func(job *pipeline.Job) {
+		if printPackets && *showHexdump {
+			job.Text = hexdump.Dump(job.Data, job.Decoded, useColor)
+		} else if printPackets || commentPackets {
+			job.Text = job.Decoded.ToString() + "\n"
+		}
+	}
+
+	// Decoding and rendering run on the pipeline workers; everything that
+	// keeps state across packets stays on this goroutine in capture order.
+	pipe := pipeline.New(pipeline.Config{
+		Workers:      *workers,
+		QueueSize:    *queueSize,
+		DropWhenFull: *queueDrop,
+		Decode: func(job *pipeline.Job, d *pipeline.Decoder) {
+			if !d.Decode(job.Data, job.LinkType) {
+				// Malformed frames are decoded and printed in full, which
+				// says what is wrong with them.
+				job.DecodeLink()
+				if job.Decoded != nil {
+					render(job)
+				}
+				return
+			}
+			if needTree {
+				job.Decoded = d.Parsable()
+			}
+			if !summarize {
+				render(job)
+			} else if printPackets || commentPackets {
+				job.Text = d.Summary() + "\n"
+			}
+		},
+	})
+	go func() {
+		defer pipe.Close()
+		for {
+			frame, err := source.Next()
+			if err != nil {
+				return
+			}
+			if sanitizer != nil {
+				// Truncated payloads keep their original length, as if
+				// captured with a smaller snap length.
+				frame.Data = sanitizer.SanitizeLink(frame.Data, frame.LinkType)
+				if frame.Data == nil {
+					continue
+				}
+				frame.CaptureLength = len(frame.Data)
+			}
+			pipe.Submit(frame)
+		}
+	}()
+
+	monitor := health.NewMonitor(source, pipe)
+	lastHealth := monitor.Snapshot()
+	var healthTick <-chan time.Time
+	if *healthEvery > 0 {
+		healthTicker := time.NewTicker(*healthEvery)
+		defer healthTicker.Stop()
+		healthTick = healthTicker.C
+	}
+
+	var collector *metrics.Collector
+	if *metricsListen != "" {
+		listener, err := net.Listen("tcp", *metricsListen)
+		if err != nil {
+			fmt.Println("metrics:", err)
+			os.Exit(1)
+		}
+		defer listener.Close()
+		config := metrics.DefaultConfig()
+		config.Monitor = monitor
+		collector = metrics.NewCollector(config)
+		go func() {
+			if err := metrics.Serve(listener, collector); err != nil {
+				report("metrics: " + err.Error())
+			}
+		}()
+	}
+
+	var webServer *web.Server
+	if *webListen != "" {
+		listener, err := net.Listen("tcp", *webListen)
+		if err != nil {
+			fmt.Println("web:", err)
+			os.Exit(1)
+		}
+		defer listener.Close()
+		config := web.DefaultConfig()
+		config.Monitor = monitor
+		config.Flows = flowTable
+		config.Stats = statsCollector
+		webServer = web.NewServer(config)
+		go func() {
+			if err := web.Serve(listener, webServer); err != nil {
+				report("web: " + err.Error())
+			}
+		}()
+	}
+
+	// expire times out state kept across packets. Files are read faster
+	// than they were captured, so their state expires by packet time.
+	expire := func(now time.Time) {
+		if tcpAnalyzer != nil {
+			tcpAnalyzer.Expire(now)
+		}
+		if scanDetector != nil {
+			scanDetector.Expire(now)
+		}
+		if ntpAnalyzer != nil {
+			ntpAnalyzer.Expire(now)
+		}
+		if grepScanner != nil {
+			grepScanner.Expire(now)
+		}
+		if flowTable != nil {
+			flowTable.Expire(now)
+			if collector != nil {
+				collector.SetActiveFlows(flowTable.Len())
+			}
+			if *showFlows && ui == nil {
+				fmt.Print(flowTable.TopToString(*topFlows))
+			}
+		}
+	}
+	fromFiles := len(interfaceNames) == 0 && len(remoteUrls) == 0 && len(readFiles) > 0
+	var lastExpire time.Time
+	// packetTime is the timestamp of the last packet decoded.
+	var packetTime time.Time
+
+	writeFrame := func(job *pipeline.Job, comment string) error {
+		return pcapngWriter.WritePacket(pcapng.Packet{
+			Interface: job.Interface,
+			LinkType:  job.LinkType,
+			Timestamp: job.Timestamp,
+			Data:      job.Data,
+			Length:    job.Length,
+			Comment:   comment,
+		})
+	}
+
+	tagInterface := len(interfaceNames)+len(remoteUrls)+len(readFiles) > 1
+	packets := pipe.Output()
+	packetNumber := 0
+loop:
+	for {
+		select {
+		case job, ok := <-packets:
+			if !ok {
+				break loop
+			}
+			if job.Failed || job.Unsupported {
+				// Frames that did not decode still belong in the capture,
+				// unless only frames matching -grep are kept.
+				if pcapngWriter != nil && grepScanner == nil {
+					comment := ""
+					if job.Failed {
+						comment = "decode failed"
+					}
+					if err := writeFrame(job, comment); err != nil {
+						report("pcapng: " + err.Error())
+						break loop
+					}
+				}
+				continue
+			}
+			if exporter != nil && packetTime.IsZero() {
+				exporter.SetOrigin(job.Timestamp)
+			}
+			packetTime = job.Timestamp
+			if fromFiles {
+				if lastExpire.IsZero() {
+					lastExpire = job.Timestamp
+				} else if job.Timestamp.Sub(lastExpire) >= *flowInterval {
+					expire(job.Timestamp)
+					lastExpire = job.Timestamp
+				}
+			}
+			if tcpAnalyzer != nil {
+				// Annotations depend on the segments before this one, so
+				// they are added here and the text is rendered again.
+				if annotated, notes := tcpAnalyzer.Annotate(job.Decoded, job.Timestamp); len(notes) > 0 {
+					job.Decoded = annotated
+					if summarize && job.Text != "" {
+						job.Text = strings.TrimSuffix(job.Text, "\n") + " [" + strings.Join(notes, "] [") + "]\n"
+					} else {
+						render(job)
+					}
+				}
+			}
+			ethernetPacket := job.Decoded
+			packetNumber++
+			if printPackets && tagInterface {
+				fmt.Printf("[%s] ", job.Interface)
+			}
+			if printPackets && *showHexdump {
+				fmt.Printf("Frame %d: %d bytes\n", packetNumber, len(job.Data))
+			}
+			if printPackets {
+				fmt.Print(job.Text)
+			}
+			matched := true
+			if grepScanner != nil {
+				hits := grepScanner.Grep(ethernetPacket, job.Timestamp)
+				matched = len(hits) > 0
+				if matched {
+					if ui == nil {
+						fmt.Println(ethernetPacket.ToString())
+					}
+					report(fmt.Sprintf("packet %d matched: %s", packetNumber, grepScanner.HitsToString(hits)))
+				}
+			}
+			if pcapngWriter != nil && matched {
+				comment := ""
+				if commentPackets {
+					comment = strings.TrimSuffix(job.Text, "\n")
+				}
+				if err := writeFrame(job, comment); err != nil {
+					report("pcapng: " + err.Error())
+					break loop
+				}
+			}
+			if ui != nil || webServer != nil {
+				entry := tui.NewEntry(packetNumber, job.Timestamp, job.Data, ethernetPacket)
+				entry.Interface, entry.LinkType = job.Interface, job.LinkType
+				if ui != nil {
+					ui.Add(entry)
+				}
+				if webServer != nil {
+					webServer.Add(entry)
+				}
+			}
+			if arpMonitor != nil {
+				for _, alert := range arpMonitor.Observe(ethernetPacket, job.Timestamp) {
+					report(alert.ToString())
+				}
+			}
+			if scanDetector != nil {
+				for _, alert := range scanDetector.Observe(ethernetPacket, job.Timestamp) {
+					report(alert.ToString())
+				}
+			}
+			if ntpAnalyzer != nil {
+				exchange, ok := ntpAnalyzer.Observe(ethernetPacket, job.Timestamp)
+				if ok && *ntpMaxOffset > 0 && (exchange.Offset > *ntpMaxOffset || exchange.Offset < -*ntpMaxOffset) {
+					report("ntp offset: " + exchange.ToString())
+				}
+			}
+			if ruleEngine != nil {
+				for _, match := range ruleEngine.Match(ethernetPacket, job.Timestamp) {
+					if err := eveWriter.Write(match); err != nil {
+						report("eve: " + err.Error())
+					}
+				}
+			}
+			if statsCollector != nil {
+				statsCollector.Add(ethernetPacket, job.Length)
+			}
+			if collector != nil {
+				collector.Observe(ethernetPacket, job.Length, job.Timestamp)
+			}
+			if flowTable == nil {
+				continue
+			}
+			if observation, ok := flow.Observe(ethernetPacket); ok {
+				flowTable.Add(observation, job.Timestamp)
+			}
+			if collector != nil {
+				collector.SetActiveFlows(flowTable.Len())
+			}
+
+		case now := <-ticker.C:
+			if !fromFiles {
+				expire(now)
+			}
+
+		case now := <-statsTicker.C:
+			if *showStats && ui == nil {
+				packetRate, bitRate := statsCollector.Rate(now)
+				fmt.Printf("Rate over last %s: %.1f packets/s - %.1f bits/s\n", *statsInterval, packetRate, bitRate)
+				fmt.Print(statsCollector.ToString(*topFlows))
+			}
+
+		case <-healthTick:
+			current := monitor.Snapshot()
+			report(fmt.Sprintf("last %s: %s", *healthEvery, strings.TrimSuffix(current.Sub(lastHealth).ToString(), "\n")))
+			lastHealth = current
+
+		case <-interrupt:
+			break loop
+
+		case <-uiDone:
+			break loop
+		}
+	}
+
+	finalHealth := monitor.Snapshot()
+	source.Close()
+
+	if ui != nil {
+		ui.Stop()
+		<-ui.Done()
+		if err := ui.Err(); err != nil {
+			fmt.Println("tui:", err)
+		}
+		// The terminal is restored, so report prints the flushed flows'
+		// export errors again.
+		ui = nil
+	}
+
+	if flowTable != nil {
+		flowTable.Flush()
+	}
+	if *showFlows {
+		fmt.Print(flowTable.Summary(*topFlows))
+	}
+
+	if *showStats {
+		fmt.Print(statsCollector.ToString(*topFlows))
+	}
+
+	if tcpAnalyzer != nil {
+		fmt.Print(tcpAnalyzer.Report())
+	}
+
+	if ntpAnalyzer != nil {
+		fmt.Print(ntpAnalyzer.Report())
+	}
+
+	if arpMonitor != nil {
+		fmt.Print(arpMonitor.ToString())
+	}
+
+	fmt.Print(pipe.Stats().ToString())
+	fmt.Print(finalHealth.ToString())
+
+	if exporter != nil {
+		if err := exporter.Close(); err != nil {
+			fmt.Println("flow export:", err)
+		}
+		fmt.Println(exporter.ToString())
+	}
+}
 
+// openSource opens the capture files or interfaces chosen on the command
+// line, merging them in timestamp order when there is more than one.
+func openSource() (capture.CaptureSource, error) {
+	var sources []capture.CaptureSource
+	closeAll := func() {
+		for _, source := range sources {
+			source.Close()
+		}
+	}
+	for _, path := range readFiles {
+		source, err := capture.OpenFile(path)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	for _, remote := range remoteUrls {
+		config, err := rpcap.ParseUrl(remote)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		config.Filter = *remoteFilter
+		source, err := rpcap.NewSource(config)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("%s: %v", remote, err)
+		}
+		sources = append(sources, source)
+	}
+	names := interfaceNames
+	if len(names) == 0 && len(readFiles) == 0 && len(remoteUrls) == 0 {
+		name, err := deviceName(*deviceIndex)
+		if err != nil {
+			return nil, err
+		}
+		names = patternList{name}
+	}
+	for _, name := range names {
+		source, err := openInterface(name)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+	// Files are merged exactly; live interfaces hold frames back briefly.
+	window := capture.DefaultMergeWindow
+	if len(interfaceNames) == 0 && len(remoteUrls) == 0 {
+		window = 0
+	}
+	return capture.NewMergedSource(window, sources...), nil
+}
 
-	handle, _ := pcap.OpenLive(devices[1].Name, 2000, true, pcap.BlockForever)
+func openInterface(name string) (capture.CaptureSource, error) {
+	switch *backend {
+	case "afpacket":
+		config := capture.DefaultAfPacketConfig(name)
+		config.Fanout = *fanout
+		config.FanoutType = *fanoutType
+		config.BlockSize = *blockSize
+		config.NumBlocks = *numBlocks
+		if *captureFilter != "" {
+			return nil, errors.New("-filter needs the pcap backend")
+		}
+		return capture.NewAfPacketSource(config)
+	case "pcap":
+		source, err := capture.NewPcapSource(name, 2000, true)
+		if err != nil {
+			return nil, err
+		}
+		if *captureFilter != "" {
+			if err := source.SetFilter(*captureFilter); err != nil {
+				source.Close()
+				return nil, err
+			}
+		}
+		return source, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q", *backend)
+}
 
-	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
-	for receivedPacket := range packetSource.Packets() {
-		//fmt.Println(receivedPacket)
-		ethernetPacket := packet.ParseFactoryMethod(receivedPacket.Data(), protocol.Ethernet)
-		fmt.Println(ethernetPacket.ToString())
-		//receivedBytes := receivedPacket.Data()
-		//receivedBytes[0:6]
-		//fmt.Println(len(receivedPacket.Data()))
+// listRemote prints the interfaces an rpcap server offers.
+func listRemote(config rpcap.SourceConfig) error {
+	interfaces, err := rpcap.FindAllDevs(config)
+	if err != nil {
+		return err
 	}
+	for _, i := range interfaces {
+		fmt.Printf("%-16s %v %s\n", i.Name, i.Addresses, i.Description)
+	}
+	return nil
+}
 
-}
\ No newline at end of file
+func deviceName(index int) (string, error) {
+	devices, err := pcap.FindAllDevs()
+	if err != nil {
+		return "", err
+	}
+	if index < 0 || index >= len(devices) {
+		return "", fmt.Errorf("no capture device %d", index)
+	}
+	return devices[index].Name, nil
+}
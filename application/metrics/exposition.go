@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// exposition writes the Prometheus text format, version 0.0.4.
+type exposition struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (e *exposition) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(e.w, format, args...)
+	e.n += int64(n)
+	e.err = err
+}
+
+func (e *exposition) header(name string, kind string, help string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (e *exposition) sample(name string, labels []label, value float64) {
+	e.printf("%s%s %s\n", name, formatLabels(labels), formatValue(value))
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.name + `="` + escapeLabel(l.value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// series is one labelled counter of a family.
+type series struct {
+	labels []label
+	value  float64
+}
+
+// family writes a metric family with its series sorted by labels, so the
+// output is stable between scrapes.
+func (e *exposition) family(name string, kind string, help string, all []series) {
+	sort.Slice(all, func(i, j int) bool {
+		return formatLabels(all[i].labels) < formatLabels(all[j].labels)
+	})
+	e.header(name, kind, help)
+	for _, s := range all {
+		e.sample(name, s.labels, s.value)
+	}
+}
+
+// histogram counts observations into cumulative buckets of upper bounds.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (e *exposition) histogram(name string, help string, h *histogram) {
+	e.header(name, "histogram", help)
+	for i, bound := range h.bounds {
+		e.sample(name+"_bucket", []label{{"le", formatValue(bound)}}, float64(h.counts[i]))
+	}
+	e.sample(name+"_bucket", []label{{"le", "+Inf"}}, float64(h.count))
+	e.sample(name+"_sum", nil, h.sum)
+	e.sample(name+"_count", nil, float64(h.count))
+}
@@ -0,0 +1,386 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sniffer/application/flow"
+	"sniffer/application/health"
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	dnsBuckets  = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+	httpBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+var httpMethods = [][]byte{
+	[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "), []byte("HEAD "),
+	[]byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "), []byte("TRACE "),
+}
+
+const dnsPort = 53
+
+type Config struct {
+	// Monitor supplies capture drop and decode error counters, if set.
+	Monitor *health.Monitor
+	// LatencyTimeout forgets DNS queries and HTTP requests that were not
+	// answered within this long.
+	LatencyTimeout time.Duration
+	// MaxPending caps the number of unanswered queries and requests kept.
+	MaxPending int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		LatencyTimeout: 30 * time.Second,
+		MaxPending:     10000,
+	}
+}
+
+type counter struct {
+	packets uint64
+	bytes   uint64
+}
+
+func (c *counter) add(length int) {
+	c.packets++
+	c.bytes += uint64(length)
+}
+
+const (
+	pendingDns = iota
+	pendingHttp
+)
+
+// pendingKey identifies an unanswered DNS query, by transaction id, or
+// HTTP request.
+type pendingKey struct {
+	kind   int
+	client flow.Endpoint
+	server flow.Endpoint
+	id     uint16
+}
+
+type pendingRequest struct {
+	key  pendingKey
+	sent time.Time
+}
+
+// Collector turns decoded packets into Prometheus metrics. Observe is
+// called from the packet loop and the HTTP handler may scrape at any time.
+type Collector struct {
+	mutex  sync.Mutex
+	config Config
+
+	protocols     map[string]*counter
+	etherTypes    map[packet.EtherType]*counter
+	ipProtocols   map[packet.IpPayloadProtocol]*counter
+	tcpFlags      map[string]uint64
+	icmpTypes     map[packet.IcmpV4Type]uint64
+	arpOperations map[string]uint64
+	activeFlows   int
+	trackFlows    bool
+
+	dnsLatency  *histogram
+	httpLatency *histogram
+	pending     map[pendingKey]time.Time
+	// order lists the pending requests oldest first, so expired ones are
+	// pruned from its front. Answered requests stay in it until they reach
+	// the front or it is compacted.
+	order []pendingRequest
+}
+
+func NewCollector(config Config) *Collector {
+	return &Collector{
+		config:        config,
+		protocols:     make(map[string]*counter),
+		etherTypes:    make(map[packet.EtherType]*counter),
+		ipProtocols:   make(map[packet.IpPayloadProtocol]*counter),
+		tcpFlags:      make(map[string]uint64),
+		icmpTypes:     make(map[packet.IcmpV4Type]uint64),
+		arpOperations: make(map[string]uint64),
+		dnsLatency:    newHistogram(dnsBuckets),
+		httpLatency:   newHistogram(httpBuckets),
+		pending:       make(map[pendingKey]time.Time),
+	}
+}
+
+// Observe accounts one decoded frame. length is its wire length.
+func (c *Collector) Observe(p packet.Parsable, length int, timestamp time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for layer := p; layer != nil; {
+		var next packet.Parsable
+		switch l := layer.(type) {
+		case packet.EthernetPacket:
+			c.count(protocol.Ethernet.Name, length)
+			c.countEtherType(l.Header.Type, length)
+			next = nextLayer(l.Packet)
+		case packet.ArpPacket:
+			c.count(l.ProtocolName, length)
+			c.arpOperations[l.Header.Operation.Name]++
+		case packet.Ipv4Packet:
+			c.count(l.ProtocolName, length)
+			c.countIpProtocol(l.Header.PayloadProtocol, length)
+			next = nextLayer(l.Packet)
+		case packet.TcpPacket:
+			c.count(l.ProtocolName, length)
+			c.countTcpFlags(l.Header)
+		case packet.UdpPacket:
+			c.count(l.ProtocolName, length)
+		case packet.IcmpV4Packet:
+			c.count(l.ProtocolName, length)
+			c.icmpTypes[l.Header.Type]++
+		}
+		layer = next
+	}
+
+	if observation, ok := flow.Observe(p); ok {
+		c.observeLatency(observation, timestamp)
+	}
+}
+
+func nextLayer(p packet.Packet) packet.Parsable {
+	if !p.CanParseMore {
+		return nil
+	}
+	return p.PacketParser
+}
+
+func (c *Collector) count(name string, length int) {
+	total, ok := c.protocols[name]
+	if !ok {
+		total = &counter{}
+		c.protocols[name] = total
+	}
+	total.add(length)
+}
+
+func (c *Collector) countEtherType(etherType packet.EtherType, length int) {
+	total, ok := c.etherTypes[etherType]
+	if !ok {
+		total = &counter{}
+		c.etherTypes[etherType] = total
+	}
+	total.add(length)
+}
+
+func (c *Collector) countIpProtocol(ipProtocol packet.IpPayloadProtocol, length int) {
+	total, ok := c.ipProtocols[ipProtocol]
+	if !ok {
+		total = &counter{}
+		c.ipProtocols[ipProtocol] = total
+	}
+	total.add(length)
+}
+
+func (c *Collector) countTcpFlags(h packet.TcpHeader) {
+	flags := []struct {
+		name string
+		set  bool
+	}{{"SYN", h.SYN}, {"ACK", h.ACK}, {"FIN", h.FIN}, {"RST", h.RST}, {"PSH", h.PSH}, {"URG", h.URG}}
+	for _, flag := range flags {
+		if flag.set {
+			c.tcpFlags[flag.name]++
+		}
+	}
+}
+
+// SetActiveFlows publishes the size of the flow table.
+func (c *Collector) SetActiveFlows(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.activeFlows = n
+	c.trackFlows = true
+}
+
+// observeLatency pairs DNS queries with responses by transaction id, and
+// the first HTTP request on a connection with the response that follows.
+// Packet timestamps are used, so latencies are right for files too.
+func (c *Collector) observeLatency(o flow.Observation, timestamp time.Time) {
+	switch transport := o.Transport.(type) {
+	case packet.UdpPacket:
+		payload := transport.RawPayload
+		if len(payload) < 12 || (o.Source.Port != dnsPort && o.Destination.Port != dnsPort) {
+			return
+		}
+		id := uint16(payload[0])<<8 | uint16(payload[1])
+		response := payload[2]&0x80 != 0
+		if !response && o.Destination.Port == dnsPort {
+			c.request(pendingKey{kind: pendingDns, client: o.Source, server: o.Destination, id: id}, timestamp)
+		} else if response && o.Source.Port == dnsPort {
+			c.response(pendingKey{kind: pendingDns, client: o.Destination, server: o.Source, id: id}, timestamp, c.dnsLatency)
+		}
+
+	case packet.TcpPacket:
+		payload := transport.RawPayload
+		if isHttpRequest(payload) {
+			c.request(pendingKey{kind: pendingHttp, client: o.Source, server: o.Destination}, timestamp)
+		} else if bytes.HasPrefix(payload, []byte("HTTP/")) {
+			c.response(pendingKey{kind: pendingHttp, client: o.Destination, server: o.Source}, timestamp, c.httpLatency)
+		}
+	}
+}
+
+// request remembers when a query or request was sent. Only the first of a
+// retransmitted query or pipelined requests is kept.
+func (c *Collector) request(key pendingKey, timestamp time.Time) {
+	if _, ok := c.pending[key]; ok {
+		return
+	}
+	c.prune(timestamp)
+	if len(c.pending) >= c.config.MaxPending {
+		return
+	}
+	c.pending[key] = timestamp
+	c.order = append(c.order, pendingRequest{key: key, sent: timestamp})
+}
+
+// prune forgets requests older than LatencyTimeout, walking only the ones
+// that have expired or were answered.
+func (c *Collector) prune(now time.Time) {
+	for len(c.order) > 0 {
+		oldest := c.order[0]
+		sent, ok := c.pending[oldest.key]
+		current := ok && sent.Equal(oldest.sent)
+		if current && now.Sub(sent) <= c.config.LatencyTimeout {
+			break
+		}
+		if current {
+			delete(c.pending, oldest.key)
+		}
+		c.order = c.order[1:]
+	}
+	if len(c.order) > 2*len(c.pending)+64 {
+		live := c.order[:0]
+		for _, r := range c.order {
+			if sent, ok := c.pending[r.key]; ok && sent.Equal(r.sent) {
+				live = append(live, r)
+			}
+		}
+		c.order = live
+	}
+}
+
+func (c *Collector) response(key pendingKey, timestamp time.Time, latency *histogram) {
+	sent, ok := c.pending[key]
+	if !ok {
+		return
+	}
+	delete(c.pending, key)
+	if elapsed := timestamp.Sub(sent); elapsed <= c.config.LatencyTimeout {
+		latency.observe(elapsed.Seconds())
+	}
+}
+
+func isHttpRequest(payload []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(payload, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	e := &exposition{w: w}
+	if c.config.Monitor != nil {
+		writeHealth(e, c.config.Monitor.Snapshot())
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var packets, octets []series
+	for name, total := range c.protocols {
+		labels := []label{{"protocol", name}}
+		packets = append(packets, series{labels, float64(total.packets)})
+		octets = append(octets, series{labels, float64(total.bytes)})
+	}
+	e.family("sniffer_packets_total", "counter", "Frames that carried each protocol layer.", packets)
+	e.family("sniffer_bytes_total", "counter", "Wire bytes of the frames that carried each protocol layer.", octets)
+
+	packets, octets = nil, nil
+	for etherType, total := range c.etherTypes {
+		labels := []label{{"ethertype", fmt.Sprintf("0x%04x", etherType.Value)}, {"name", etherType.Name}}
+		packets = append(packets, series{labels, float64(total.packets)})
+		octets = append(octets, series{labels, float64(total.bytes)})
+	}
+	e.family("sniffer_ethertype_packets_total", "counter", "Frames by EtherType.", packets)
+	e.family("sniffer_ethertype_bytes_total", "counter", "Wire bytes by EtherType.", octets)
+
+	packets, octets = nil, nil
+	for ipProtocol, total := range c.ipProtocols {
+		labels := []label{{"protocol", strconv.Itoa(int(ipProtocol.Value))}, {"name", ipProtocol.PayloadProtocol.Name}}
+		packets = append(packets, series{labels, float64(total.packets)})
+		octets = append(octets, series{labels, float64(total.bytes)})
+	}
+	e.family("sniffer_ip_protocol_packets_total", "counter", "IPv4 packets by protocol number.", packets)
+	e.family("sniffer_ip_protocol_bytes_total", "counter", "Wire bytes of IPv4 packets by protocol number.", octets)
+
+	var all []series
+	for flag, count := range c.tcpFlags {
+		all = append(all, series{[]label{{"flag", flag}}, float64(count)})
+	}
+	e.family("sniffer_tcp_flags_total", "counter", "TCP segments with each flag set.", all)
+
+	all = nil
+	for icmpType, count := range c.icmpTypes {
+		all = append(all, series{[]label{{"type", strconv.Itoa(int(icmpType.Value))}, {"name", icmpType.Name}}, float64(count)})
+	}
+	e.family("sniffer_icmp_messages_total", "counter", "ICMP messages by type.", all)
+
+	all = nil
+	for operation, count := range c.arpOperations {
+		all = append(all, series{[]label{{"operation", operation}}, float64(count)})
+	}
+	e.family("sniffer_arp_operations_total", "counter", "ARP packets by operation.", all)
+
+	if c.trackFlows {
+		e.header("sniffer_active_flows", "gauge", "Conversations in the flow table.")
+		e.sample("sniffer_active_flows", nil, float64(c.activeFlows))
+	}
+
+	e.histogram("sniffer_dns_latency_seconds", "Time from a DNS query to its response.", c.dnsLatency)
+	e.histogram("sniffer_http_latency_seconds", "Time from an HTTP request to the start of its response.", c.httpLatency)
+	return e.n, e.err
+}
+
+func writeHealth(e *exposition, s health.Snapshot) {
+	e.header("sniffer_capture_received_total", "counter", "Frames the kernel or libpcap received, including dropped ones.")
+	e.sample("sniffer_capture_received_total", nil, float64(s.Received))
+	e.header("sniffer_capture_captured_total", "counter", "Frames read by the sniffer.")
+	e.sample("sniffer_capture_captured_total", nil, float64(s.Captured))
+	e.family("sniffer_capture_dropped_total", "counter", "Frames lost before decoding, by where they were dropped.", []series{
+		{[]label{{"stage", "kernel"}}, float64(s.KernelDropped)},
+		{[]label{{"stage", "interface"}}, float64(s.InterfaceDropped)},
+		{[]label{{"stage", "queue"}}, float64(s.QueueDropped)},
+	})
+	e.header("sniffer_truncated_frames_total", "counter", "Frames captured shorter than they were on the wire.")
+	e.sample("sniffer_truncated_frames_total", nil, float64(s.Truncated))
+	e.header("sniffer_decode_errors_total", "counter", "Frames the decoder could not parse.")
+	e.sample("sniffer_decode_errors_total", nil, float64(s.DecodeFailed))
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var body bytes.Buffer
+	c.WriteTo(&body)
+	w.Write(body.Bytes())
+}
+
+// Serve exposes the collector on /metrics until the listener fails.
+func Serve(listener net.Listener, c *Collector) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c)
+	return http.Serve(listener, mux)
+}
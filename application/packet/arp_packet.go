@@ -125,6 +125,9 @@ var arpHardwareTypeRegistry = []ArpHardwareType{
 }
 
 func (a ArpPacket) parse(rawData []byte) Parsable {
+	if len(rawData) < ArpHeaderLength {
+		return nil
+	}
 	rawData = rawData[0:ArpHeaderLength]
 	header := parseArpHeader(rawData)
 	arpPacket := ArpPacket{
@@ -198,3 +201,18 @@ func getArpOperation(data []byte) ArpOperation {
 
 	return ArpOperation{Value: operationCode, Name: "Unknown"}
 }
+
+func (a ArpPacket) fields(base int) Field {
+	h := a.Header
+	l := newLayerFields("arp", "Address Resolution Protocol ("+h.Operation.Name+")", base, ArpHeaderLength)
+	l.add("arp.hw.type", "Hardware type: "+h.HardwareType.Name, ArpHardwareTypeOffset, ArpHardwareTypeSize)
+	l.add("arp.proto.type", "Protocol type: "+h.ProtocolType.Name, ArpProtocolTypeOffset, ArpProtocolTypeSize)
+	l.add("arp.hw.size", fmt.Sprintf("Hardware size: %d", h.HardwareAddressLength), ArpHardwareAddrLengthOffset, ArpHardwareAddrLengthSize)
+	l.add("arp.proto.size", fmt.Sprintf("Protocol size: %d", h.ProtocolAddressLength), ArpProtocolAddressLengthOffset, ArpProtocolAddressLengthSize)
+	l.add("arp.opcode", fmt.Sprintf("Opcode: %s (%d)", h.Operation.Name, h.Operation.Value), ArpOperationOffset, ArpOperationSize)
+	l.add("arp.src.hw_mac", "Sender MAC address: "+h.SrcHardwareAddr.ToString(), ArpSourceMacAddressOffset, ArpSourceMacAddressSize)
+	l.add("arp.src.proto_ipv4", "Sender IP address: "+h.SrcAddress.ToString(), ArpSourceProtocolAddressOffset, ArpSourceProtocolAddressSize)
+	l.add("arp.dst.hw_mac", "Target MAC address: "+h.DstHardwareAddr.ToString(), ArpDestHardwareAddressOffset, ArpDestHardwareAddressSize)
+	l.add("arp.dst.proto_ipv4", "Target IP address: "+h.DstAddress.ToString(), ArpDestProtocolAddressOffset, ArpDestProtocolAddressSize)
+	return l.layer
+}
@@ -0,0 +1,43 @@
+package packet
+
+// InternetChecksum is the RFC 1071 ones' complement sum over the
+// concatenation of parts.
+func InternetChecksum(parts ...[]byte) uint16 {
+	var sum uint32
+	odd := false
+	for _, part := range parts {
+		for _, b := range part {
+			if odd {
+				sum += uint32(b)
+			} else {
+				sum += uint32(b) << 8
+			}
+			odd = !odd
+		}
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// TransportChecksum computes a TCP or UDP checksum over the IPv4 pseudo
+// header and segment. The checksum field inside segment must be zero.
+func TransportChecksum(source []byte, destination []byte, protocol byte, segment []byte) uint16 {
+	pseudo := []byte{0, protocol, byte(len(segment) >> 8), byte(len(segment))}
+	return InternetChecksum(source, destination, pseudo, segment)
+}
+
+// AdjustChecksum updates checksum after the even length data old has been
+// replaced by new, without summing the rest of the packet (RFC 1624).
+func AdjustChecksum(checksum uint16, old []byte, new []byte) uint16 {
+	sum := uint32(^checksum)
+	for i := 0; i+1 < len(old); i += 2 {
+		sum += uint32(^(uint16(old[i])<<8 | uint16(old[i+1])))
+		sum += uint32(uint16(new[i])<<8 | uint16(new[i+1]))
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,385 @@
+package packet
+
+import (
+	"errors"
+	"sniffer/application/protocol"
+	"strconv"
+)
+
+// DecodingLayer is a layer that decodes into itself, so one preallocated
+// object can be reused for every frame. DecodeFromBytes keeps slices into
+// data instead of copying, and leaves PacketParser nil: the layers decoded
+// from one frame are not linked, see LayerParser.Chain.
+type DecodingLayer interface {
+	Parsable
+	LayerType() protocol.Protocol
+	DecodeFromBytes(data []byte) error
+	// NextLayerType is the protocol of LayerPayload, or the zero Protocol
+	// when the payload is not decoded further.
+	NextLayerType() protocol.Protocol
+	LayerPayload() []byte
+}
+
+var (
+	ErrEthernetTruncated = errors.New("ethernet: frame shorter than its header")
+	ErrIpv4Truncated     = errors.New("ipv4: packet shorter than its header")
+	ErrTcpTruncated      = errors.New("tcp: segment shorter than its header")
+	ErrUdpTruncated      = errors.New("udp: datagram shorter than its header")
+)
+
+func (e *EthernetPacket) LayerType() protocol.Protocol {
+	return protocol.Ethernet
+}
+
+func (e *EthernetPacket) DecodeFromBytes(data []byte) error {
+	if len(data) < HeaderLength {
+		return ErrEthernetTruncated
+	}
+	e.Header = parseHeader(data[0:HeaderLength])
+	e.Packet = Packet{
+		CanParseMore: e.Header.Type == IPV4 || e.Header.Type == ARP,
+		RawHeader:    data[0:HeaderLength],
+		RawPayload:   data[HeaderLength:],
+		HeaderLength: HeaderLength,
+	}
+	return nil
+}
+
+func (e *EthernetPacket) NextLayerType() protocol.Protocol {
+	switch e.Header.Type {
+	case IPV4:
+		return protocol.IpV4
+	case ARP:
+		return protocol.Arp
+	}
+	return protocol.Protocol{}
+}
+
+func (e *EthernetPacket) LayerPayload() []byte {
+	return e.RawPayload
+}
+
+func (i *Ipv4Packet) LayerType() protocol.Protocol {
+	return protocol.IpV4
+}
+
+func (i *Ipv4Packet) DecodeFromBytes(data []byte) error {
+	if len(data) < Ipv4MinHeaderSize {
+		return ErrIpv4Truncated
+	}
+	headerLength := int(data[Ipv4VersionAndIhlOffset]&0x0f) * 4
+	if headerLength < Ipv4MinHeaderSize || len(data) < headerLength {
+		return ErrIpv4Truncated
+	}
+	i.Header = parseIpV4Header(data)
+	i.Packet = Packet{
+		RawHeader:    data[0:headerLength],
+		RawPayload:   data[headerLength:i.Header.payloadEnd(len(data))],
+		CanParseMore: i.Header.canParseMore(),
+		ProtocolName: "IpV4",
+		Length:       int(i.Header.TotalLength) + headerLength,
+		HeaderLength: headerLength,
+	}
+	return nil
+}
+
+func (i *Ipv4Packet) NextLayerType() protocol.Protocol {
+	if !i.CanParseMore {
+		return protocol.Protocol{}
+	}
+	return i.Header.PayloadProtocol.PayloadProtocol
+}
+
+func (i *Ipv4Packet) LayerPayload() []byte {
+	return i.RawPayload
+}
+
+func (t *TcpPacket) LayerType() protocol.Protocol {
+	return protocol.Tcp
+}
+
+func (t *TcpPacket) DecodeFromBytes(data []byte) error {
+	if len(data) < TcpMinHeaderSize {
+		return ErrTcpTruncated
+	}
+	headerLength := int(data[TcpDataOffsetAndReservedBitsOffset]>>4) * 4
+	if headerLength < TcpMinHeaderSize || len(data) < headerLength {
+		return ErrTcpTruncated
+	}
+	t.Header = parseTcpHeader(data)
+	t.Packet = Packet{
+		RawHeader:    data[0:headerLength],
+		RawPayload:   data[headerLength:],
+		ProtocolName: protocol.Tcp.Name,
+		Length:       len(data),
+		HeaderLength: headerLength,
+	}
+	t.SourceProtocol = getProtocolBaseOnTcpPort(t.Header.SourcePort)
+	t.DestProtocol = getProtocolBaseOnTcpPort(t.Header.DestinationPort)
+	t.Annotations = t.Annotations[:0]
+	return nil
+}
+
+func (t *TcpPacket) NextLayerType() protocol.Protocol {
+	return protocol.Protocol{}
+}
+
+func (t *TcpPacket) LayerPayload() []byte {
+	return t.RawPayload
+}
+
+func (u *UdpPacket) LayerType() protocol.Protocol {
+	return protocol.Udp
+}
+
+func (u *UdpPacket) DecodeFromBytes(data []byte) error {
+	return u.decodeWithin(data, -1)
+}
+
+// decodeWithin checks Length against the payload length the IP header
+// claims, which LayerParser passes on, see payloadLengthLayer.
+func (u *UdpPacket) decodeWithin(data []byte, ipPayloadLength int) error {
+	if len(data) < UdpHeaderSize {
+		return ErrUdpTruncated
+	}
+	u.decode(data, ipPayloadLength)
+	return nil
+}
+
+// NextLayerType is the application protocol the datagram was dispatched
+// to. No DecodingLayer exists for those; Chain decodes them.
+func (u *UdpPacket) NextLayerType() protocol.Protocol {
+	return u.Application
+}
+
+func (u *UdpPacket) LayerPayload() []byte {
+	return u.RawPayload
+}
+
+// payloadLengthLayer is a layer that knows how long its payload claims to
+// be, which is more than LayerPayload holds when the capture was cut short.
+// It is -1 when unknown.
+type payloadLengthLayer interface {
+	payloadLength() int
+}
+
+// lengthCheckedLayer is a layer that checks its own length field against
+// the payload length claimed by the layer below it.
+type lengthCheckedLayer interface {
+	decodeWithin(data []byte, payloadLength int) error
+}
+
+// LayerParser decodes frames into a fixed set of preallocated layers, in
+// the style of gopacket's DecodingLayerParser:
+//
+//	var ethernet packet.EthernetPacket
+//	var ipv4 packet.Ipv4Packet
+//	var tcp packet.TcpPacket
+//	parser := packet.NewLayerParser(protocol.Ethernet, &ethernet, &ipv4, &tcp)
+//	decoded := make([]protocol.Protocol, 0, 4)
+//	for each frame {
+//		err := parser.DecodeLayers(frame, &decoded)
+//		...
+//	}
+//
+// Decoding does not allocate. Each call overwrites the layers, so anything
+// kept past the next frame must be copied out first.
+type LayerParser struct {
+	first  protocol.Protocol
+	layers []DecodingLayer
+	// Next is the protocol the last call stopped at because no layer was
+	// registered for it, or the zero Protocol if it decoded to the end.
+	Next protocol.Protocol
+}
+
+func NewLayerParser(first protocol.Protocol, layers ...DecodingLayer) *LayerParser {
+	return &LayerParser{first: first, layers: layers}
+}
+
+func (p *LayerParser) layer(t protocol.Protocol) DecodingLayer {
+	for _, l := range p.layers {
+		if l.LayerType() == t {
+			return l
+		}
+	}
+	return nil
+}
+
+// DecodeLayers decodes data starting with the first layer and appends the
+// type of every decoded layer to decoded, which it truncates first. It
+// stops without error at a layer it has no decoder for, see Next, and
+// returns the error of a layer that fails to decode.
+func (p *LayerParser) DecodeLayers(data []byte, decoded *[]protocol.Protocol) error {
+	*decoded = (*decoded)[:0]
+	p.Next = p.first
+	payloadLength := -1
+	for p.Next != (protocol.Protocol{}) {
+		l := p.layer(p.Next)
+		if l == nil {
+			return nil
+		}
+		var err error
+		if checked, ok := l.(lengthCheckedLayer); ok {
+			err = checked.decodeWithin(data, payloadLength)
+		} else {
+			err = l.DecodeFromBytes(data)
+		}
+		if err != nil {
+			return err
+		}
+		*decoded = append(*decoded, p.Next)
+		p.Next = l.NextLayerType()
+		data = l.LayerPayload()
+		payloadLength = -1
+		if claimed, ok := l.(payloadLengthLayer); ok {
+			payloadLength = claimed.payloadLength()
+		}
+	}
+	return nil
+}
+
+// Chain copies the decoded layers into the linked value tree returned by
+// ParseFactoryMethod, decoding any layer the parser stopped at the classic
+// way, so the result can be handed to code written against that API. It
+// allocates; keep it off the hot path.
+func (p *LayerParser) Chain(decoded []protocol.Protocol) Parsable {
+	if len(decoded) == 0 {
+		return nil
+	}
+	var next Parsable
+	if p.Next != (protocol.Protocol{}) {
+		next = parseRest(p.layer(decoded[len(decoded)-1]).LayerPayload(), p.Next)
+	}
+	for i := len(decoded) - 1; i >= 0; i-- {
+		switch l := p.layer(decoded[i]).(type) {
+		case *EthernetPacket:
+			layer := *l
+			layer.PacketParser = next
+			// The layer above may be too short to decode.
+			layer.CanParseMore = next != nil
+			next = layer
+		case *Ipv4Packet:
+			layer := *l
+			layer.PacketParser = next
+			layer.CanParseMore = next != nil
+			next = layer
+		case *TcpPacket:
+			layer := *l
+			layer.PacketParser = next
+			layer.Annotations = append([]string(nil), l.Annotations...)
+			next = layer
+		case *UdpPacket:
+			layer := *l
+			layer.PacketParser = next
+			next = layer
+		default:
+			next = l
+		}
+	}
+	return next
+}
+
+func parseRest(data []byte, p protocol.Protocol) (result Parsable) {
+	defer func() {
+		if recover() != nil {
+			result = nil
+		}
+	}()
+	return ParseFactoryMethod(data, p)
+}
+
+// AppendSummary appends a one line description of the decoded layers to
+// dst, such as
+//
+//	10.0.0.1 > 192.168.0.1 Tcp 1024 > 80 [PSH ACK] seq 1 ack 0 win 65535 len 512
+//
+// It does not allocate when dst has room, unlike ToString.
+func (p *LayerParser) AppendSummary(dst []byte, decoded []protocol.Protocol) []byte {
+	for _, t := range decoded {
+		switch l := p.layer(t).(type) {
+		case *EthernetPacket:
+			if len(decoded) == 1 {
+				dst = appendMac(dst, l.Header.SrcMacAddr.Value)
+				dst = append(dst, " > "...)
+				dst = appendMac(dst, l.Header.DestMacAddr.Value)
+				dst = append(dst, " type 0x"...)
+				dst = strconv.AppendUint(dst, uint64(l.Header.Type.Value), 16)
+			}
+		case *Ipv4Packet:
+			dst = appendIpv4(dst, l.Header.SourceAddress.Value)
+			dst = append(dst, " > "...)
+			dst = appendIpv4(dst, l.Header.DestinationAddress.Value)
+			dst = append(dst, ' ')
+			dst = append(dst, l.Header.PayloadProtocol.PayloadProtocol.Name...)
+		case *TcpPacket:
+			h := &l.Header
+			dst = append(dst, ' ')
+			dst = strconv.AppendUint(dst, uint64(h.SourcePort), 10)
+			dst = append(dst, " > "...)
+			dst = strconv.AppendUint(dst, uint64(h.DestinationPort), 10)
+			dst = append(dst, " ["...)
+			dst = appendTcpFlags(dst, h.Flags())
+			dst = append(dst, "] seq "...)
+			dst = strconv.AppendUint(dst, uint64(h.SequenceNumber), 10)
+			dst = append(dst, " ack "...)
+			dst = strconv.AppendUint(dst, uint64(h.AckNumber), 10)
+			dst = append(dst, " win "...)
+			dst = strconv.AppendUint(dst, uint64(h.Window), 10)
+			dst = append(dst, " len "...)
+			dst = strconv.AppendInt(dst, int64(len(l.RawPayload)), 10)
+		case *UdpPacket:
+			dst = append(dst, ' ')
+			dst = strconv.AppendUint(dst, uint64(l.Header.SourcePort), 10)
+			dst = append(dst, " > "...)
+			dst = strconv.AppendUint(dst, uint64(l.Header.DestinationPort), 10)
+			dst = append(dst, " len "...)
+			dst = strconv.AppendInt(dst, int64(len(l.RawPayload)), 10)
+			if l.CanParseMore {
+				dst = append(dst, ' ')
+				dst = append(dst, l.Application.Name...)
+			}
+		}
+	}
+	if p.Next != (protocol.Protocol{}) && len(decoded) > 0 && decoded[len(decoded)-1] == protocol.Ethernet {
+		dst = append(dst, ' ')
+		dst = append(dst, p.Next.Name...)
+	}
+	return dst
+}
+
+func appendIpv4(dst []byte, address []byte) []byte {
+	for i, b := range address {
+		if i > 0 {
+			dst = append(dst, '.')
+		}
+		dst = strconv.AppendUint(dst, uint64(b), 10)
+	}
+	return dst
+}
+
+func appendMac(dst []byte, address []byte) []byte {
+	const hex = "0123456789abcdef"
+	for i, b := range address {
+		if i > 0 {
+			dst = append(dst, ':')
+		}
+		dst = append(dst, hex[b>>4], hex[b&0x0f])
+	}
+	return dst
+}
+
+func appendTcpFlags(dst []byte, flags byte) []byte {
+	names := [...]string{"FIN", "SYN", "RST", "PSH", "ACK", "URG"}
+	first := true
+	for i, name := range names {
+		if flags&(1<<uint(i)) == 0 {
+			continue
+		}
+		if !first {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, name...)
+		first = false
+	}
+	return dst
+}
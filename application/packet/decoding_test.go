@@ -0,0 +1,124 @@
+package packet
+
+import (
+	"encoding/binary"
+	"sniffer/application/protocol"
+	"testing"
+)
+
+func arpFrame() []byte {
+	frame := make([]byte, HeaderLength+ArpHeaderLength)
+	binary.BigEndian.PutUint16(frame[TypeOffset:], ARP.Value)
+	arp := frame[HeaderLength:]
+	copy(arp, []byte{0, 1, 8, 0, 6, 4, 0, 1})
+	copy(arp[ArpSourceProtocolAddressOffset:], []byte{10, 0, 0, 1})
+	copy(arp[ArpDestProtocolAddressOffset:], []byte{10, 0, 0, 2})
+	return frame
+}
+
+// withProtocol turns the datagram of udpFrame into another IP protocol.
+func withProtocol(frame []byte, ipProtocol byte) []byte {
+	frame = append([]byte(nil), frame...)
+	frame[HeaderLength+Ipv4ProtocolOffset] = ipProtocol
+	if ipProtocol == 6 {
+		frame[HeaderLength+Ipv4MinHeaderSize+TcpDataOffsetAndReservedBitsOffset] = 5 << 4
+	}
+	return frame
+}
+
+func testFrames() map[string][]byte {
+	udp := udpFrame(UdpHeaderSize+len(dnsQuery), dnsQuery)
+	return map[string][]byte{
+		"arp":  arpFrame(),
+		"udp":  udp,
+		"tcp":  withProtocol(udpFrame(UdpHeaderSize+32, make([]byte, 32)), 6),
+		"icmp": withProtocol(udp, 1),
+	}
+}
+
+// TestTruncatedFramesDecode cuts every test frame at every length: decoding
+// must never panic, and whatever decodes must print.
+func TestTruncatedFramesDecode(t *testing.T) {
+	var ethernet EthernetPacket
+	var ipv4 Ipv4Packet
+	var tcp TcpPacket
+	var udp UdpPacket
+	parser := NewLayerParser(protocol.Ethernet, &ethernet, &ipv4, &tcp, &udp)
+	var decoded []protocol.Protocol
+
+	for name, frame := range testFrames() {
+		for length := 0; length <= len(frame); length++ {
+			data := frame[:length]
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s cut to %d bytes: %v", name, length, r)
+					}
+				}()
+				if p := ParseFactoryMethod(data, protocol.Ethernet); p != nil {
+					p.ToString()
+					Dissect(p)
+				} else if length >= HeaderLength {
+					t.Errorf("%s cut to %d bytes: no Ethernet layer", name, length)
+				}
+				if parser.DecodeLayers(data, &decoded) == nil && len(decoded) > 0 {
+					parser.AppendSummary(nil, decoded)
+					parser.Chain(decoded).ToString()
+				}
+			}()
+		}
+	}
+}
+
+func TestTruncatedArpStopsAtEthernet(t *testing.T) {
+	frame := arpFrame()[:HeaderLength+10]
+	classic, ok := ParseFactoryMethod(frame, protocol.Ethernet).(EthernetPacket)
+	if !ok || classic.CanParseMore || classic.PacketParser != nil {
+		t.Fatalf("classic: got %#v, want an Ethernet layer that stops", classic)
+	}
+
+	var ethernet EthernetPacket
+	parser := NewLayerParser(protocol.Ethernet, &ethernet)
+	var decoded []protocol.Protocol
+	if err := parser.DecodeLayers(frame, &decoded); err != nil || parser.Next != protocol.Arp {
+		t.Fatalf("DecodeLayers: %v, stopped at %q", err, parser.Next.Name)
+	}
+	chained := parser.Chain(decoded).(EthernetPacket)
+	if chained.CanParseMore {
+		t.Fatal("Chain: Ethernet layer still claims an ARP layer")
+	}
+}
+
+func TestMalformedHeaderLengths(t *testing.T) {
+	udp := udpFrame(UdpHeaderSize+len(dnsQuery), dnsQuery)
+	badIhl := append([]byte(nil), udp...)
+	badIhl[HeaderLength+Ipv4VersionAndIhlOffset] = 0x4f
+	shortIhl := append([]byte(nil), udp...)
+	shortIhl[HeaderLength+Ipv4VersionAndIhlOffset] = 0x44
+	badDataOffset := withProtocol(udp, 6)
+	badDataOffset[HeaderLength+Ipv4MinHeaderSize+TcpDataOffsetAndReservedBitsOffset] = 0xf0
+
+	for _, test := range []struct {
+		name  string
+		frame []byte
+		// depth is how many layers the classic decoder gets through.
+		depth int
+	}{
+		{"ihl past the frame", badIhl, 1},
+		{"ihl below the minimum", shortIhl, 1},
+		{"tcp data offset past the segment", badDataOffset, 2},
+	} {
+		depth := 0
+		for p := ParseFactoryMethod(test.frame, protocol.Ethernet); p != nil; {
+			depth++
+			packet := packetOf(p)
+			if !packet.CanParseMore {
+				break
+			}
+			p = packet.PacketParser
+		}
+		if depth != test.depth {
+			t.Errorf("%s: decoded %d layers, want %d", test.name, depth, test.depth)
+		}
+	}
+}
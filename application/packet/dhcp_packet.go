@@ -0,0 +1,207 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sniffer/application/protocol"
+)
+
+const (
+	DhcpOpOffset            = 0
+	DhcpHardwareTypeOffset  = 1
+	DhcpTransactionIdOffset = 4
+	DhcpFlagsOffset         = 10
+	DhcpClientIpOffset      = 12
+	DhcpYourIpOffset        = 16
+	DhcpServerIpOffset      = 20
+	DhcpRelayIpOffset       = 24
+	DhcpClientMacOffset     = 28
+	DhcpCookieOffset        = 236
+	DhcpOptionsOffset       = 240
+	DhcpMagicCookie         = 0x63825363
+)
+
+const (
+	dhcpOptionPad         = 0
+	dhcpOptionHostName    = 12
+	dhcpOptionRequestedIp = 50
+	dhcpOptionMessageType = 53
+	dhcpOptionServerId    = 54
+	dhcpOptionEnd         = 255
+)
+
+var dhcpMessageTypes = []string{"", "Discover", "Offer", "Request", "Decline", "ACK", "NAK", "Release", "Inform"}
+
+var dhcpOptionNames = map[byte]string{
+	1: "Subnet Mask", 3: "Router", 6: "Domain Name Server", 12: "Host Name", 15: "Domain Name",
+	50: "Requested IP Address", 51: "IP Address Lease Time", 53: "DHCP Message Type",
+	54: "DHCP Server Identifier", 55: "Parameter Request List", 61: "Client Identifier",
+}
+
+type DhcpOption struct {
+	Code byte
+	Data []byte
+	// offset locates the option in the message.
+	offset int
+}
+
+func (o DhcpOption) ToString() string {
+	name, ok := dhcpOptionNames[o.Code]
+	if !ok {
+		name = fmt.Sprintf("Option %d", o.Code)
+	}
+	switch o.Code {
+	case dhcpOptionMessageType:
+		if len(o.Data) == 1 {
+			return name + ": " + dhcpMessageTypeName(o.Data[0])
+		}
+	case dhcpOptionHostName:
+		return name + ": " + string(o.Data)
+	case 1, 3, 6, dhcpOptionRequestedIp, dhcpOptionServerId:
+		if len(o.Data)%4 == 0 && len(o.Data) > 0 {
+			result := name + ":"
+			for i := 0; i < len(o.Data); i += 4 {
+				result += " " + net.IP(o.Data[i:i+4]).String()
+			}
+			return result
+		}
+	case 51:
+		if len(o.Data) == 4 {
+			return fmt.Sprintf("%s: %ds", name, binary.BigEndian.Uint32(o.Data))
+		}
+	}
+	return fmt.Sprintf("%s (%d bytes)", name, len(o.Data))
+}
+
+func dhcpMessageTypeName(t byte) string {
+	if t > 0 && int(t) < len(dhcpMessageTypes) {
+		return dhcpMessageTypes[t]
+	}
+	return fmt.Sprintf("type %d", t)
+}
+
+// DhcpPacket is a BOOTP message carrying DHCP options.
+type DhcpPacket struct {
+	Packet
+	Op            byte
+	HardwareType  byte
+	TransactionId uint32
+	Flags         uint16
+	ClientIp      net.IP
+	YourIp        net.IP
+	ServerIp      net.IP
+	RelayIp       net.IP
+	ClientMac     MacAddress
+	// MessageType is option 53, 0 for plain BOOTP.
+	MessageType byte
+	Options     []DhcpOption
+	Malformed   string
+}
+
+// isDhcp looks for the magic cookie that starts the DHCP options.
+func isDhcp(payload []byte) bool {
+	return len(payload) >= DhcpOptionsOffset && (payload[DhcpOpOffset] == 1 || payload[DhcpOpOffset] == 2) &&
+		binary.BigEndian.Uint32(payload[DhcpCookieOffset:]) == DhcpMagicCookie
+}
+
+func ParseDhcpPacket(rawData []byte) Parsable {
+	return DhcpPacket{}.parse(rawData)
+}
+
+func (d DhcpPacket) parse(rawData []byte) Parsable {
+	d = DhcpPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Dhcp.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	if len(rawData) < DhcpOptionsOffset {
+		d.Malformed = "message shorter than the BOOTP header"
+		return d
+	}
+	d.Op = rawData[DhcpOpOffset]
+	d.HardwareType = rawData[DhcpHardwareTypeOffset]
+	d.TransactionId = binary.BigEndian.Uint32(rawData[DhcpTransactionIdOffset:])
+	d.Flags = binary.BigEndian.Uint16(rawData[DhcpFlagsOffset:])
+	d.ClientIp = net.IP(rawData[DhcpClientIpOffset : DhcpClientIpOffset+4])
+	d.YourIp = net.IP(rawData[DhcpYourIpOffset : DhcpYourIpOffset+4])
+	d.ServerIp = net.IP(rawData[DhcpServerIpOffset : DhcpServerIpOffset+4])
+	d.RelayIp = net.IP(rawData[DhcpRelayIpOffset : DhcpRelayIpOffset+4])
+	d.ClientMac = MacAddress{Value: rawData[DhcpClientMacOffset : DhcpClientMacOffset+6]}
+	if binary.BigEndian.Uint32(rawData[DhcpCookieOffset:]) != DhcpMagicCookie {
+		d.Malformed = "no DHCP magic cookie"
+		return d
+	}
+
+	offset := DhcpOptionsOffset
+	for offset < len(rawData) {
+		code := rawData[offset]
+		if code == dhcpOptionEnd {
+			break
+		}
+		if code == dhcpOptionPad {
+			offset++
+			continue
+		}
+		if offset+2 > len(rawData) || offset+2+int(rawData[offset+1]) > len(rawData) {
+			d.Malformed = fmt.Sprintf("option %d is truncated", code)
+			break
+		}
+		length := int(rawData[offset+1])
+		option := DhcpOption{Code: code, Data: rawData[offset+2 : offset+2+length], offset: offset}
+		if code == dhcpOptionMessageType && length == 1 {
+			d.MessageType = option.Data[0]
+		}
+		d.Options = append(d.Options, option)
+		offset += 2 + length
+	}
+	return d
+}
+
+func (d DhcpPacket) Summary() string {
+	if d.MessageType == 0 {
+		op := "Request"
+		if d.Op == 2 {
+			op = "Reply"
+		}
+		return fmt.Sprintf("BOOTP %s - Transaction ID 0x%08x", op, d.TransactionId)
+	}
+	return fmt.Sprintf("DHCP %s - Transaction ID 0x%08x", dhcpMessageTypeName(d.MessageType), d.TransactionId)
+}
+
+func (d DhcpPacket) ToString() string {
+	if d.Length < DhcpOptionsOffset {
+		return fmt.Sprintf("DHCP Message [%d byte] - Malformed: %s ", d.Length, d.Malformed)
+	}
+	result := fmt.Sprintf("DHCP Message [%d byte] - %s ", d.Length, d.Summary()) +
+		fmt.Sprintf("- Client IP %s - Your IP %s - Server IP %s - Relay IP %s ", d.ClientIp, d.YourIp, d.ServerIp, d.RelayIp) +
+		fmt.Sprintf("- Client MAC %s ", d.ClientMac.ToString())
+	for _, o := range d.Options {
+		result += fmt.Sprintf("- %s ", o.ToString())
+	}
+	if d.Malformed != "" {
+		result += fmt.Sprintf("- Malformed: %s ", d.Malformed)
+	}
+	return result
+}
+
+func (d DhcpPacket) fields(base int) Field {
+	l := newLayerFields("dhcp", "Dynamic Host Configuration Protocol ("+dhcpMessageTypeName(d.MessageType)+")", base, d.Length)
+	if d.Length < DhcpOptionsOffset {
+		return l.layer
+	}
+	l.add("dhcp.type", fmt.Sprintf("Message type: %d", d.Op), DhcpOpOffset, 1)
+	l.add("dhcp.id", fmt.Sprintf("Transaction ID: 0x%08x", d.TransactionId), DhcpTransactionIdOffset, 4)
+	l.add("dhcp.flags", fmt.Sprintf("Flags: 0x%04x", d.Flags), DhcpFlagsOffset, 2)
+	l.add("dhcp.ip.client", "Client IP address: "+d.ClientIp.String(), DhcpClientIpOffset, 4)
+	l.add("dhcp.ip.your", "Your IP address: "+d.YourIp.String(), DhcpYourIpOffset, 4)
+	l.add("dhcp.ip.server", "Next server IP address: "+d.ServerIp.String(), DhcpServerIpOffset, 4)
+	l.add("dhcp.ip.relay", "Relay agent IP address: "+d.RelayIp.String(), DhcpRelayIpOffset, 4)
+	l.add("dhcp.hw.mac_addr", "Client MAC address: "+d.ClientMac.ToString(), DhcpClientMacOffset, 6)
+	l.add("dhcp.cookie", "Magic cookie: DHCP", DhcpCookieOffset, 4)
+	for _, o := range d.Options {
+		l.add(fmt.Sprintf("dhcp.option.%d", o.Code), o.ToString(), o.offset, 2+len(o.Data))
+	}
+	return l.layer
+}
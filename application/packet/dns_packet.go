@@ -0,0 +1,293 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sniffer/application/protocol"
+	"strings"
+)
+
+const (
+	DnsIdOffset         = 0
+	DnsFlagsOffset      = 2
+	DnsQuestionsOffset  = 4
+	DnsAnswersOffset    = 6
+	DnsAuthorityOffset  = 8
+	DnsAdditionalOffset = 10
+	DnsHeaderSize       = 12
+)
+
+var dnsTypeNames = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 12: "PTR", 15: "MX", 16: "TXT",
+	28: "AAAA", 33: "SRV", 41: "OPT", 65: "HTTPS", 255: "ANY",
+}
+
+var dnsRcodeNames = []string{"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED"}
+
+func dnsTypeName(t uint16) string {
+	if name, ok := dnsTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+type DnsHeader struct {
+	Id          uint16
+	Flags       uint16
+	Questions   uint16
+	Answers     uint16
+	Authorities uint16
+	Additionals uint16
+}
+
+func (h DnsHeader) Response() bool {
+	return h.Flags&0x8000 != 0
+}
+
+func (h DnsHeader) Opcode() byte {
+	return byte(h.Flags>>11) & 0x0f
+}
+
+func (h DnsHeader) Rcode() byte {
+	return byte(h.Flags) & 0x0f
+}
+
+func (h DnsHeader) RcodeName() string {
+	if int(h.Rcode()) < len(dnsRcodeNames) {
+		return dnsRcodeNames[h.Rcode()]
+	}
+	return fmt.Sprintf("RCODE%d", h.Rcode())
+}
+
+type DnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	// offset and length locate the question in the message.
+	offset int
+	length int
+}
+
+type DnsRecord struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	Ttl   uint32
+	// Data is the record data rendered as text, e.g. an address or a name.
+	Data   string
+	offset int
+	length int
+}
+
+func (r DnsRecord) ToString() string {
+	return fmt.Sprintf("%s %s %s ttl %d", r.Name, dnsTypeName(r.Type), r.Data, r.Ttl)
+}
+
+// DnsPacket decodes the header, the questions and the answers. Authority
+// and additional records are counted but not decoded.
+type DnsPacket struct {
+	Packet
+	Header    DnsHeader
+	Questions []DnsQuestion
+	Answers   []DnsRecord
+	// Malformed says why decoding stopped early.
+	Malformed string
+}
+
+func ParseDnsPacket(rawData []byte) Parsable {
+	return DnsPacket{}.parse(rawData)
+}
+
+func (d DnsPacket) parse(rawData []byte) Parsable {
+	d = DnsPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Dns.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	if len(rawData) < DnsHeaderSize {
+		d.Malformed = "message shorter than its header"
+		return d
+	}
+	d.Header = DnsHeader{
+		Id:          binary.BigEndian.Uint16(rawData[DnsIdOffset:]),
+		Flags:       binary.BigEndian.Uint16(rawData[DnsFlagsOffset:]),
+		Questions:   binary.BigEndian.Uint16(rawData[DnsQuestionsOffset:]),
+		Answers:     binary.BigEndian.Uint16(rawData[DnsAnswersOffset:]),
+		Authorities: binary.BigEndian.Uint16(rawData[DnsAuthorityOffset:]),
+		Additionals: binary.BigEndian.Uint16(rawData[DnsAdditionalOffset:]),
+	}
+
+	offset := DnsHeaderSize
+	for i := 0; i < int(d.Header.Questions); i++ {
+		name, next, ok := readDnsName(rawData, offset)
+		if !ok || next+4 > len(rawData) {
+			d.Malformed = fmt.Sprintf("question %d is truncated", i+1)
+			return d
+		}
+		d.Questions = append(d.Questions, DnsQuestion{
+			Name:   name,
+			Type:   binary.BigEndian.Uint16(rawData[next:]),
+			Class:  binary.BigEndian.Uint16(rawData[next+2:]),
+			offset: offset,
+			length: next + 4 - offset,
+		})
+		offset = next + 4
+	}
+	for i := 0; i < int(d.Header.Answers); i++ {
+		name, next, ok := readDnsName(rawData, offset)
+		if !ok || next+10 > len(rawData) {
+			d.Malformed = fmt.Sprintf("answer %d is truncated", i+1)
+			return d
+		}
+		record := DnsRecord{
+			Name:   name,
+			Type:   binary.BigEndian.Uint16(rawData[next:]),
+			Class:  binary.BigEndian.Uint16(rawData[next+2:]),
+			Ttl:    binary.BigEndian.Uint32(rawData[next+4:]),
+			offset: offset,
+		}
+		dataLength := int(binary.BigEndian.Uint16(rawData[next+8:]))
+		start := next + 10
+		if start+dataLength > len(rawData) {
+			d.Malformed = fmt.Sprintf("answer %d is truncated", i+1)
+			return d
+		}
+		record.Data = dnsRecordData(rawData, record.Type, start, dataLength)
+		record.length = start + dataLength - offset
+		d.Answers = append(d.Answers, record)
+		offset = start + dataLength
+	}
+	return d
+}
+
+// readDnsName reads a possibly compressed name at offset and returns it
+// with the offset just past it.
+func readDnsName(message []byte, offset int) (string, int, bool) {
+	var labels []string
+	end := -1
+	// Every pointer must go backwards, which also rules out loops.
+	limit := offset
+	for {
+		if offset >= len(message) {
+			return "", 0, false
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			if len(labels) == 0 {
+				return ".", end, true
+			}
+			return strings.Join(labels, "."), end, true
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(message) {
+				return "", 0, false
+			}
+			pointer := int(binary.BigEndian.Uint16(message[offset:]) & 0x3fff)
+			if pointer >= limit {
+				return "", 0, false
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset, limit = pointer, pointer
+		case length&0xc0 != 0:
+			return "", 0, false
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, false
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+func dnsRecordData(message []byte, recordType uint16, start int, length int) string {
+	data := message[start : start+length]
+	switch recordType {
+	case 1:
+		if length == net.IPv4len {
+			return net.IP(data).String()
+		}
+	case 28:
+		if length == net.IPv6len {
+			return net.IP(data).String()
+		}
+	case 2, 5, 12:
+		if name, _, ok := readDnsName(message, start); ok {
+			return name
+		}
+	case 15:
+		if length > 2 {
+			if name, _, ok := readDnsName(message, start+2); ok {
+				return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(data), name)
+			}
+		}
+	}
+	return fmt.Sprintf("(%d bytes)", length)
+}
+
+func (h DnsHeader) kind() string {
+	if h.Response() {
+		return "response"
+	}
+	return "query"
+}
+
+func (d DnsPacket) Summary() string {
+	result := fmt.Sprintf("Standard %s 0x%04x", d.Header.kind(), d.Header.Id)
+	if d.Header.Response() && d.Header.Rcode() != 0 {
+		result += " " + d.Header.RcodeName()
+	}
+	for _, q := range d.Questions {
+		result += fmt.Sprintf(" %s %s", dnsTypeName(q.Type), q.Name)
+	}
+	for _, a := range d.Answers {
+		result += fmt.Sprintf(" %s %s", dnsTypeName(a.Type), a.Data)
+	}
+	return result
+}
+
+func (d DnsPacket) ToString() string {
+	h := d.Header
+	result := fmt.Sprintf("DNS Message [%d byte] - Id 0x%04x - Response %t - Opcode %d - Rcode %s ", d.Length, h.Id, h.Response(), h.Opcode(), h.RcodeName()) +
+		fmt.Sprintf("- Questions %d - Answers %d - Authority %d - Additional %d ", h.Questions, h.Answers, h.Authorities, h.Additionals)
+	for _, q := range d.Questions {
+		result += fmt.Sprintf("- Question: %s %s ", q.Name, dnsTypeName(q.Type))
+	}
+	for _, a := range d.Answers {
+		result += fmt.Sprintf("- Answer: %s ", a.ToString())
+	}
+	if d.Malformed != "" {
+		result += fmt.Sprintf("- Malformed: %s ", d.Malformed)
+	}
+	return result
+}
+
+func (d DnsPacket) fields(base int) Field {
+	h := d.Header
+	l := newLayerFields("dns", "Domain Name System ("+h.kind()+")", base, d.Length)
+	if d.Length < DnsHeaderSize {
+		return l.layer
+	}
+	l.add("dns.id", fmt.Sprintf("Transaction ID: 0x%04x", h.Id), DnsIdOffset, 2)
+	l.addBits("dns.flags.response", fmt.Sprintf("Response: %t", h.Response()), DnsFlagsOffset, 2, 0, 1)
+	l.addBits("dns.flags.opcode", fmt.Sprintf("Opcode: %d", h.Opcode()), DnsFlagsOffset, 2, 1, 4)
+	l.addBits("dns.flags.rcode", "Reply code: "+h.RcodeName(), DnsFlagsOffset, 2, 12, 4)
+	l.add("dns.count.queries", fmt.Sprintf("Questions: %d", h.Questions), DnsQuestionsOffset, 2)
+	l.add("dns.count.answers", fmt.Sprintf("Answer RRs: %d", h.Answers), DnsAnswersOffset, 2)
+	l.add("dns.count.auth_rr", fmt.Sprintf("Authority RRs: %d", h.Authorities), DnsAuthorityOffset, 2)
+	l.add("dns.count.add_rr", fmt.Sprintf("Additional RRs: %d", h.Additionals), DnsAdditionalOffset, 2)
+	for _, q := range d.Questions {
+		l.add("dns.qry", fmt.Sprintf("Query: %s type %s", q.Name, dnsTypeName(q.Type)), q.offset, q.length)
+	}
+	for _, a := range d.Answers {
+		l.add("dns.resp", "Answer: "+a.ToString(), a.offset, a.length)
+	}
+	return l.layer
+}
@@ -1,6 +1,7 @@
 package packet
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sniffer/application/protocol"
 )
@@ -68,7 +69,12 @@ type EthernetHeader struct {
 	Type        EtherType
 }
 
+// parse returns nil when rawData is shorter than the header, as do the
+// parse methods of the other layers.
 func (e EthernetPacket) parse(rawData []byte) Parsable {
+	if len(rawData) < HeaderLength {
+		return nil
+	}
 	rawDataHeader := rawData[0:HeaderLength]
 	ethernetHeader := parseHeader(rawDataHeader)
 	//TODO: check padding
@@ -91,6 +97,7 @@ func (e EthernetPacket) parse(rawData []byte) Parsable {
 		CanParseMore: canParseMore,
 		RawHeader:    rawData[0:HeaderLength],
 		RawPayload:   rawData[HeaderLength:],
+		HeaderLength: HeaderLength,
 	}
 
 	ethernetPacket.Packet = basePacket
@@ -99,8 +106,6 @@ func (e EthernetPacket) parse(rawData []byte) Parsable {
 	if canParseMore {
 		etherType := ethernetPacket.Header.Type
 
-		fmt.Println(etherType)
-
 		//switch {
 		//case etherType.Name == IPV4.Name:
 		//	ethernetPacket.PacketParser = ParseFactoryMethod(basePacket.RawPayload, protocol.IpV4)
@@ -117,6 +122,7 @@ func (e EthernetPacket) parse(rawData []byte) Parsable {
 		} else if etherType.Name == ARP.Name {
 			ethernetPacket.PacketParser = ParseFactoryMethod(basePacket.RawPayload, protocol.Arp)
 		}
+		ethernetPacket.CanParseMore = ethernetPacket.PacketParser != nil
 
 	}
 
@@ -151,3 +157,20 @@ func ParseEthernet(rawData []byte) Parsable {
 
 	return ep.parse(rawData)
 }
+
+func (e EthernetPacket) fields(base int) Field {
+	l := newLayerFields("eth", "Ethernet II", base, HeaderLength)
+	l.add("eth.dst", "Destination: "+e.Header.DestMacAddr.ToString(), DestMacOffset, DestMacSize)
+	l.add("eth.src", "Source: "+e.Header.SrcMacAddr.ToString(), SrcMacOffset, SrcMacSize)
+	l.add("eth.type", fmt.Sprintf("Type: %s (0x%04x)", e.Header.Type.Name, e.Header.Type.Value), TypeOffset, TypeSize)
+	return l.layer
+}
+
+// Serialize writes the header back into its 14 wire bytes.
+func (h EthernetHeader) Serialize() []byte {
+	result := make([]byte, HeaderLength)
+	copy(result[DestMacOffset:DestMacOffset+DestMacSize], h.DestMacAddr.Value)
+	copy(result[SrcMacOffset:SrcMacOffset+SrcMacSize], h.SrcMacAddr.Value)
+	binary.BigEndian.PutUint16(result[TypeOffset:], h.Type.Value)
+	return result
+}
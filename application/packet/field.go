@@ -0,0 +1,210 @@
+package packet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field records where one decoded value sits in the captured frame. Offset
+// and Length are absolute byte positions. Fields narrower than their bytes,
+// such as the IPv4 header length or the TCP flags, also carry a bit range
+// counted from the most significant bit of the first byte.
+type Field struct {
+	Name      string
+	Label     string
+	Offset    int
+	Length    int
+	BitOffset int
+	BitLength int
+	Children  []Field
+}
+
+func (f Field) End() int {
+	return f.Offset + f.Length
+}
+
+func (f Field) ToString() string {
+	if f.BitLength > 0 {
+		return fmt.Sprintf("%s [%d:%d bits %d-%d]", f.Label, f.Offset, f.End(), f.BitOffset, f.BitOffset+f.BitLength-1)
+	}
+	return fmt.Sprintf("%s [%d:%d]", f.Label, f.Offset, f.End())
+}
+
+// dissectable is implemented by every layer that can describe its fields.
+// base is the absolute frame offset of the layer's first byte.
+type dissectable interface {
+	fields(base int) Field
+}
+
+// Dissect returns one field per decoded layer, each holding the fields of
+// its header, followed by whatever payload the last layer left undecoded.
+func Dissect(frame Parsable) []Field {
+	var result []Field
+	base := 0
+	layer := frame
+	for layer != nil {
+		d, ok := layer.(dissectable)
+		if !ok {
+			break
+		}
+		field := d.fields(base)
+		result = append(result, field)
+
+		p := packetOf(layer)
+		base += p.HeaderLength
+		if !p.CanParseMore || p.PacketParser == nil {
+			if len(p.RawPayload) > 0 {
+				result = append(result, Field{
+					Name:   "data",
+					Label:  fmt.Sprintf("Data (%d bytes)", len(p.RawPayload)),
+					Offset: base,
+					Length: len(p.RawPayload),
+				})
+			}
+			break
+		}
+		layer = p.PacketParser
+	}
+	return result
+}
+
+func packetOf(layer Parsable) Packet {
+	switch l := layer.(type) {
+	case EthernetPacket:
+		return l.Packet
+	case ArpPacket:
+		return l.Packet
+	case Ipv4Packet:
+		return l.Packet
+	case TcpPacket:
+		return l.Packet
+	case UdpPacket:
+		return l.Packet
+	case IcmpV4Packet:
+		return l.Packet
+	case DnsPacket:
+		return l.Packet
+	case DhcpPacket:
+		return l.Packet
+	case NtpPacket:
+		return l.Packet
+	case SnmpPacket:
+		return l.Packet
+	case SyslogPacket:
+		return l.Packet
+	case QuicPacket:
+		return l.Packet
+	}
+	return Packet{}
+}
+
+// FindField looks a field up by name, e.g. "ip.ttl", anywhere in the tree.
+func FindField(fields []Field, name string) (Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+		if found, ok := FindField(f.Children, name); ok {
+			return found, true
+		}
+	}
+	return Field{}, false
+}
+
+func FieldsToString(fields []Field) string {
+	var result strings.Builder
+	var walk func(fields []Field, depth int)
+	walk = func(fields []Field, depth int) {
+		for _, f := range fields {
+			result.WriteString(strings.Repeat("    ", depth))
+			result.WriteString(f.ToString())
+			result.WriteString("\n")
+			walk(f.Children, depth+1)
+		}
+	}
+	walk(fields, 0)
+	return result.String()
+}
+
+// Value reads the field back out of frame as an unsigned integer. Fields
+// longer than eight bytes, such as options or payload, cannot be read this way.
+func (f Field) Value(frame []byte) (uint64, error) {
+	if err := f.check(frame); err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range frame[f.Offset:f.End()] {
+		value = value<<8 | uint64(b)
+	}
+	if f.BitLength > 0 {
+		value >>= uint(f.Length*8 - f.BitOffset - f.BitLength)
+		value &= 1<<uint(f.BitLength) - 1
+	}
+	return value, nil
+}
+
+// Patch overwrites the field in frame, leaving the neighbouring bits of a
+// bit field untouched. Checksums are not recomputed.
+func (f Field) Patch(frame []byte, value uint64) error {
+	if err := f.check(frame); err != nil {
+		return err
+	}
+	width := f.Length * 8
+	if f.BitLength > 0 {
+		width = f.BitLength
+	}
+	if width < 64 && value>>uint(width) != 0 {
+		return fmt.Errorf("%s: value %d does not fit in %d bits", f.Name, value, width)
+	}
+
+	var current uint64
+	for _, b := range frame[f.Offset:f.End()] {
+		current = current<<8 | uint64(b)
+	}
+	if f.BitLength > 0 {
+		shift := uint(f.Length*8 - f.BitOffset - f.BitLength)
+		mask := (uint64(1)<<uint(f.BitLength) - 1) << shift
+		value = current&^mask | value<<shift
+	}
+	for i := f.End() - 1; i >= f.Offset; i-- {
+		frame[i] = byte(value)
+		value >>= 8
+	}
+	return nil
+}
+
+func (f Field) check(frame []byte) error {
+	if f.Length == 0 || f.Length > 8 {
+		return fmt.Errorf("%s: %d byte field is not a number", f.Name, f.Length)
+	}
+	if f.Offset < 0 || f.End() > len(frame) {
+		return fmt.Errorf("%s: bytes %d-%d are outside the %d byte frame", f.Name, f.Offset, f.End(), len(frame))
+	}
+	return nil
+}
+
+// layerFields collects the fields of one header relative to base.
+type layerFields struct {
+	layer Field
+	base  int
+}
+
+func newLayerFields(name string, label string, base int, length int) *layerFields {
+	return &layerFields{layer: Field{Name: name, Label: label, Offset: base, Length: length}, base: base}
+}
+
+func (l *layerFields) add(name string, label string, offset int, size int) {
+	l.layer.Children = append(l.layer.Children, Field{Name: name, Label: label, Offset: l.base + offset, Length: size})
+}
+
+// addBits adds a field occupying bitLength bits of the size bytes at offset.
+func (l *layerFields) addBits(name string, label string, offset int, size int, bitOffset int, bitLength int) {
+	l.layer.Children = append(l.layer.Children, Field{
+		Name:      name,
+		Label:     label,
+		Offset:    l.base + offset,
+		Length:    size,
+		BitOffset: bitOffset,
+		BitLength: bitLength,
+	})
+}
@@ -8,7 +8,7 @@ import (
 
 const (
 	IcmpV4TypeOffset     = 0
-	IcmpV4TypeSize       = 0
+	IcmpV4TypeSize       = 1
 	IcmpV4CodeOffset     = 1
 	IcmpV4CodeSize       = 1
 	IcmpV4ChecksumOffset = 2
@@ -185,6 +185,9 @@ func parseIcmpV4Header(rawData []byte) IcmpV4Header {
 }
 
 func (i IcmpV4Packet) parse(rawData []byte) Parsable {
+	if len(rawData) < IcmpV4HeaderSize {
+		return nil
+	}
 	header := parseIcmpV4Header(rawData[0:4])
 
 	return IcmpV4Packet{
@@ -209,3 +212,12 @@ func (i IcmpV4Packet) ToString() string {
 func ParseIcmpV4Packet(rawData []byte) Parsable {
 	return IcmpV4Packet{}.parse(rawData)
 }
+
+func (i IcmpV4Packet) fields(base int) Field {
+	h := i.Header
+	l := newLayerFields("icmp", "Internet Control Message Protocol", base, IcmpV4HeaderSize)
+	l.add("icmp.type", fmt.Sprintf("Type: %d (%s)", h.Type.Value, h.Type.Name), IcmpV4TypeOffset, IcmpV4TypeSize)
+	l.add("icmp.code", "Code: "+h.Detail.Name, IcmpV4CodeOffset, IcmpV4CodeSize)
+	l.add("icmp.checksum", fmt.Sprintf("Checksum: 0x%04x", h.Checksum), IcmpV4ChecksumOffset, IcmpV4ChecksumSize)
+	return l.layer
+}
@@ -1,6 +1,7 @@
 package packet
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sniffer/application/common"
 	"sniffer/application/protocol"
@@ -72,18 +73,19 @@ func ParseIpV4Packet(rawData []byte) Parsable {
 }
 
 func (i Ipv4Packet) parse(rawData []byte) Parsable {
-	header := parseIpV4Header(rawData)
-	canParseMore := false
-	if header.PayloadProtocol.PayloadProtocol.Name != "Unknown" {
-		canParseMore = true
+	if !validIpv4Header(rawData) {
+		return nil
 	}
+	header := parseIpV4Header(rawData)
+	canParseMore := header.canParseMore()
 
 
 
+	payload := rawData[header.Length:header.payloadEnd(len(rawData))]
 	ipV4Packet := Ipv4Packet{
 		Packet: Packet{
 			RawHeader:    rawData[0:header.Length],
-			RawPayload:   rawData[header.Length:],
+			RawPayload:   payload,
 			CanParseMore: canParseMore,
 			ProtocolName: "IpV4",
 			Length:       int(header.TotalLength) + header.Length,
@@ -106,18 +108,55 @@ func (i Ipv4Packet) parse(rawData []byte) Parsable {
 		//}
 
 		if header.PayloadProtocol.PayloadProtocol == protocol.IcmpV4{
-			ipV4Packet.PacketParser  = ParseFactoryMethod(rawData[ipV4Packet.HeaderLength:], protocol.IcmpV4)
+			ipV4Packet.PacketParser  = ParseFactoryMethod(payload, protocol.IcmpV4)
 		} else if header.PayloadProtocol.PayloadProtocol == protocol.Udp {
-			ipV4Packet.PacketParser  = ParseFactoryMethod(rawData[ipV4Packet.HeaderLength:], protocol.Udp)
+			ipV4Packet.PacketParser  = parseUdp(payload, ipV4Packet.payloadLength())
 		} else if header.PayloadProtocol.PayloadProtocol == protocol.Tcp {
-			ipV4Packet.PacketParser  = ParseFactoryMethod(rawData[ipV4Packet.HeaderLength:], protocol.Tcp)
+			ipV4Packet.PacketParser  = ParseFactoryMethod(payload, protocol.Tcp)
 		}
+		ipV4Packet.CanParseMore = ipV4Packet.PacketParser != nil
 
 	}
 
 	return ipV4Packet
 }
 
+// validIpv4Header reports whether rawData holds the whole header its IHL
+// claims.
+func validIpv4Header(rawData []byte) bool {
+	if len(rawData) < Ipv4MinHeaderSize {
+		return false
+	}
+	headerLength := int(rawData[Ipv4VersionAndIhlOffset]&0x0f) * 4
+	return headerLength >= Ipv4MinHeaderSize && headerLength <= len(rawData)
+}
+
+// payloadLength is the payload length the header claims, or -1 for a
+// fragment, whose payload is only part of the transport message.
+func (i Ipv4Packet) payloadLength() int {
+	if i.Header.MoreFragmentFlag || i.Header.FragmentOffset != 0 {
+		return -1
+	}
+	return int(i.Header.TotalLength) - i.Header.Length
+}
+
+// canParseMore is false for unknown protocols and for fragments after the
+// first, whose payload starts in the middle of the transport message.
+func (h Ipv4Header) canParseMore() bool {
+	return h.PayloadProtocol.PayloadProtocol.Name != "Unknown" && h.FragmentOffset == 0
+}
+
+// payloadEnd is where the payload ends in a packet of captured bytes. It
+// is TotalLength, which leaves out the padding of short Ethernet frames,
+// unless the capture was cut short or TotalLength is less than the header.
+func (h Ipv4Header) payloadEnd(captured int) int {
+	end := int(h.TotalLength)
+	if end < h.Length || end > captured {
+		return captured
+	}
+	return end
+}
+
 func parseIpV4Header(rawData []byte) Ipv4Header {
 	versionAndIhl := rawData[Ipv4VersionAndIhlOffset]
 	version := byte((versionAndIhl & 240) >> 4)
@@ -127,7 +166,7 @@ func parseIpV4Header(rawData []byte) Ipv4Header {
 	identification := common.GetUint16FromBytes(rawData[Ipv4IdentificationOffset : Ipv4IdentificationOffset+Ipv4IdentificationSize])
 	flagsAndFragment := common.GetUint16FromBytes(rawData[Ipv4FlagsAndFragmentOffset : Ipv4FlagsAndFragmentOffset+Ipv4FlagsAndFragmentSize])
 	reservedFlag := (int(flagsAndFragment) & 0x8000) != 0
-	dontFragmentFlag := (int(flagsAndFragment) & 4000) != 0
+	dontFragmentFlag := (int(flagsAndFragment) & 0x4000) != 0
 	moreFragmentFlag := (int(flagsAndFragment) & 8192) != 0
 	fragmentOffset := int(flagsAndFragment) & 8191
 	ttl := rawData[Ipv4TtlOffset]
@@ -188,9 +227,62 @@ func (i Ipv4Packet) ToString() string {
 
 	if i.CanParseMore {
 		result += fmt.Sprintf("\n")
-		result += i.PacketParser.parse(i.RawPayload).ToString()
+		result += i.PacketParser.ToString()
 	}
 
 	return result
 
 }
+
+func (i Ipv4Packet) fields(base int) Field {
+	h := i.Header
+	l := newLayerFields("ip", fmt.Sprintf("Internet Protocol Version 4, Src: %s, Dst: %s", h.SourceAddress.ToString(), h.DestinationAddress.ToString()), base, h.Length)
+	l.addBits("ip.version", fmt.Sprintf("Version: %d", h.Version), Ipv4VersionAndIhlOffset, Ipv4VersionAndIhlSize, 0, 4)
+	l.addBits("ip.hdr_len", fmt.Sprintf("Header length: %d bytes (%d)", h.Length, h.Ihl), Ipv4VersionAndIhlOffset, Ipv4VersionAndIhlSize, 4, 4)
+	l.add("ip.tos", fmt.Sprintf("Type of service: 0x%02x", h.Tos), Ipv4TosOffset, Ipv4TosSize)
+	l.add("ip.len", fmt.Sprintf("Total length: %d", h.TotalLength), Ipv4TotalLengthOffset, Ipv4TotalLengthSize)
+	l.add("ip.id", fmt.Sprintf("Identification: 0x%04x (%d)", h.Identification, h.Identification), Ipv4IdentificationOffset, Ipv4IdentificationSize)
+	l.addBits("ip.flags.rb", fmt.Sprintf("Reserved bit: %t", h.ReservedFlag), Ipv4FlagsAndFragmentOffset, Ipv4FlagsAndFragmentSize, 0, 1)
+	l.addBits("ip.flags.df", fmt.Sprintf("Don't fragment: %t", h.DontFragmentFlag), Ipv4FlagsAndFragmentOffset, Ipv4FlagsAndFragmentSize, 1, 1)
+	l.addBits("ip.flags.mf", fmt.Sprintf("More fragments: %t", h.MoreFragmentFlag), Ipv4FlagsAndFragmentOffset, Ipv4FlagsAndFragmentSize, 2, 1)
+	l.addBits("ip.frag_offset", fmt.Sprintf("Fragment offset: %d", h.FragmentOffset), Ipv4FlagsAndFragmentOffset, Ipv4FlagsAndFragmentSize, 3, 13)
+	l.add("ip.ttl", fmt.Sprintf("Time to live: %d", h.Ttl), Ipv4TtlOffset, Ipv4TtlSize)
+	l.add("ip.proto", fmt.Sprintf("Protocol: %s (%d)", h.PayloadProtocol.PayloadProtocol.Name, h.PayloadProtocol.Value), Ipv4ProtocolOffset, Ipv4ProtocolSize)
+	l.add("ip.checksum", fmt.Sprintf("Header checksum: 0x%04x", h.HeaderChecksum), Ipv4HeaderChecksumOffset, Ipv4HeaderChecksumSize)
+	l.add("ip.src", "Source address: "+h.SourceAddress.ToString(), Ipv4SourceAddressOffset, Ipv4SourceAddressSize)
+	l.add("ip.dst", "Destination address: "+h.DestinationAddress.ToString(), Ipv4DestAddressOffset, Ipv4DestAddressSize)
+	if len(h.Options) > 0 {
+		l.add("ip.options", fmt.Sprintf("Options (%d bytes)", len(h.Options)), Ipv4OptionsOffset, len(h.Options))
+	}
+	return l.layer
+}
+
+// Serialize writes the header back into wire format. The header length is
+// taken from Options and the checksum is recomputed, so fields may be edited
+// freely before serializing.
+func (h Ipv4Header) Serialize() []byte {
+	length := Ipv4MinHeaderSize + len(h.Options)
+	result := make([]byte, length)
+	result[Ipv4VersionAndIhlOffset] = h.Version<<4 | byte(length/4)
+	result[Ipv4TosOffset] = h.Tos
+	binary.BigEndian.PutUint16(result[Ipv4TotalLengthOffset:], h.TotalLength)
+	binary.BigEndian.PutUint16(result[Ipv4IdentificationOffset:], h.Identification)
+	flagsAndFragment := h.FragmentOffset & 0x1fff
+	if h.ReservedFlag {
+		flagsAndFragment |= 0x8000
+	}
+	if h.DontFragmentFlag {
+		flagsAndFragment |= 0x4000
+	}
+	if h.MoreFragmentFlag {
+		flagsAndFragment |= 0x2000
+	}
+	binary.BigEndian.PutUint16(result[Ipv4FlagsAndFragmentOffset:], flagsAndFragment)
+	result[Ipv4TtlOffset] = h.Ttl
+	result[Ipv4ProtocolOffset] = h.PayloadProtocol.Value
+	copy(result[Ipv4SourceAddressOffset:Ipv4SourceAddressOffset+Ipv4SourceAddressSize], h.SourceAddress.Value)
+	copy(result[Ipv4DestAddressOffset:Ipv4DestAddressOffset+Ipv4DestAddressSize], h.DestinationAddress.Value)
+	copy(result[Ipv4OptionsOffset:], h.Options)
+	binary.BigEndian.PutUint16(result[Ipv4HeaderChecksumOffset:], InternetChecksum(result))
+	return result
+}
@@ -0,0 +1,241 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sniffer/application/protocol"
+	"strings"
+	"time"
+)
+
+const (
+	NtpFlagsOffset          = 0
+	NtpStratumOffset        = 1
+	NtpPollOffset           = 2
+	NtpPrecisionOffset      = 3
+	NtpRootDelayOffset      = 4
+	NtpRootDispersionOffset = 8
+	NtpReferenceIdOffset    = 12
+	NtpReferenceTimeOffset  = 16
+	NtpOriginTimeOffset     = 24
+	NtpReceiveTimeOffset    = 32
+	NtpTransmitTimeOffset   = 40
+	NtpHeaderSize           = 48
+)
+
+const (
+	NtpModeClient = 3
+	NtpModeServer = 4
+	// ntpMaxMacLength is the longest MAC, a key id and a SHA-1 digest. Any
+	// longer trailer of an NTPv4 message holds extension fields, RFC 7822.
+	ntpMaxMacLength             = 24
+	ntpMinExtensionLength       = 16
+	ntpUnixEpochOffset    int64 = 2208988800
+)
+
+var ntpModeNames = []string{"reserved", "symmetric active", "symmetric passive", "client", "server", "broadcast", "control", "private"}
+
+var ntpExtensionNames = map[uint16]string{
+	0x0104: "Unique Identifier", 0x0204: "NTS Cookie", 0x0304: "NTS Cookie Placeholder", 0x0404: "NTS Authenticator",
+}
+
+// NtpTimestamp is the 64 bit fixed point timestamp, seconds since 1900 in
+// the upper half.
+type NtpTimestamp uint64
+
+func (t NtpTimestamp) IsZero() bool {
+	return t == 0
+}
+
+// Time converts t, reading seconds with the high bit clear as era 1, which
+// starts in 2036, as RFC 4330 suggests.
+func (t NtpTimestamp) Time() time.Time {
+	seconds := int64(t >> 32)
+	if seconds < 0x80000000 {
+		seconds += 1 << 32
+	}
+	nanoseconds := (int64(t&0xffffffff)*int64(time.Second) + 1<<31) >> 32
+	return time.Unix(seconds-ntpUnixEpochOffset, nanoseconds).UTC()
+}
+
+func (t NtpTimestamp) ToString() string {
+	if t.IsZero() {
+		return "(unset)"
+	}
+	return t.Time().Format("2006-01-02 15:04:05.000000000 UTC")
+}
+
+// ntpShort converts the 16.16 fixed point format of root delay and dispersion.
+func ntpShort(value uint32) time.Duration {
+	return time.Duration((int64(value) * int64(time.Second)) >> 16)
+}
+
+type NtpHeader struct {
+	LeapIndicator  byte
+	Version        byte
+	Mode           byte
+	Stratum        byte
+	Poll           int8
+	Precision      int8
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+	ReferenceId    [4]byte
+	ReferenceTime  NtpTimestamp
+	OriginTime     NtpTimestamp
+	ReceiveTime    NtpTimestamp
+	TransmitTime   NtpTimestamp
+}
+
+func (h NtpHeader) ModeName() string {
+	return ntpModeNames[h.Mode&7]
+}
+
+// ReferenceIdToString shows the reference id as a kiss code for stratum 0,
+// a clock source for stratum 1 and an address otherwise.
+func (h NtpHeader) ReferenceIdToString() string {
+	if h.Stratum <= 1 {
+		return strings.TrimRight(string(h.ReferenceId[:]), "\x00")
+	}
+	return net.IP(h.ReferenceId[:]).String()
+}
+
+type NtpExtension struct {
+	Type uint16
+	Data []byte
+	// offset locates the field in the message.
+	offset int
+}
+
+func (e NtpExtension) ToString() string {
+	name, ok := ntpExtensionNames[e.Type]
+	if !ok {
+		name = fmt.Sprintf("Extension 0x%04x", e.Type)
+	}
+	return fmt.Sprintf("%s (%d bytes)", name, len(e.Data))
+}
+
+type NtpPacket struct {
+	Packet
+	Header     NtpHeader
+	Extensions []NtpExtension
+	// Mac is the key id and digest closing authenticated messages.
+	Mac       []byte
+	Malformed string
+}
+
+func ParseNtpPacket(rawData []byte) Parsable {
+	return NtpPacket{}.parse(rawData)
+}
+
+func (n NtpPacket) parse(rawData []byte) Parsable {
+	n = NtpPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Ntp.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	if len(rawData) < NtpHeaderSize {
+		n.Malformed = "message shorter than its header"
+		return n
+	}
+	flags := rawData[NtpFlagsOffset]
+	n.Header = NtpHeader{
+		LeapIndicator:  flags >> 6,
+		Version:        (flags >> 3) & 7,
+		Mode:           flags & 7,
+		Stratum:        rawData[NtpStratumOffset],
+		Poll:           int8(rawData[NtpPollOffset]),
+		Precision:      int8(rawData[NtpPrecisionOffset]),
+		RootDelay:      ntpShort(binary.BigEndian.Uint32(rawData[NtpRootDelayOffset:])),
+		RootDispersion: ntpShort(binary.BigEndian.Uint32(rawData[NtpRootDispersionOffset:])),
+		ReferenceTime:  NtpTimestamp(binary.BigEndian.Uint64(rawData[NtpReferenceTimeOffset:])),
+		OriginTime:     NtpTimestamp(binary.BigEndian.Uint64(rawData[NtpOriginTimeOffset:])),
+		ReceiveTime:    NtpTimestamp(binary.BigEndian.Uint64(rawData[NtpReceiveTimeOffset:])),
+		TransmitTime:   NtpTimestamp(binary.BigEndian.Uint64(rawData[NtpTransmitTimeOffset:])),
+	}
+	copy(n.Header.ReferenceId[:], rawData[NtpReferenceIdOffset:])
+
+	offset := NtpHeaderSize
+	for n.Header.Version >= 4 && len(rawData)-offset > ntpMaxMacLength {
+		length := int(binary.BigEndian.Uint16(rawData[offset+2:]))
+		if length < ntpMinExtensionLength || length%4 != 0 || offset+length > len(rawData) {
+			n.Malformed = fmt.Sprintf("extension field at byte %d has a bad length of %d", offset, length)
+			return n
+		}
+		n.Extensions = append(n.Extensions, NtpExtension{
+			Type:   binary.BigEndian.Uint16(rawData[offset:]),
+			Data:   rawData[offset+4 : offset+length],
+			offset: offset,
+		})
+		offset += length
+	}
+	if trailer := rawData[offset:]; len(trailer) >= 4 {
+		n.Mac = trailer
+	} else if len(trailer) != 0 {
+		n.Malformed = fmt.Sprintf("%d trailing bytes", len(trailer))
+	}
+	return n
+}
+
+func (n NtpPacket) Summary() string {
+	if n.Malformed != "" {
+		return "Malformed: " + n.Malformed
+	}
+	h := n.Header
+	result := fmt.Sprintf("NTP Version %d, %s", h.Version, h.ModeName())
+	if h.Mode == NtpModeServer && h.Stratum == 0 {
+		result += ", kiss code " + h.ReferenceIdToString()
+	}
+	return result
+}
+
+func (n NtpPacket) ToString() string {
+	if n.Length < NtpHeaderSize {
+		return fmt.Sprintf("NTP Message [%d byte] - Malformed: %s ", n.Length, n.Malformed)
+	}
+	h := n.Header
+	result := fmt.Sprintf("NTP Message [%d byte] - Leap %d - Version %d - Mode %s - Stratum %d - Poll %d - Precision %d ",
+		n.Length, h.LeapIndicator, h.Version, h.ModeName(), h.Stratum, h.Poll, h.Precision) +
+		fmt.Sprintf("- Root delay %s - Root dispersion %s - Reference id %s ", h.RootDelay, h.RootDispersion, h.ReferenceIdToString()) +
+		fmt.Sprintf("- Reference %s - Origin %s - Receive %s - Transmit %s ",
+			h.ReferenceTime.ToString(), h.OriginTime.ToString(), h.ReceiveTime.ToString(), h.TransmitTime.ToString())
+	for _, e := range n.Extensions {
+		result += fmt.Sprintf("- %s ", e.ToString())
+	}
+	if len(n.Mac) != 0 {
+		result += fmt.Sprintf("- MAC %d byte ", len(n.Mac))
+	}
+	if n.Malformed != "" {
+		result += fmt.Sprintf("- Malformed: %s ", n.Malformed)
+	}
+	return result
+}
+
+func (n NtpPacket) fields(base int) Field {
+	h := n.Header
+	l := newLayerFields("ntp", "Network Time Protocol ("+h.ModeName()+")", base, n.Length)
+	if n.Length < NtpHeaderSize {
+		return l.layer
+	}
+	l.addBits("ntp.flags.li", fmt.Sprintf("Leap indicator: %d", h.LeapIndicator), NtpFlagsOffset, 1, 0, 2)
+	l.addBits("ntp.flags.vn", fmt.Sprintf("Version: %d", h.Version), NtpFlagsOffset, 1, 2, 3)
+	l.addBits("ntp.flags.mode", "Mode: "+h.ModeName(), NtpFlagsOffset, 1, 5, 3)
+	l.add("ntp.stratum", fmt.Sprintf("Stratum: %d", h.Stratum), NtpStratumOffset, 1)
+	l.add("ntp.ppoll", fmt.Sprintf("Poll: %d", h.Poll), NtpPollOffset, 1)
+	l.add("ntp.precision", fmt.Sprintf("Precision: %d", h.Precision), NtpPrecisionOffset, 1)
+	l.add("ntp.rootdelay", "Root delay: "+h.RootDelay.String(), NtpRootDelayOffset, 4)
+	l.add("ntp.rootdispersion", "Root dispersion: "+h.RootDispersion.String(), NtpRootDispersionOffset, 4)
+	l.add("ntp.refid", "Reference ID: "+h.ReferenceIdToString(), NtpReferenceIdOffset, 4)
+	l.add("ntp.reftime", "Reference timestamp: "+h.ReferenceTime.ToString(), NtpReferenceTimeOffset, 8)
+	l.add("ntp.org", "Origin timestamp: "+h.OriginTime.ToString(), NtpOriginTimeOffset, 8)
+	l.add("ntp.rec", "Receive timestamp: "+h.ReceiveTime.ToString(), NtpReceiveTimeOffset, 8)
+	l.add("ntp.xmt", "Transmit timestamp: "+h.TransmitTime.ToString(), NtpTransmitTimeOffset, 8)
+	for _, e := range n.Extensions {
+		l.add("ntp.ext", e.ToString(), e.offset, 4+len(e.Data))
+	}
+	if len(n.Mac) != 0 {
+		l.add("ntp.mac", fmt.Sprintf("MAC: %d bytes", len(n.Mac)), n.Length-len(n.Mac), len(n.Mac))
+	}
+	return l.layer
+}
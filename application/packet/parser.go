@@ -34,6 +34,9 @@ func ParseFactoryMethod(rawData []byte, p protocol.Protocol) Parsable {
 		return ParseSshPacket(rawData)
 
 	default:
+		if d, ok := udpDecoderFor(p); ok {
+			return d.Parse(rawData)
+		}
 		panic("protocol not supported!!!")
 
 	}
@@ -0,0 +1,144 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sniffer/application/common"
+	"sniffer/application/protocol"
+)
+
+const (
+	QuicVersion1     = 0x00000001
+	QuicVersion2     = 0x6b3343cf
+	quicLongHeader   = 0x80
+	quicFixedBit     = 0x40
+	quicMaxIdLength  = 20
+	quicVersionStart = 1
+)
+
+// QuicPacket reads the invariant parts of the first QUIC packet in a
+// datagram. Everything past the header is encrypted.
+type QuicPacket struct {
+	Packet
+	LongHeader bool
+	// Version is 0 for version negotiation and for short header packets.
+	Version       uint32
+	DestinationId []byte
+	SourceId      []byte
+	PacketType    string
+	Malformed     string
+}
+
+func isQuic(payload []byte) bool {
+	if len(payload) < 7 || payload[0]&quicLongHeader == 0 {
+		return false
+	}
+	version := binary.BigEndian.Uint32(payload[quicVersionStart:])
+	known := version == QuicVersion1 || version == QuicVersion2 || version&0xffffff00 == 0xff000000
+	return known && payload[0]&quicFixedBit != 0 && payload[5] <= quicMaxIdLength
+}
+
+func ParseQuicPacket(rawData []byte) Parsable {
+	return QuicPacket{}.parse(rawData)
+}
+
+func (q QuicPacket) parse(rawData []byte) Parsable {
+	q = QuicPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Quic.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	if len(rawData) == 0 {
+		q.Malformed = "empty datagram"
+		return q
+	}
+	if rawData[0]&quicLongHeader == 0 {
+		// The connection id length of short headers is only known to the
+		// endpoints.
+		q.PacketType = "1-RTT"
+		return q
+	}
+	q.LongHeader = true
+	if len(rawData) < 6 {
+		q.Malformed = "long header is truncated"
+		return q
+	}
+	q.Version = binary.BigEndian.Uint32(rawData[quicVersionStart:])
+	offset := 5
+	for _, id := range []*[]byte{&q.DestinationId, &q.SourceId} {
+		if offset >= len(rawData) || offset+1+int(rawData[offset]) > len(rawData) {
+			q.Malformed = "connection id is truncated"
+			return q
+		}
+		*id = rawData[offset+1 : offset+1+int(rawData[offset])]
+		offset += 1 + len(*id)
+	}
+	q.PacketType = quicPacketType(q.Version, (rawData[0]>>4)&3)
+	return q
+}
+
+func quicPacketType(version uint32, bits byte) string {
+	if version == 0 {
+		return "Version Negotiation"
+	}
+	types := [4]string{"Initial", "0-RTT", "Handshake", "Retry"}
+	if version == QuicVersion2 {
+		// QUIC v2 shuffles the type bits, RFC 9369.
+		types = [4]string{"Retry", "Initial", "0-RTT", "Handshake"}
+	}
+	return types[bits]
+}
+
+func (q QuicPacket) versionName() string {
+	switch {
+	case q.Version == QuicVersion1:
+		return "1"
+	case q.Version == QuicVersion2:
+		return "2"
+	case q.Version&0xffffff00 == 0xff000000:
+		return fmt.Sprintf("draft-%d", q.Version&0xff)
+	}
+	return fmt.Sprintf("0x%08x", q.Version)
+}
+
+func (q QuicPacket) Summary() string {
+	if q.Malformed != "" {
+		return "Malformed: " + q.Malformed
+	}
+	if !q.LongHeader {
+		return "Protected Payload (1-RTT)"
+	}
+	return fmt.Sprintf("%s, version %s, DCID=%x", q.PacketType, q.versionName(), q.DestinationId)
+}
+
+func (q QuicPacket) ToString() string {
+	result := fmt.Sprintf("QUIC Packet [%d byte] ", q.Length)
+	if q.LongHeader && q.Malformed == "" {
+		result += fmt.Sprintf("- %s - Version %s - Destination connection id %s- Source connection id %s",
+			q.PacketType, q.versionName(), common.ByteSliceToString(q.DestinationId), common.ByteSliceToString(q.SourceId))
+	} else if q.Malformed == "" {
+		result += "- Short header, protected payload "
+	}
+	if q.Malformed != "" {
+		result += fmt.Sprintf("- Malformed: %s ", q.Malformed)
+	}
+	return result
+}
+
+func (q QuicPacket) fields(base int) Field {
+	l := newLayerFields("quic", "QUIC IETF: "+q.Summary(), base, q.Length)
+	if q.Malformed != "" {
+		return l.layer
+	}
+	if !q.LongHeader {
+		l.addBits("quic.header_form", "Header form: short", 0, 1, 0, 1)
+		return l.layer
+	}
+	l.addBits("quic.header_form", "Header form: long", 0, 1, 0, 1)
+	l.addBits("quic.long.packet_type", "Packet type: "+q.PacketType, 0, 1, 2, 2)
+	l.add("quic.version", "Version: "+q.versionName(), quicVersionStart, 4)
+	l.add("quic.dcid", fmt.Sprintf("Destination connection id: %x", q.DestinationId), 6, len(q.DestinationId))
+	l.add("quic.scid", fmt.Sprintf("Source connection id: %x", q.SourceId), 7+len(q.DestinationId), len(q.SourceId))
+	return l.layer
+}
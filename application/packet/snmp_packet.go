@@ -0,0 +1,267 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"sniffer/application/protocol"
+	"strconv"
+)
+
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berObjectId    = 0x06
+	berSequence    = 0x30
+)
+
+var snmpPduNames = map[byte]string{
+	0xa0: "get-request", 0xa1: "get-next-request", 0xa2: "get-response", 0xa3: "set-request",
+	0xa4: "trap", 0xa5: "getBulkRequest", 0xa6: "informRequest", 0xa7: "snmpV2-trap", 0xa8: "report",
+}
+
+var errBer = errors.New("malformed BER encoding")
+
+// readBer splits the TLV at the start of data. Only definite lengths are
+// allowed, as SNMP requires.
+func readBer(data []byte) (tag byte, value []byte, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errBer
+	}
+	tag = data[0]
+	length := int(data[1])
+	header := 2
+	if length&0x80 != 0 {
+		count := length & 0x7f
+		if count == 0 || count > 3 || len(data) < 2+count {
+			return 0, nil, nil, errBer
+		}
+		length = 0
+		for _, b := range data[2 : 2+count] {
+			length = length<<8 | int(b)
+		}
+		header += count
+	}
+	if len(data) < header+length {
+		return 0, nil, nil, errBer
+	}
+	return tag, data[header : header+length], data[header+length:], nil
+}
+
+func berInt(value []byte) int64 {
+	var result int64
+	for i, b := range value {
+		if i == 0 && b&0x80 != 0 {
+			result = -1
+		}
+		result = result<<8 | int64(b)
+	}
+	return result
+}
+
+func berOid(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+	result := strconv.Itoa(int(value[0])/40) + "." + strconv.Itoa(int(value[0])%40)
+	var arc uint64
+	for _, b := range value[1:] {
+		arc = arc<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			result += "." + strconv.FormatUint(arc, 10)
+			arc = 0
+		}
+	}
+	return result
+}
+
+// SnmpPacket decodes SNMPv1 and v2c messages. Of SNMPv3, whose PDU is
+// usually encrypted, only the version and message id are read.
+type SnmpPacket struct {
+	Packet
+	// Version is the version field, 0 for SNMPv1, 1 for v2c and 3 for v3.
+	Version   int64
+	Community string
+	PduType   byte
+	RequestId int64
+	// ErrorStatus is 0 for trap PDUs, which have no such field.
+	ErrorStatus int64
+	// Oids are the object identifiers of the variable bindings.
+	Oids      []string
+	Malformed string
+	// Where the values sit in the message, for fields.
+	versionAt   span
+	communityAt span
+	pduAt       span
+	oidsAt      []span
+}
+
+// span locates bytes of a message.
+type span struct {
+	offset int
+	length int
+}
+
+// spanOf locates part, a slice of message.
+func spanOf(message []byte, part []byte) span {
+	return span{offset: cap(message) - cap(part), length: len(part)}
+}
+
+func ParseSnmpPacket(rawData []byte) Parsable {
+	return SnmpPacket{}.parse(rawData)
+}
+
+func (s SnmpPacket) parse(rawData []byte) Parsable {
+	s = SnmpPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Snmp.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	if err := s.decode(rawData); err != nil {
+		s.Malformed = err.Error()
+	}
+	return s
+}
+
+func (s *SnmpPacket) decode(data []byte) error {
+	tag, message, _, err := readBer(data)
+	if err != nil || tag != berSequence {
+		return errBer
+	}
+	tag, version, message, err := readBer(message)
+	if err != nil || tag != berInteger {
+		return errBer
+	}
+	s.Version = berInt(version)
+	s.versionAt = spanOf(data, version)
+	if s.Version == 3 {
+		tag, global, _, err := readBer(message)
+		if err != nil || tag != berSequence {
+			return errBer
+		}
+		tag, id, _, err := readBer(global)
+		if err != nil || tag != berInteger {
+			return errBer
+		}
+		s.RequestId = berInt(id)
+		return nil
+	}
+
+	tag, community, message, err := readBer(message)
+	if err != nil || tag != berOctetString {
+		return errBer
+	}
+	s.Community = string(community)
+	s.communityAt = spanOf(data, community)
+	tag, pdu, _, err := readBer(message)
+	if err != nil {
+		return err
+	}
+	s.PduType = tag
+	s.pduAt = spanOf(data, pdu)
+	if tag == 0xa4 {
+		// The SNMPv1 trap PDU has a layout of its own; skip to its bindings.
+		for i := 0; i < 5 && err == nil; i++ {
+			_, _, pdu, err = readBer(pdu)
+		}
+	} else {
+		var value []byte
+		if tag, value, pdu, err = readBer(pdu); err != nil || tag != berInteger {
+			return errBer
+		}
+		s.RequestId = berInt(value)
+		if tag, value, pdu, err = readBer(pdu); err != nil || tag != berInteger {
+			return errBer
+		}
+		s.ErrorStatus = berInt(value)
+		if _, _, pdu, err = readBer(pdu); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	tag, bindings, _, err := readBer(pdu)
+	if err != nil || tag != berSequence {
+		return errBer
+	}
+	for len(bindings) > 0 {
+		var binding []byte
+		if _, binding, bindings, err = readBer(bindings); err != nil {
+			return err
+		}
+		tag, oid, _, err := readBer(binding)
+		if err != nil || tag != berObjectId {
+			return errBer
+		}
+		s.Oids = append(s.Oids, berOid(oid))
+		s.oidsAt = append(s.oidsAt, spanOf(data, oid))
+	}
+	return nil
+}
+
+func (s SnmpPacket) versionName() string {
+	switch s.Version {
+	case 0:
+		return "v1"
+	case 1:
+		return "v2c"
+	}
+	return "v" + strconv.FormatInt(s.Version, 10)
+}
+
+func (s SnmpPacket) pduName() string {
+	if name, ok := snmpPduNames[s.PduType]; ok {
+		return name
+	}
+	return fmt.Sprintf("PDU 0x%02x", s.PduType)
+}
+
+func (s SnmpPacket) Summary() string {
+	if s.Malformed != "" {
+		return "Malformed: " + s.Malformed
+	}
+	if s.Version == 3 {
+		return fmt.Sprintf("SNMPv3 message %d", s.RequestId)
+	}
+	result := s.pduName()
+	if s.PduType != 0xa4 {
+		result += " " + strconv.FormatInt(s.RequestId, 10)
+	}
+	for _, oid := range s.Oids {
+		result += " " + oid
+	}
+	return result
+}
+
+func (s SnmpPacket) ToString() string {
+	result := fmt.Sprintf("SNMP Message [%d byte] - Version %s ", s.Length, s.versionName())
+	if s.Version != 3 && s.PduType != 0 {
+		result += fmt.Sprintf("- Community %q - %s - Request id %d - Error status %d ", s.Community, s.pduName(), s.RequestId, s.ErrorStatus)
+		for _, oid := range s.Oids {
+			result += fmt.Sprintf("- Object %s ", oid)
+		}
+	} else if s.Version == 3 {
+		result += fmt.Sprintf("- Message id %d ", s.RequestId)
+	}
+	if s.Malformed != "" {
+		result += fmt.Sprintf("- Malformed: %s ", s.Malformed)
+	}
+	return result
+}
+
+func (s SnmpPacket) fields(base int) Field {
+	l := newLayerFields("snmp", "Simple Network Management Protocol", base, s.Length)
+	if s.Malformed != "" {
+		return l.layer
+	}
+	l.add("snmp.version", "Version: "+s.versionName(), s.versionAt.offset, s.versionAt.length)
+	if s.Version != 3 {
+		l.add("snmp.community", "Community: "+s.Community, s.communityAt.offset, s.communityAt.length)
+		l.add("snmp.data", "PDU: "+s.pduName(), s.pduAt.offset, s.pduAt.length)
+		for i, oid := range s.Oids {
+			l.add("snmp.name", "Object name: "+oid, s.oidsAt[i].offset, s.oidsAt[i].length)
+		}
+	}
+	return l.layer
+}
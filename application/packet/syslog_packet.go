@@ -0,0 +1,131 @@
+package packet
+
+import (
+	"fmt"
+	"sniffer/application/protocol"
+	"strconv"
+	"strings"
+)
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp",
+	"ntp", "security", "console", "solaris-cron", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// SyslogPacket is a BSD (RFC 3164) or RFC 5424 syslog message.
+type SyslogPacket struct {
+	Packet
+	Facility byte
+	Severity byte
+	// Version is 1 for RFC 5424 messages and 0 for BSD ones, whose header
+	// is left in Message.
+	Version   int
+	Timestamp string
+	Hostname  string
+	AppName   string
+	ProcId    string
+	MsgId     string
+	Message   string
+	Malformed string
+	// priorityLength is the length of "<PRI>".
+	priorityLength int
+}
+
+// syslogPriority reads "<PRI>" and returns PRI and the length of the tag.
+func syslogPriority(payload []byte) (int, int, bool) {
+	if len(payload) < 3 || payload[0] != '<' {
+		return 0, 0, false
+	}
+	priority := 0
+	for i := 1; i < len(payload) && i <= 4; i++ {
+		c := payload[i]
+		if c == '>' && i > 1 {
+			return priority, i + 1, priority <= 191
+		}
+		if c < '0' || c > '9' {
+			return 0, 0, false
+		}
+		priority = priority*10 + int(c-'0')
+	}
+	return 0, 0, false
+}
+
+func isSyslog(payload []byte) bool {
+	_, _, ok := syslogPriority(payload)
+	return ok
+}
+
+func ParseSyslogPacket(rawData []byte) Parsable {
+	return SyslogPacket{}.parse(rawData)
+}
+
+func (s SyslogPacket) parse(rawData []byte) Parsable {
+	s = SyslogPacket{Packet: Packet{
+		RawHeader:    rawData,
+		ProtocolName: protocol.Syslog.Name,
+		Length:       len(rawData),
+		HeaderLength: len(rawData),
+	}}
+	priority, length, ok := syslogPriority(rawData)
+	if !ok {
+		s.Malformed = "no <PRI> at the start"
+		s.Message = string(rawData)
+		return s
+	}
+	s.Facility = byte(priority / 8)
+	s.Severity = byte(priority % 8)
+	s.priorityLength = length
+	rest := strings.TrimRight(string(rawData[length:]), "\x00\r\n")
+
+	// RFC 5424: VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP
+	// MSGID SP STRUCTURED-DATA [SP MSG]; "-" stands for a missing value.
+	if strings.HasPrefix(rest, "1 ") {
+		parts := strings.SplitN(rest, " ", 7)
+		if len(parts) == 7 {
+			s.Version = 1
+			s.Timestamp, s.Hostname, s.AppName, s.ProcId, s.MsgId = parts[1], parts[2], parts[3], parts[4], parts[5]
+			s.Message = parts[6]
+			return s
+		}
+	}
+	s.Message = rest
+	return s
+}
+
+func (s SyslogPacket) facilityName() string {
+	if int(s.Facility) < len(syslogFacilities) {
+		return syslogFacilities[s.Facility]
+	}
+	return strconv.Itoa(int(s.Facility))
+}
+
+func (s SyslogPacket) Summary() string {
+	if s.Malformed != "" {
+		return s.Message
+	}
+	return fmt.Sprintf("%s.%s: %s", s.facilityName(), syslogSeverities[s.Severity], s.Message)
+}
+
+func (s SyslogPacket) ToString() string {
+	if s.Malformed != "" {
+		return fmt.Sprintf("Syslog Message [%d byte] - Malformed: %s ", s.Length, s.Malformed)
+	}
+	result := fmt.Sprintf("Syslog Message [%d byte] - Facility %s - Severity %s ", s.Length, s.facilityName(), syslogSeverities[s.Severity])
+	if s.Version == 1 {
+		result += fmt.Sprintf("- Timestamp %s - Hostname %s - App %s - Proc %s - Msg id %s ", s.Timestamp, s.Hostname, s.AppName, s.ProcId, s.MsgId)
+	}
+	return result + fmt.Sprintf("- Message: %s ", s.Message)
+}
+
+func (s SyslogPacket) fields(base int) Field {
+	l := newLayerFields("syslog", "Syslog message: "+s.Summary(), base, s.Length)
+	if s.Malformed != "" {
+		return l.layer
+	}
+	l.add("syslog.facility", "Facility: "+s.facilityName(), 0, s.priorityLength)
+	l.add("syslog.level", "Level: "+syslogSeverities[s.Severity], 0, s.priorityLength)
+	l.add("syslog.msg", "Message: "+s.Message, s.priorityLength, s.Length-s.priorityLength)
+	return l.layer
+}
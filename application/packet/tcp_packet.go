@@ -68,6 +68,15 @@ var tcpOptionTable = []TcpOption{
 	{34, "TCP Fast Open Cookie"},
 }
 
+const (
+	TcpFlagFin = 1
+	TcpFlagSyn = 2
+	TcpFlagRst = 4
+	TcpFlagPsh = 8
+	TcpFlagAck = 16
+	TcpFlagUrg = 32
+)
+
 type TcpHeader struct {
 	SourcePort      uint16
 	DestinationPort uint16
@@ -89,6 +98,30 @@ type TcpHeader struct {
 	HeaderLength    int
 }
 
+// Flags packs the control bits back into their on-wire order (FIN is bit 0).
+func (h TcpHeader) Flags() byte {
+	var flags byte
+	if h.FIN {
+		flags |= TcpFlagFin
+	}
+	if h.SYN {
+		flags |= TcpFlagSyn
+	}
+	if h.RST {
+		flags |= TcpFlagRst
+	}
+	if h.PSH {
+		flags |= TcpFlagPsh
+	}
+	if h.ACK {
+		flags |= TcpFlagAck
+	}
+	if h.URG {
+		flags |= TcpFlagUrg
+	}
+	return flags
+}
+
 func parseTcpHeader(rawData []byte) TcpHeader {
 	srcPort := common.GetUint16FromBytes(rawData[TcpSourcePortOffset : TcpSourcePortOffset+TcpSourcePortSize])
 	destPort := common.GetUint16FromBytes(rawData[TcpDestinationPortOffset : TcpDestinationPortOffset+TcpDestinationPortSize])
@@ -136,9 +169,17 @@ type TcpPacket struct {
 	Header         TcpHeader
 	SourceProtocol string
 	DestProtocol   string
+	// Annotations are expert notes added by connection analysis, e.g. retransmissions.
+	Annotations []string
 }
 
 func (t TcpPacket) parse(rawData []byte) Parsable {
+	if len(rawData) < TcpMinHeaderSize {
+		return nil
+	}
+	if headerLength := int(rawData[TcpDataOffsetAndReservedBitsOffset]>>4) * 4; headerLength < TcpMinHeaderSize || headerLength > len(rawData) {
+		return nil
+	}
 	header := parseTcpHeader(rawData)
 
 	sourceProtocol := getProtocolBaseOnTcpPort(header.SourcePort)
@@ -173,7 +214,7 @@ func getProtocolBaseOnTcpPort(port uint16) string {
 }
 
 func (t TcpPacket) ToString() string {
-	return fmt.Sprintf("Tcp Packet [Header %d byte] - ", t.Header.HeaderLength)+
+	result := fmt.Sprintf("Tcp Packet [Header %d byte] - ", t.Header.HeaderLength)+
 		fmt.Sprintf(" source port %d [%s]- dest port %d [%s] ", t.Header.SourcePort, t.SourceProtocol, t.Header.DestinationPort, t.DestProtocol) +
 		fmt.Sprintf(" sequence number: %d - Ack number: %d ", t.Header.SequenceNumber, t.Header.AckNumber) +
 		fmt.Sprintf(" data offset: %d ", t.Header.DataOffset) +
@@ -185,8 +226,40 @@ func (t TcpPacket) ToString() string {
 		fmt.Sprintf(" header length: %d ", t.Header.HeaderLength) +
 		fmt.Sprintf(" options: %s ", common.ByteSliceToString(t.Header.RawOptions)) +
 		fmt.Sprintf(" Payload: %s ", common.ByteSliceToString(t.RawPayload))
+
+	for _, v := range t.Annotations {
+		result += fmt.Sprintf("[%s] ", v)
+	}
+
+	return result
 }
 
 func ParseTcpPacket(rawData []byte) Parsable {
 	return TcpPacket{}.parse(rawData)
 }
+
+func (t TcpPacket) fields(base int) Field {
+	h := t.Header
+	l := newLayerFields("tcp", fmt.Sprintf("Transmission Control Protocol, Src Port: %d, Dst Port: %d", h.SourcePort, h.DestinationPort), base, h.HeaderLength)
+	l.add("tcp.srcport", fmt.Sprintf("Source port: %d", h.SourcePort), TcpSourcePortOffset, TcpSourcePortSize)
+	l.add("tcp.dstport", fmt.Sprintf("Destination port: %d", h.DestinationPort), TcpDestinationPortOffset, TcpDestinationPortSize)
+	l.add("tcp.seq", fmt.Sprintf("Sequence number: %d", h.SequenceNumber), TcpSequenceNumberOffset, TcpSequenceNumberSize)
+	l.add("tcp.ack", fmt.Sprintf("Acknowledgment number: %d", h.AckNumber), TcpAckNumOffset, TcpAckNumSize)
+	l.addBits("tcp.hdr_len", fmt.Sprintf("Header length: %d bytes (%d)", h.HeaderLength, h.DataOffset), TcpDataOffsetAndReservedBitsOffset, TcpDataOffsetAndReservedBitsSize, 0, 4)
+	l.addBits("tcp.flags.res", fmt.Sprintf("Reserved: 0x%02x", h.Reserved), TcpDataOffsetAndReservedBitsOffset, TcpDataOffsetAndReservedBitsSize, 4, 6)
+	flags := []struct {
+		name  string
+		label string
+		set   bool
+	}{{"urg", "URG", h.URG}, {"ack", "ACK", h.ACK}, {"push", "PSH", h.PSH}, {"reset", "RST", h.RST}, {"syn", "SYN", h.SYN}, {"fin", "FIN", h.FIN}}
+	for i, flag := range flags {
+		l.addBits("tcp.flags."+flag.name, fmt.Sprintf("%s: %t", flag.label, flag.set), TcpDataOffsetAndReservedBitsOffset, TcpDataOffsetAndReservedBitsSize, 10+i, 1)
+	}
+	l.add("tcp.window_size_value", fmt.Sprintf("Window: %d", h.Window), TcpWindowOffset, TcpWindowSize)
+	l.add("tcp.checksum", fmt.Sprintf("Checksum: 0x%04x", h.Checksum), TcpChecksumOffset, TcpChecksumSize)
+	l.add("tcp.urgent_pointer", fmt.Sprintf("Urgent pointer: %d", h.UrgentPointer), TcpUrgentPointerOffset, TcpUrgentPointerSize)
+	if len(h.RawOptions) > 0 {
+		l.add("tcp.options", fmt.Sprintf("Options (%d bytes)", len(h.RawOptions)), TcpOptionsOffset, len(h.RawOptions))
+	}
+	return l.layer
+}
@@ -0,0 +1,81 @@
+package packet
+
+import "sniffer/application/protocol"
+
+// UdpDecoder hands UDP payloads of one application protocol to its
+// decoder.
+type UdpDecoder struct {
+	Protocol protocol.Protocol
+	// Ports the protocol is registered on. A datagram from or to one of
+	// them is handed over without looking at the payload.
+	Ports []uint16
+	// Heuristic recognises the protocol from the payload alone, for
+	// datagrams whose ports match no decoder. It may be nil.
+	Heuristic func(payload []byte) bool
+	// Parse decodes the payload. It must not panic: problems are reported
+	// in the returned layer.
+	Parse func(payload []byte) Parsable
+}
+
+// ApplicationLayer is implemented by the layers UDP dispatches to.
+type ApplicationLayer interface {
+	Parsable
+	// Summary is a one line description for packet lists.
+	Summary() string
+}
+
+var udpDecoders = []UdpDecoder{
+	{Protocol: protocol.Dns, Ports: []uint16{53, 5353, 5355}, Parse: ParseDnsPacket},
+	{Protocol: protocol.Dhcp, Ports: []uint16{67, 68}, Heuristic: isDhcp, Parse: ParseDhcpPacket},
+	{Protocol: protocol.Ntp, Ports: []uint16{123}, Parse: ParseNtpPacket},
+	{Protocol: protocol.Snmp, Ports: []uint16{161, 162}, Parse: ParseSnmpPacket},
+	{Protocol: protocol.Syslog, Ports: []uint16{514}, Heuristic: isSyslog, Parse: ParseSyslogPacket},
+	{Protocol: protocol.Quic, Ports: []uint16{443}, Heuristic: isQuic, Parse: ParseQuicPacket},
+}
+
+// RegisterUdpDecoder adds a decoder. Decoders are tried in the order they
+// were registered, the built-in ones first. The list is read without a
+// lock, so decoders must be registered before any packet is decoded, from
+// an init function or at the start of main.
+func RegisterUdpDecoder(d UdpDecoder) {
+	udpDecoders = append(udpDecoders, d)
+}
+
+// UdpDecoders lists the registered decoders.
+func UdpDecoders() []UdpDecoder {
+	return append([]UdpDecoder(nil), udpDecoders...)
+}
+
+func udpDecoderFor(p protocol.Protocol) (UdpDecoder, bool) {
+	for _, d := range udpDecoders {
+		if d.Protocol == p {
+			return d, true
+		}
+	}
+	return UdpDecoder{}, false
+}
+
+// dispatchUdp picks the decoder for a datagram: by the lower port first,
+// as Wireshark does, then by the higher one, then by heuristics. port is
+// the port that matched, 0 when a heuristic did.
+func dispatchUdp(h UdpHeader, payload []byte) (d *UdpDecoder, port uint16) {
+	low, high := h.SourcePort, h.DestinationPort
+	if high < low {
+		low, high = high, low
+	}
+	for _, candidate := range [2]uint16{low, high} {
+		for i := range udpDecoders {
+			for _, p := range udpDecoders[i].Ports {
+				if p == candidate {
+					return &udpDecoders[i], candidate
+				}
+			}
+		}
+	}
+	for i := range udpDecoders {
+		if udpDecoders[i].Heuristic != nil && udpDecoders[i].Heuristic(payload) {
+			return &udpDecoders[i], 0
+		}
+	}
+	return nil, 0
+}
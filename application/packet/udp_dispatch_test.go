@@ -0,0 +1,201 @@
+package packet
+
+import (
+	"encoding/binary"
+	"sniffer/application/protocol"
+	"testing"
+)
+
+// udpDatagram is a UDP header for the given ports followed by payload.
+func udpDatagram(sourcePort uint16, destinationPort uint16, payload []byte) []byte {
+	udp := make([]byte, UdpHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(udp[UdpSrcPortOffset:], sourcePort)
+	binary.BigEndian.PutUint16(udp[UdpDestinationPortOffset:], destinationPort)
+	binary.BigEndian.PutUint16(udp[UdpLengthOffset:], uint16(len(udp)))
+	copy(udp[UdpHeaderSize:], payload)
+	return udp
+}
+
+// dhcpDiscover is a DHCPDISCOVER from 02:00:00:00:00:01.
+func dhcpDiscover() []byte {
+	message := make([]byte, DhcpOptionsOffset)
+	message[DhcpOpOffset] = 1
+	message[DhcpHardwareTypeOffset] = 1
+	binary.BigEndian.PutUint32(message[DhcpTransactionIdOffset:], 0xcafe0001)
+	copy(message[DhcpClientMacOffset:], []byte{0x02, 0, 0, 0, 0, 1})
+	binary.BigEndian.PutUint32(message[DhcpCookieOffset:], DhcpMagicCookie)
+	return append(message, dhcpOptionMessageType, 1, 1, dhcpOptionEnd)
+}
+
+// ntpClient is an NTPv4 client request.
+func ntpClient() []byte {
+	message := make([]byte, NtpHeaderSize)
+	message[NtpFlagsOffset] = 4<<3 | NtpModeClient
+	return message
+}
+
+// snmpGet is an SNMPv2c get-request for 1.3.6.1.2.1 with community public.
+var snmpGet = []byte{
+	0x30, 0x26, 0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+	0xa0, 0x19, 0x02, 0x04, 0x00, 0x00, 0x30, 0x39, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+	0x30, 0x0b, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x05, 0x00,
+}
+
+// quicInitial is the start of a QUIC v1 Initial packet with an 8 byte
+// destination connection id and no source connection id.
+var quicInitial = []byte{0xc3, 0, 0, 0, 1, 8, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0x41, 0x00}
+
+func TestUdpDispatch(t *testing.T) {
+	syslog := []byte("<13>hello")
+	tests := []struct {
+		name        string
+		source      uint16
+		destination uint16
+		payload     []byte
+		want        protocol.Protocol
+		port        uint16
+	}{
+		{"dns by destination port", 1024, 53, dnsQuery, protocol.Dns, 53},
+		{"dns by source port", 53, 40000, dnsQuery, protocol.Dns, 53},
+		{"lower port first", 514, 161, syslog, protocol.Snmp, 161},
+		{"ntp", 123, 123, ntpClient(), protocol.Ntp, 123},
+		{"dhcp by heuristic", 40000, 40001, dhcpDiscover(), protocol.Dhcp, 0},
+		{"syslog by heuristic", 40000, 40001, syslog, protocol.Syslog, 0},
+		{"quic by heuristic", 40000, 40001, quicInitial, protocol.Quic, 0},
+		{"unknown payload", 40000, 40001, []byte("hello"), protocol.Protocol{}, 0},
+		{"empty payload", 1024, 53, nil, protocol.Protocol{}, 0},
+	}
+	for _, test := range tests {
+		u := parseUdp(udpDatagram(test.source, test.destination, test.payload), -1)
+		if u.Application != test.want || u.DispatchPort != test.port {
+			t.Errorf("%s: dispatched to %q by port %d, want %q by port %d",
+				test.name, u.Application.Name, u.DispatchPort, test.want.Name, test.port)
+		}
+		if dispatched := test.want != (protocol.Protocol{}); dispatched != (u.PacketParser != nil) {
+			t.Errorf("%s: application layer %v", test.name, u.PacketParser)
+		}
+	}
+}
+
+func TestDnsDecoder(t *testing.T) {
+	response := append(append([]byte(nil), dnsQuery...),
+		0xc0, 12, 0, 1, 0, 1, 0, 0, 0x0e, 0x10, 0, 4, 93, 184, 216, 34)
+	response[DnsFlagsOffset] = 0x81
+	response[DnsAnswersOffset+1] = 1
+
+	d := ParseDnsPacket(response).(DnsPacket)
+	if d.Malformed != "" || !d.Header.Response() || d.Header.Id != 0x1234 {
+		t.Fatalf("decoded %+v", d)
+	}
+	if len(d.Questions) != 1 || d.Questions[0].Name != "example.com" || d.Questions[0].Type != 1 {
+		t.Errorf("questions %+v", d.Questions)
+	}
+	if len(d.Answers) != 1 || d.Answers[0].Name != "example.com" || d.Answers[0].Data != "93.184.216.34" || d.Answers[0].Ttl != 3600 {
+		t.Errorf("answers %+v", d.Answers)
+	}
+
+	// A pointer to itself must not loop.
+	looped := append(append([]byte(nil), dnsQuery[:DnsHeaderSize]...), 0xc0, DnsHeaderSize, 0, 1, 0, 1)
+	if d := ParseDnsPacket(looped).(DnsPacket); d.Malformed == "" {
+		t.Error("a looping name pointer was accepted")
+	}
+	if d := ParseDnsPacket(dnsQuery[:20]).(DnsPacket); d.Malformed == "" {
+		t.Error("a truncated question was accepted")
+	}
+}
+
+func TestDhcpDecoder(t *testing.T) {
+	d := ParseDhcpPacket(dhcpDiscover()).(DhcpPacket)
+	if d.Malformed != "" || d.MessageType != 1 || d.TransactionId != 0xcafe0001 {
+		t.Fatalf("decoded %+v", d)
+	}
+	if mac := d.ClientMac.ToString(); mac != (MacAddress{Value: []byte{0x02, 0, 0, 0, 0, 1}}).ToString() {
+		t.Errorf("client mac %s", mac)
+	}
+
+	truncated := dhcpDiscover()
+	truncated = append(truncated[:DhcpOptionsOffset], dhcpOptionHostName, 10, 'a')
+	if d := ParseDhcpPacket(truncated).(DhcpPacket); d.Malformed == "" {
+		t.Error("a truncated option was accepted")
+	}
+	if d := ParseDhcpPacket(dhcpDiscover()[:100]).(DhcpPacket); d.Malformed == "" {
+		t.Error("a truncated BOOTP header was accepted")
+	}
+}
+
+func TestNtpDecoder(t *testing.T) {
+	n := ParseNtpPacket(ntpClient()).(NtpPacket)
+	if n.Malformed != "" || n.Header.Version != 4 || n.Header.Mode != NtpModeClient {
+		t.Fatalf("decoded %+v", n.Header)
+	}
+
+	// Key id and a 16 byte digest.
+	authenticated := append(ntpClient(), make([]byte, 20)...)
+	if n := ParseNtpPacket(authenticated).(NtpPacket); n.Malformed != "" || len(n.Mac) != 20 {
+		t.Errorf("authenticated message: mac %d bytes, malformed %q", len(n.Mac), n.Malformed)
+	}
+	if n := ParseNtpPacket(append(ntpClient(), 0, 0)).(NtpPacket); n.Malformed == "" {
+		t.Error("two trailing bytes were accepted")
+	}
+	if n := ParseNtpPacket(ntpClient()[:40]).(NtpPacket); n.Malformed == "" {
+		t.Error("a truncated header was accepted")
+	}
+}
+
+func TestSnmpDecoder(t *testing.T) {
+	s := ParseSnmpPacket(snmpGet).(SnmpPacket)
+	if s.Malformed != "" || s.Version != 1 || s.Community != "public" || s.PduType != 0xa0 || s.RequestId != 12345 {
+		t.Fatalf("decoded %+v", s)
+	}
+	if len(s.Oids) != 1 || s.Oids[0] != "1.3.6.1.2.1" {
+		t.Errorf("oids %v", s.Oids)
+	}
+	for cut := 1; cut < len(snmpGet); cut++ {
+		if s := ParseSnmpPacket(snmpGet[:cut]).(SnmpPacket); s.Malformed == "" {
+			t.Errorf("cut to %d bytes it decoded without complaint", cut)
+		}
+	}
+}
+
+func TestSyslogDecoder(t *testing.T) {
+	s := ParseSyslogPacket([]byte("<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event")).(SyslogPacket)
+	if s.Malformed != "" || s.Version != 1 || s.Facility != 20 || s.Severity != 5 {
+		t.Fatalf("decoded %+v", s)
+	}
+	if s.Hostname != "mymachine.example.com" || s.AppName != "evntslog" || s.MsgId != "ID47" || s.Message != "- An application event" {
+		t.Errorf("RFC 5424 fields %+v", s)
+	}
+
+	bsd := ParseSyslogPacket([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed\n")).(SyslogPacket)
+	if bsd.Version != 0 || bsd.Facility != 4 || bsd.Severity != 2 || bsd.Message != "Oct 11 22:14:15 mymachine su: 'su root' failed" {
+		t.Errorf("BSD message %+v", bsd)
+	}
+	for _, bad := range []string{"hello", "<>x", "<192>x", "<1234>x"} {
+		if s := ParseSyslogPacket([]byte(bad)).(SyslogPacket); s.Malformed == "" {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
+
+func TestQuicDecoder(t *testing.T) {
+	q := ParseQuicPacket(quicInitial).(QuicPacket)
+	if q.Malformed != "" || !q.LongHeader || q.Version != QuicVersion1 || q.PacketType != "Initial" {
+		t.Fatalf("decoded %+v", q)
+	}
+	if len(q.DestinationId) != 8 || len(q.SourceId) != 0 {
+		t.Errorf("connection ids % x and % x", q.DestinationId, q.SourceId)
+	}
+
+	v2 := append([]byte(nil), quicInitial...)
+	v2[0] = 0xd3
+	binary.BigEndian.PutUint32(v2[quicVersionStart:], QuicVersion2)
+	if q := ParseQuicPacket(v2).(QuicPacket); q.PacketType != "Initial" {
+		t.Errorf("QUIC v2 type bits 01 decoded as %q", q.PacketType)
+	}
+	if q := ParseQuicPacket([]byte{0x40, 1, 2, 3}).(QuicPacket); q.LongHeader || q.PacketType != "1-RTT" {
+		t.Errorf("short header decoded as %+v", q)
+	}
+	if q := ParseQuicPacket(quicInitial[:10]).(QuicPacket); q.Malformed == "" {
+		t.Error("a truncated connection id was accepted")
+	}
+}
@@ -28,6 +28,13 @@ type UdpHeader struct {
 type UdpPacket struct {
 	Packet
 	Header UdpHeader
+	// Application is the protocol the payload was dispatched to, chosen by
+	// DispatchPort, or by a heuristic when DispatchPort is 0.
+	Application  protocol.Protocol
+	DispatchPort uint16
+	// LengthError is set when Length disagrees with the IP payload. Such a
+	// datagram is not dispatched.
+	LengthError string
 }
 
 func parseUdpHeader(rawData []byte) UdpHeader {
@@ -39,30 +46,105 @@ func parseUdpHeader(rawData []byte) UdpHeader {
 	}
 }
 
-func (u UdpPacket) parse(rawData []byte) Parsable {
-	header := parseUdpHeader(rawData[0:UdpHeaderSize])
+// decode fills in everything but the application layer. ipPayloadLength
+// is the payload length the IP header claims, or -1 when it is not known,
+// as for a fragment.
+func (u *UdpPacket) decode(rawData []byte, ipPayloadLength int) {
+	if len(rawData) < UdpHeaderSize {
+		*u = UdpPacket{
+			Packet:      Packet{RawHeader: rawData, ProtocolName: protocol.Udp.Name},
+			LengthError: fmt.Sprintf("%d bytes are shorter than the header", len(rawData)),
+		}
+		return
+	}
+	u.Header = parseUdpHeader(rawData[0:UdpHeaderSize])
+	length := int(u.Header.Length)
+	end := len(rawData)
+	u.LengthError = ""
+	switch {
+	case length < UdpHeaderSize:
+		u.LengthError = fmt.Sprintf("length %d is shorter than the header", length)
+	case ipPayloadLength >= 0 && length > ipPayloadLength:
+		u.LengthError = fmt.Sprintf("length %d exceeds the %d byte IP payload", length, ipPayloadLength)
+	case length < end:
+		// Whatever follows is padding, e.g. up to the Ethernet minimum.
+		end = length
+	}
+	u.Packet = Packet{
+		RawHeader:    rawData[0:UdpHeaderSize],
+		RawPayload:   rawData[UdpHeaderSize:end],
+		ProtocolName: protocol.Udp.Name,
+		Length:       length,
+		HeaderLength: UdpHeaderSize,
+	}
+	u.Application = protocol.Protocol{}
+	u.DispatchPort = 0
+	if u.LengthError != "" || len(u.RawPayload) == 0 {
+		return
+	}
+	if d, port := dispatchUdp(u.Header, u.RawPayload); d != nil {
+		u.Application = d.Protocol
+		u.DispatchPort = port
+		u.CanParseMore = true
+	}
+}
 
-	return UdpPacket{
-		Packet: Packet{
-			RawHeader:    rawData[0:UdpHeaderSize],
-			RawPayload:   rawData[UdpHeaderSize:],
-			CanParseMore: false,
-			ProtocolName: protocol.Udp.Name,
-			Length:       int(header.Length),
-			HeaderLength: UdpHeaderSize,
-		},
+func parseUdp(rawData []byte, ipPayloadLength int) UdpPacket {
+	var u UdpPacket
+	u.decode(rawData, ipPayloadLength)
+	if u.CanParseMore {
+		d, _ := udpDecoderFor(u.Application)
+		u.PacketParser = d.Parse(u.RawPayload)
 	}
+	return u
+}
+
+func (u UdpPacket) parse(rawData []byte) Parsable {
+	return parseUdp(rawData, len(rawData))
+}
 
+func (u UdpPacket) dispatchToString() string {
+	if u.DispatchPort == 0 {
+		return fmt.Sprintf("%s by heuristic", u.Application.Name)
+	}
+	return fmt.Sprintf("%s by port %d", u.Application.Name, u.DispatchPort)
 }
 
 func (u UdpPacket) ToString() string {
-	return fmt.Sprintf("UDP Packet [Heeder %d byte] ", UdpHeaderSize) +
+	result := fmt.Sprintf("UDP Packet [Heeder %d byte] ", UdpHeaderSize) +
 		fmt.Sprintf("- Source Port: %d ", u.Header.SourcePort) +
 		fmt.Sprintf("- Destination Port: %d ", u.Header.DestinationPort) +
 		fmt.Sprintf("- Length: %d ", u.Header.Length) +
 		fmt.Sprintf("- Checksum: %x ", u.Header.Checksum)
+	if u.LengthError != "" {
+		result += fmt.Sprintf("- Bad length: %s ", u.LengthError)
+	}
+	if u.CanParseMore {
+		result += fmt.Sprintf("- Dispatched to %s ", u.dispatchToString())
+		if u.PacketParser != nil {
+			result += "\n" + u.PacketParser.ToString()
+		}
+	}
+	return result
 }
 
 func ParseUdpPacket(rawData []byte) Parsable {
 	return UdpPacket{}.parse(rawData)
 }
+
+func (u UdpPacket) fields(base int) Field {
+	h := u.Header
+	l := newLayerFields("udp", fmt.Sprintf("User Datagram Protocol, Src Port: %d, Dst Port: %d", h.SourcePort, h.DestinationPort), base, UdpHeaderSize)
+	l.add("udp.srcport", fmt.Sprintf("Source port: %d", h.SourcePort), UdpSrcPortOffset, UdpSrcPortSize)
+	l.add("udp.dstport", fmt.Sprintf("Destination port: %d", h.DestinationPort), UdpDestinationPortOffset, UdpDestinationPortSize)
+	length := fmt.Sprintf("Length: %d", h.Length)
+	if u.LengthError != "" {
+		length += " (bad: " + u.LengthError + ")"
+	}
+	l.add("udp.length", length, UdpLengthOffset, UdpLengthSize)
+	l.add("udp.checksum", fmt.Sprintf("Checksum: 0x%04x", h.Checksum), UdpChecksumOffset, UdpChecksumSize)
+	if u.CanParseMore {
+		l.add("udp.dispatch", "Dispatched to "+u.dispatchToString(), 0, UdpHeaderSize)
+	}
+	return l.layer
+}
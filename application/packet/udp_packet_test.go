@@ -0,0 +1,98 @@
+package packet
+
+import (
+	"encoding/binary"
+	"sniffer/application/protocol"
+	"testing"
+)
+
+// udpFrame builds an Ethernet frame carrying a UDP datagram to port 53
+// whose Length field is udpLength.
+func udpFrame(udpLength int, payload []byte) []byte {
+	const ipStart = HeaderLength
+	const udpStart = ipStart + Ipv4MinHeaderSize
+	frame := make([]byte, udpStart+UdpHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(frame[TypeOffset:], IPV4.Value)
+
+	ip := frame[ipStart:udpStart]
+	ip[Ipv4VersionAndIhlOffset] = 0x45
+	binary.BigEndian.PutUint16(ip[Ipv4TotalLengthOffset:], uint16(len(frame)-ipStart))
+	ip[Ipv4TtlOffset] = 64
+	ip[Ipv4ProtocolOffset] = 17
+	copy(ip[Ipv4SourceAddressOffset:], []byte{10, 0, 0, 1})
+	copy(ip[Ipv4DestAddressOffset:], []byte{10, 0, 0, 2})
+
+	udp := frame[udpStart:]
+	binary.BigEndian.PutUint16(udp[UdpSrcPortOffset:], 1024)
+	binary.BigEndian.PutUint16(udp[UdpDestinationPortOffset:], 53)
+	binary.BigEndian.PutUint16(udp[UdpLengthOffset:], uint16(udpLength))
+	copy(udp[UdpHeaderSize:], payload)
+	return frame
+}
+
+// dnsQuery is a query for example.com.
+var dnsQuery = []byte{
+	0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+	7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+	0, 1, 0, 1,
+}
+
+func decodeUdpLayers(t *testing.T, frame []byte) *UdpPacket {
+	t.Helper()
+	var ethernet EthernetPacket
+	var ipv4 Ipv4Packet
+	var udp UdpPacket
+	parser := NewLayerParser(protocol.Ethernet, &ethernet, &ipv4, &udp)
+	var decoded []protocol.Protocol
+	if err := parser.DecodeLayers(frame, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("decoded %v, want three layers", decoded)
+	}
+	return &udp
+}
+
+func classicUdp(t *testing.T, frame []byte) UdpPacket {
+	t.Helper()
+	ethernet := ParseFactoryMethod(frame, protocol.Ethernet).(EthernetPacket)
+	ipv4 := ethernet.PacketParser.(Ipv4Packet)
+	return ipv4.PacketParser.(UdpPacket)
+}
+
+func TestUdpLengthAgainstIpPayload(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		udpLength int
+		bad       bool
+	}{
+		{"exact", UdpHeaderSize + len(dnsQuery), false},
+		{"longer than the ip payload", 200, true},
+		{"shorter than the header", 4, true},
+	} {
+		frame := udpFrame(test.udpLength, dnsQuery)
+		layered := decodeUdpLayers(t, frame)
+		classic := classicUdp(t, frame)
+		for _, u := range []struct {
+			path string
+			udp  UdpPacket
+		}{{"LayerParser", *layered}, {"ParseFactoryMethod", classic}} {
+			if bad := u.udp.LengthError != ""; bad != test.bad {
+				t.Errorf("%s, %s: LengthError %q", test.name, u.path, u.udp.LengthError)
+			}
+			if dispatched := u.udp.Application != (protocol.Protocol{}); dispatched == test.bad {
+				t.Errorf("%s, %s: dispatched to %q", test.name, u.path, u.udp.Application.Name)
+			}
+		}
+	}
+}
+
+func TestUdpShorterThanHeader(t *testing.T) {
+	frame := udpFrame(UdpHeaderSize, nil)
+	// Cut the datagram to four bytes but keep the IP header consistent.
+	frame = frame[:HeaderLength+Ipv4MinHeaderSize+4]
+	binary.BigEndian.PutUint16(frame[HeaderLength+Ipv4TotalLengthOffset:], Ipv4MinHeaderSize+4)
+	if u := classicUdp(t, frame); u.LengthError == "" {
+		t.Fatal("no LengthError for a four byte datagram")
+	}
+}
@@ -0,0 +1,154 @@
+package pcapng
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Block types and option codes from the pcapng specification.
+const (
+	blockSectionHeader        = 0x0A0D0D0A
+	blockInterfaceDescription = 0x00000001
+	blockEnhancedPacket       = 0x00000006
+	byteOrderMagic            = 0x1A2B3C4D
+
+	optionEnd         = 0
+	optionComment     = 1
+	optionName        = 2
+	optionApplication = 4
+	optionTsResol     = 9
+)
+
+// Packet is one frame to write, with the interface it arrived on.
+type Packet struct {
+	Interface string
+	LinkType  layers.LinkType
+	Timestamp time.Time
+	Data      []byte
+	// Length is the length on the wire; 0 means len(Data).
+	Length int
+	// Comment is shown by Wireshark next to the frame.
+	Comment string
+}
+
+// Writer streams pcapng. Unlike pcapgo's writer it adds interfaces as they
+// show up and can attach a comment to each frame. Every packet is flushed
+// so a reader on a pipe sees it at once.
+type Writer struct {
+	w          *bufio.Writer
+	interfaces map[interfaceKey]uint32
+}
+
+type interfaceKey struct {
+	name     string
+	linkType layers.LinkType
+}
+
+// NewWriter writes the section header naming application as the writer.
+func NewWriter(w io.Writer, application string) (*Writer, error) {
+	writer := &Writer{w: bufio.NewWriterSize(w, 1<<16), interfaces: make(map[interfaceKey]uint32)}
+
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:], 1)
+	binary.LittleEndian.PutUint16(body[6:], 0)
+	// The section length is unknown while streaming.
+	binary.LittleEndian.PutUint64(body[8:], 0xFFFFFFFFFFFFFFFF)
+	body = appendOption(body, optionApplication, []byte(application))
+	body = appendOption(body, optionEnd, nil)
+	if err := writer.writeBlock(blockSectionHeader, body); err != nil {
+		return nil, err
+	}
+	return writer, writer.w.Flush()
+}
+
+func (w *Writer) WritePacket(p Packet) error {
+	id, err := w.interfaceId(p.Interface, p.LinkType)
+	if err != nil {
+		return err
+	}
+	length := p.Length
+	if length < len(p.Data) {
+		length = len(p.Data)
+	}
+	nanoseconds := uint64(p.Timestamp.UnixNano())
+
+	body := make([]byte, 20, 20+len(p.Data)+len(p.Comment)+12)
+	binary.LittleEndian.PutUint32(body[0:], id)
+	binary.LittleEndian.PutUint32(body[4:], uint32(nanoseconds>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(nanoseconds))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(p.Data)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(length))
+	body = append(body, p.Data...)
+	body = pad(body)
+	if p.Comment != "" {
+		comment := []byte(p.Comment)
+		if len(comment) > 0xFFFF {
+			comment = comment[:0xFFFF]
+		}
+		body = appendOption(body, optionComment, comment)
+		body = appendOption(body, optionEnd, nil)
+	}
+	if err := w.writeBlock(blockEnhancedPacket, body); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// interfaceId returns the id of an interface, describing it first if it
+// is new.
+func (w *Writer) interfaceId(name string, linkType layers.LinkType) (uint32, error) {
+	key := interfaceKey{name, linkType}
+	if id, ok := w.interfaces[key]; ok {
+		return id, nil
+	}
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:], uint16(linkType))
+	// A snap length of 0 means unlimited.
+	binary.LittleEndian.PutUint32(body[4:], 0)
+	if name != "" {
+		body = appendOption(body, optionName, []byte(name))
+	}
+	body = appendOption(body, optionTsResol, []byte{9})
+	body = appendOption(body, optionEnd, nil)
+	if err := w.writeBlock(blockInterfaceDescription, body); err != nil {
+		return 0, err
+	}
+	id := uint32(len(w.interfaces))
+	w.interfaces[key] = id
+	return id, nil
+}
+
+// writeBlock frames a body, already padded to 4 bytes, with the block
+// type and the total length at both ends.
+func (w *Writer) writeBlock(kind uint32, body []byte) error {
+	var b [8]byte
+	binary.LittleEndian.PutUint32(b[0:], kind)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)+12))
+	if _, err := w.w.Write(b[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(body); err != nil {
+		return err
+	}
+	_, err := w.w.Write(b[4:])
+	return err
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	var h [4]byte
+	binary.LittleEndian.PutUint16(h[0:], code)
+	binary.LittleEndian.PutUint16(h[2:], uint16(len(value)))
+	return pad(append(append(b, h[:]...), value...))
+}
+
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Decoder is the decoding state of one worker: packet.LayerParsers over
+// layers that are reused for every frame, so decoding a frame and
+// summarising it on one line allocates nothing but the line.
+type Decoder struct {
+	ethernet     packet.EthernetPacket
+	ipv4         packet.Ipv4Packet
+	tcp          packet.TcpPacket
+	udp          packet.UdpPacket
+	fromEthernet *packet.LayerParser
+	fromIpv4     *packet.LayerParser
+	// parser decoded the last frame.
+	parser  *packet.LayerParser
+	decoded []protocol.Protocol
+	line    []byte
+}
+
+func NewDecoder() *Decoder {
+	d := &Decoder{
+		decoded: make([]protocol.Protocol, 0, 4),
+		line:    make([]byte, 0, 256),
+	}
+	d.fromEthernet = packet.NewLayerParser(protocol.Ethernet, &d.ethernet, &d.ipv4, &d.tcp, &d.udp)
+	d.fromIpv4 = packet.NewLayerParser(protocol.IpV4, &d.ipv4, &d.tcp, &d.udp)
+	return d
+}
+
+// Decode decodes a frame into the reusable layers. It reports false when
+// the link type has no decoder or a layer is truncated; DecodeLink then
+// gives the classic result, which says what is malformed.
+func (d *Decoder) Decode(data []byte, linkType layers.LinkType) bool {
+	switch linkType {
+	case layers.LinkTypeEthernet:
+		d.parser = d.fromEthernet
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4:
+		if len(data) == 0 || data[0]>>4 != 4 {
+			return false
+		}
+		d.parser = d.fromIpv4
+	default:
+		return false
+	}
+	return d.parser.DecodeLayers(data, &d.decoded) == nil && len(d.decoded) > 0
+}
+
+// Summary describes the last frame decoded on one line.
+func (d *Decoder) Summary() string {
+	d.line = d.parser.AppendSummary(d.line[:0], d.decoded)
+	return string(d.line)
+}
+
+// Parsable links the layers of the last frame decoded into the value tree
+// ParseFactoryMethod returns. It allocates, so only frames that go on to
+// code written against that tree should pay for it.
+func (d *Decoder) Parsable() packet.Parsable {
+	return d.parser.Chain(d.decoded)
+}
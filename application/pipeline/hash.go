@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"encoding/binary"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+)
+
+const (
+	fnvOffset = 2166136261
+	fnvPrime  = 16777619
+)
+
+// FlowHash hashes the IPv4 addresses and ports of a raw Ethernet frame
+// without decoding it. Both directions of a conversation hash the same, so
+// one worker sees all of it. Frames that are not IPv4 hash to zero.
+func FlowHash(frame []byte) uint32 {
+	const ip = packet.HeaderLength
+	if len(frame) < ip+packet.Ipv4MinHeaderSize ||
+		binary.BigEndian.Uint16(frame[packet.TypeOffset:]) != packet.IPV4.Value {
+		return 0
+	}
+	protocol := frame[ip+packet.Ipv4ProtocolOffset]
+	source := binary.BigEndian.Uint32(frame[ip+packet.Ipv4SourceAddressOffset:])
+	destination := binary.BigEndian.Uint32(frame[ip+packet.Ipv4DestAddressOffset:])
+
+	var sourcePort, destinationPort uint16
+	headerLength := int(frame[ip]&0x0f) * 4
+	transport := ip + headerLength
+	fragmentOffset := binary.BigEndian.Uint16(frame[ip+packet.Ipv4FlagsAndFragmentOffset:]) & 0x1fff
+	if (protocol == flow.IpProtocolTcp || protocol == flow.IpProtocolUdp) && fragmentOffset == 0 && len(frame) >= transport+4 {
+		sourcePort = binary.BigEndian.Uint16(frame[transport:])
+		destinationPort = binary.BigEndian.Uint16(frame[transport+2:])
+	}
+
+	if source > destination || (source == destination && sourcePort > destinationPort) {
+		source, destination = destination, source
+		sourcePort, destinationPort = destinationPort, sourcePort
+	}
+	hash := uint32(fnvOffset)
+	mix := func(value uint32, bytes int) {
+		for i := bytes - 1; i >= 0; i-- {
+			hash ^= (value >> uint(8*i)) & 0xff
+			hash *= fnvPrime
+		}
+	}
+	mix(source, 4)
+	mix(destination, 4)
+	mix(uint32(sourcePort), 2)
+	mix(uint32(destinationPort), 2)
+	mix(uint32(protocol), 1)
+
+	// FNV leaves the low bits, which pick the worker, weakly mixed; finish
+	// with the murmur3 avalanche step.
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return hash
+}
@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"fmt"
+	"sniffer/application/capture"
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+const DefaultQueueSize = 1024
+
+// Job is one frame travelling through the pipeline. Decode fills in
+// Decoded, and anything else it wants to hand to the output stage, on a
+// worker goroutine.
+type Job struct {
+	Sequence  uint64
+	Data      []byte
+	Timestamp time.Time
+	Length    int
+	Interface string
+	LinkType  layers.LinkType
+	Decoded   packet.Parsable
+	// Failed is set when a frame is too malformed to decode at all, or the
+	// decoder panicked on it.
+	Failed bool
+	// Unsupported is set by Decode when no decoder handles the frame's
+	// link type.
+	Unsupported bool
+	// Text is free for Decode to render output off the capture path.
+	Text string
+}
+
+type Config struct {
+	Workers   int
+	QueueSize int
+	// DropWhenFull drops frames whose worker queue is full instead of
+	// blocking the capture goroutine.
+	DropWhenFull bool
+	// Decode runs on a worker, with the worker's own Decoder. The default
+	// fills in Decoded from the Decoder, or from DecodeLink for frames it
+	// cannot take.
+	Decode func(job *Job, d *Decoder)
+}
+
+type Stats struct {
+	Received uint64
+	// Truncated counts frames captured shorter than they were on the wire.
+	Truncated    uint64
+	QueueDropped uint64
+	DecodeFailed uint64
+	// Unsupported counts frames of a link type nothing decodes.
+	Unsupported uint64
+	Emitted     uint64
+	// PerWorker counts the frames each worker decoded.
+	PerWorker []uint64
+}
+
+func (s Stats) ToString() string {
+	workers := make([]string, len(s.PerWorker))
+	for i, count := range s.PerWorker {
+		workers[i] = fmt.Sprint(count)
+	}
+	return fmt.Sprintf("pipeline: %d received, %d truncated, %d dropped on full queues, %d failed to decode, %d of unsupported link types, %d emitted, per worker [%s]\n",
+		s.Received, s.Truncated, s.QueueDropped, s.DecodeFailed, s.Unsupported, s.Emitted, strings.Join(workers, " "))
+}
+
+// Pipeline decodes frames on a pool of workers. Frames are sharded by
+// FlowHash so each conversation stays on one worker and in order, and the
+// output stage restores capture order across workers. All queues are
+// bounded: a slow consumer backs up into the workers and then into Submit.
+type Pipeline struct {
+	// Counters come first to stay 64-bit aligned for sync/atomic.
+	received     uint64
+	truncated    uint64
+	queueDropped uint64
+	decodeFailed uint64
+	unsupported  uint64
+	emitted      uint64
+	sequence     uint64
+	perWorker    []uint64
+
+	config  Config
+	queues  []chan *Job
+	results chan *Job
+	output  chan *Job
+	workers sync.WaitGroup
+}
+
+func New(config Config) *Pipeline {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Decode == nil {
+		config.Decode = decodeDefault
+	}
+
+	p := &Pipeline{
+		config:    config,
+		queues:    make([]chan *Job, config.Workers),
+		results:   make(chan *Job, config.QueueSize),
+		output:    make(chan *Job, config.QueueSize),
+		perWorker: make([]uint64, config.Workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan *Job, config.QueueSize)
+		p.workers.Add(1)
+		go p.work(i)
+	}
+	go func() {
+		p.workers.Wait()
+		close(p.results)
+	}()
+	go p.reorder()
+	return p
+}
+
+// Submit hands a frame to its worker. It must be called from a single
+// goroutine and reports false when the frame was dropped.
+func (p *Pipeline) Submit(frame capture.Frame) bool {
+	atomic.AddUint64(&p.received, 1)
+	if frame.CaptureLength < frame.Length {
+		atomic.AddUint64(&p.truncated, 1)
+	}
+	job := &Job{
+		Sequence:  p.sequence,
+		Data:      frame.Data,
+		Timestamp: frame.Timestamp,
+		Length:    frame.Length,
+		Interface: frame.Interface,
+		LinkType:  frame.LinkType,
+	}
+	queue := p.queues[FlowHash(frame.Data)%uint32(len(p.queues))]
+	if p.config.DropWhenFull {
+		select {
+		case queue <- job:
+		default:
+			atomic.AddUint64(&p.queueDropped, 1)
+			return false
+		}
+	} else {
+		queue <- job
+	}
+	// Sequence numbers are only spent on queued frames, so the output stage
+	// never waits for a dropped one.
+	p.sequence++
+	return true
+}
+
+// Close stops input. Output is closed once every queued frame is emitted.
+func (p *Pipeline) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+}
+
+// Output delivers decoded frames in the order they were submitted.
+func (p *Pipeline) Output() <-chan *Job {
+	return p.output
+}
+
+func (p *Pipeline) work(index int) {
+	defer p.workers.Done()
+	d := NewDecoder()
+	for job := range p.queues[index] {
+		p.decode(job, d)
+		atomic.AddUint64(&p.perWorker[index], 1)
+		p.results <- job
+	}
+}
+
+func (p *Pipeline) decode(job *Job, d *Decoder) {
+	defer func() {
+		if recover() != nil {
+			job.Failed = true
+			atomic.AddUint64(&p.decodeFailed, 1)
+		}
+	}()
+	p.config.Decode(job, d)
+	if job.Unsupported {
+		atomic.AddUint64(&p.unsupported, 1)
+	} else if job.Failed {
+		atomic.AddUint64(&p.decodeFailed, 1)
+	}
+}
+
+func decodeDefault(job *Job, d *Decoder) {
+	if d.Decode(job.Data, job.LinkType) {
+		job.Decoded = d.Parsable()
+		return
+	}
+	job.DecodeLink()
+}
+
+// DecodeLink decodes the frame with DecodeLink and sets Failed or
+// Unsupported when that gives nothing.
+func (j *Job) DecodeLink() {
+	j.Decoded = DecodeLink(j.Data, j.LinkType)
+	if j.Decoded != nil {
+		return
+	}
+	switch j.LinkType {
+	case layers.LinkTypeEthernet:
+		j.Failed = true
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4:
+		// Raw IP links carry IPv6 too, which nothing decodes.
+		j.Failed = len(j.Data) > 0 && j.Data[0]>>4 == 4
+		j.Unsupported = !j.Failed
+	default:
+		j.Unsupported = true
+	}
+}
+
+// DecodeLink decodes a frame by its link type: Ethernet, or IPv4 for the raw
+// IP link types. It returns nil for any other link type, and for a frame
+// too short for its outermost header.
+func DecodeLink(data []byte, linkType layers.LinkType) packet.Parsable {
+	switch linkType {
+	case layers.LinkTypeEthernet:
+		return packet.ParseFactoryMethod(data, protocol.Ethernet)
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4:
+		if len(data) > 0 && data[0]>>4 == 4 {
+			return packet.ParseFactoryMethod(data, protocol.IpV4)
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) reorder() {
+	pending := make(map[uint64]*Job)
+	next := uint64(0)
+	for job := range p.results {
+		pending[job.Sequence] = job
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.output <- ready
+			atomic.AddUint64(&p.emitted, 1)
+			next++
+		}
+	}
+	close(p.output)
+}
+
+func (p *Pipeline) Stats() Stats {
+	stats := Stats{
+		Received:     atomic.LoadUint64(&p.received),
+		Truncated:    atomic.LoadUint64(&p.truncated),
+		QueueDropped: atomic.LoadUint64(&p.queueDropped),
+		DecodeFailed: atomic.LoadUint64(&p.decodeFailed),
+		Unsupported:  atomic.LoadUint64(&p.unsupported),
+		Emitted:      atomic.LoadUint64(&p.emitted),
+		PerWorker:    make([]uint64, len(p.perWorker)),
+	}
+	for i := range p.perWorker {
+		stats.PerWorker[i] = atomic.LoadUint64(&p.perWorker[i])
+	}
+	return stats
+}
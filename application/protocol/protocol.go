@@ -44,3 +44,33 @@ var Ssh = Protocol{
 	Name: "Ssh",
 	Code: 7,
 }
+
+var Dns = Protocol{
+	Name: "Dns",
+	Code: 8,
+}
+
+var Dhcp = Protocol{
+	Name: "Dhcp",
+	Code: 9,
+}
+
+var Ntp = Protocol{
+	Name: "Ntp",
+	Code: 10,
+}
+
+var Snmp = Protocol{
+	Name: "Snmp",
+	Code: 11,
+}
+
+var Syslog = Protocol{
+	Name: "Syslog",
+	Code: 12,
+}
+
+var Quic = Protocol{
+	Name: "Quic",
+	Code: 13,
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sniffer/application/control"
+	"syscall"
+	"time"
+)
+
+// runRemote implements "sniffer remote [flags] command [argument]", a
+// client for "sniffer agent".
+func runRemote(args []string) int {
+	flags := flag.NewFlagSet("remote", flag.ExitOnError)
+	server := flags.String("server", "localhost:50051", "agent to connect to, host:port")
+	cert := flags.String("cert", "", "PEM client certificate")
+	key := flags.String("key", "", "PEM private key of -cert")
+	ca := flags.String("ca", "", "PEM CA that the agent's certificate must be signed by")
+	timeout := flags.Duration("timeout", 10*time.Second, "deadline of each call, except stream")
+	filter := flags.String("filter", "", "start: BPF filter in tcpdump syntax")
+	snapLength := flags.Int("snaplen", control.DefaultSnapLength, "start: bytes captured per frame")
+	promiscuous := flags.Bool("promiscuous", true, "start: capture in promiscuous mode")
+	raw := flags.Bool("raw", false, "stream: receive frame bytes instead of decoded headers")
+	top := flags.Int("top", 10, "flows: number of flows shown, 0 for all")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer remote [flags] interfaces | start IFACE | stop ID | stream ID | flows ID")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	command, argument := flags.Arg(0), flags.Arg(1)
+	arguments := map[string]int{"interfaces": 0, "start": 1, "stop": 1, "stream": 1, "flows": 1}
+	if count, ok := arguments[command]; !ok || flags.NArg() != count+1 {
+		flags.Usage()
+		return 2
+	}
+	config, err := control.ClientTls(*cert, *key, *ca)
+	if err != nil {
+		fmt.Println("remote:", err)
+		return 1
+	}
+	client, err := control.NewClient(*server, config)
+	if err != nil {
+		fmt.Println("remote:", err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	switch command {
+	case "interfaces":
+		response, err := client.ListInterfaces(ctx, &control.ListInterfacesRequest{})
+		if err != nil {
+			fmt.Println("remote:", err)
+			return 1
+		}
+		for _, i := range response.Interfaces {
+			fmt.Printf("%-16s %v %s\n", i.Name, i.Addresses, i.Description)
+		}
+
+	case "start":
+		response, err := client.StartCapture(ctx, &control.StartCaptureRequest{
+			Interface:   argument,
+			Filter:      *filter,
+			SnapLength:  uint32(*snapLength),
+			Promiscuous: *promiscuous,
+		})
+		if err != nil {
+			fmt.Println("remote:", err)
+			return 1
+		}
+		fmt.Println(response.CaptureId)
+
+	case "stop":
+		response, err := client.StopCapture(ctx, &control.StopCaptureRequest{CaptureId: argument})
+		if err != nil {
+			fmt.Println("remote:", err)
+			return 1
+		}
+		if response.Stats != nil {
+			fmt.Println(response.Stats.ToString())
+		}
+
+	case "flows":
+		response, err := client.GetFlowStats(ctx, &control.FlowStatsRequest{CaptureId: argument, Top: uint32(*top)})
+		if err != nil {
+			fmt.Println("remote:", err)
+			return 1
+		}
+		fmt.Printf("%d active flows\n", response.Active)
+		for _, f := range response.Flows {
+			fmt.Println(f.ToString())
+		}
+
+	case "stream":
+		streamContext, stop := context.WithCancel(context.Background())
+		defer stop()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupt
+			stop()
+		}()
+		stream, err := client.StreamPackets(streamContext, &control.StreamPacketsRequest{CaptureId: argument, Raw: *raw})
+		if err != nil {
+			fmt.Println("remote:", err)
+			return 1
+		}
+		for {
+			m, err := stream.Recv()
+			if err == io.EOF || streamContext.Err() != nil {
+				break
+			}
+			if err != nil {
+				fmt.Println("remote:", err)
+				return 1
+			}
+			fmt.Println(m.ToString())
+		}
+	}
+	return 0
+}
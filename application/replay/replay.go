@@ -0,0 +1,214 @@
+package replay
+
+import (
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"io"
+	"os"
+	"time"
+)
+
+// Config selects how frames are paced. Rate wins over Speed; a Speed of
+// zero or less sends as fast as the sink accepts.
+type Config struct {
+	// Speed multiplies the original timing, 2 replays twice as fast.
+	Speed float64
+	// Rate sends at a fixed number of packets per second.
+	Rate float64
+	// Loops is how often the file is replayed, zero loops until stopped.
+	Loops    int
+	Rewriter *Rewriter
+}
+
+// Sink receives replayed frames. Realtime sinks are paced against the wall
+// clock; others get the scheduled time as the frame timestamp instead.
+type Sink interface {
+	WritePacket(ci gopacket.CaptureInfo, data []byte) error
+	Realtime() bool
+}
+
+type packetWriter interface {
+	WritePacketData(data []byte) error
+}
+
+type interfaceSink struct {
+	handle packetWriter
+}
+
+// NewInterfaceSink sends frames through a live handle, e.g. a *pcap.Handle.
+func NewInterfaceSink(handle packetWriter) Sink {
+	return interfaceSink{handle: handle}
+}
+
+func (s interfaceSink) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	return s.handle.WritePacketData(data)
+}
+
+func (s interfaceSink) Realtime() bool {
+	return true
+}
+
+type fileSink struct {
+	writer *pcapgo.Writer
+}
+
+// NewFileSink writes frames to a pcap file with the given link type.
+func NewFileSink(w io.Writer, linkType layers.LinkType) (Sink, error) {
+	writer := pcapgo.NewWriterNanos(w)
+	if err := writer.WriteFileHeader(65536, linkType); err != nil {
+		return nil, err
+	}
+	return fileSink{writer: writer}, nil
+}
+
+func (s fileSink) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	return s.writer.WritePacket(ci, data)
+}
+
+func (s fileSink) Realtime() bool {
+	return false
+}
+
+type Stats struct {
+	Packets   int
+	Bytes     int
+	Rewritten int
+	Loops     int
+	Elapsed   time.Duration
+}
+
+func (s Stats) ToString() string {
+	return fmt.Sprintf("replayed %d packets (%d bytes) in %d loop(s) over %s, %d rewritten",
+		s.Packets, s.Bytes, s.Loops, s.Elapsed.Round(time.Millisecond), s.Rewritten)
+}
+
+// LinkType reads the link type from a pcap file header.
+func LinkType(path string) (layers.LinkType, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader, err := pcapgo.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	return reader.LinkType(), nil
+}
+
+// Replay sends every frame of the pcap file at path to sink, as many times
+// as Loops asks, until stop is closed.
+func Replay(path string, sink Sink, config Config, stop <-chan struct{}) (Stats, error) {
+	var stats Stats
+	started := time.Now()
+	var origin time.Time
+	// offset shifts every loop after the first so scheduled times keep
+	// increasing.
+	var offset time.Duration
+	var timer *time.Timer
+
+	for config.Loops <= 0 || stats.Loops < config.Loops {
+		file, err := os.Open(path)
+		if err != nil {
+			return stats, err
+		}
+		reader, err := pcapgo.NewReader(file)
+		if err != nil {
+			file.Close()
+			return stats, err
+		}
+
+		var first time.Time
+		var due time.Duration
+		count := 0
+		for {
+			data, ci, err := reader.ReadPacketData()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return stats, err
+			}
+
+			if count == 0 {
+				first = ci.Timestamp
+				if stats.Loops == 0 {
+					origin = ci.Timestamp
+					if sink.Realtime() {
+						origin = started
+					}
+				}
+			}
+			switch {
+			case config.Rate > 0:
+				due = time.Duration(float64(count) * float64(time.Second) / config.Rate)
+			case config.Speed > 0:
+				due = time.Duration(float64(ci.Timestamp.Sub(first)) / config.Speed)
+			default:
+				due = 0
+			}
+			count++
+
+			if sink.Realtime() && (config.Rate > 0 || config.Speed > 0) {
+				wait := time.Until(origin.Add(offset + due))
+				if wait > 0 {
+					if timer == nil {
+						timer = time.NewTimer(wait)
+					} else {
+						timer.Reset(wait)
+					}
+					select {
+					case <-timer.C:
+					case <-stop:
+						timer.Stop()
+						file.Close()
+						return finish(stats, started), nil
+					}
+				}
+			}
+			select {
+			case <-stop:
+				file.Close()
+				return finish(stats, started), nil
+			default:
+			}
+
+			if config.Rewriter != nil && !config.Rewriter.Empty() {
+				data = append([]byte(nil), data...)
+				if config.Rewriter.Rewrite(data) {
+					stats.Rewritten++
+				}
+			}
+			ci.Timestamp = origin.Add(offset + due)
+			ci.CaptureLength = len(data)
+			if err := sink.WritePacket(ci, data); err != nil {
+				file.Close()
+				return finish(stats, started), err
+			}
+			stats.Packets++
+			stats.Bytes += len(data)
+		}
+		file.Close()
+
+		stats.Loops++
+		if count == 0 {
+			break
+		}
+		// Leave the average inter-frame gap between the last frame of a
+		// loop and the first of the next.
+		offset += due
+		if count > 1 {
+			offset += due / time.Duration(count-1)
+		}
+	}
+	return finish(stats, started), nil
+}
+
+func finish(stats Stats, started time.Time) Stats {
+	stats.Elapsed = time.Since(started)
+	return stats
+}
@@ -0,0 +1,205 @@
+package replay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+	"strings"
+)
+
+// Rewriter replaces MAC and IPv4 addresses in Ethernet, ARP and IPv4
+// headers and fixes the IPv4, TCP and UDP checksums to match.
+type Rewriter struct {
+	Macs map[[6]byte][6]byte
+	Ips  map[[4]byte][4]byte
+}
+
+func NewRewriter() *Rewriter {
+	return &Rewriter{
+		Macs: make(map[[6]byte][6]byte),
+		Ips:  make(map[[4]byte][4]byte),
+	}
+}
+
+func (r *Rewriter) Empty() bool {
+	return len(r.Macs) == 0 && len(r.Ips) == 0
+}
+
+// AddMac parses an old=new pair of MAC addresses.
+func (r *Rewriter) AddMac(mapping string) error {
+	from, to, err := splitMapping(mapping)
+	if err != nil {
+		return err
+	}
+	old, err := net.ParseMAC(from)
+	if err != nil || len(old) != 6 {
+		return fmt.Errorf("%q is not an Ethernet address", from)
+	}
+	replacement, err := net.ParseMAC(to)
+	if err != nil || len(replacement) != 6 {
+		return fmt.Errorf("%q is not an Ethernet address", to)
+	}
+	var key, value [6]byte
+	copy(key[:], old)
+	copy(value[:], replacement)
+	r.Macs[key] = value
+	return nil
+}
+
+// AddIp parses an old=new pair of IPv4 addresses.
+func (r *Rewriter) AddIp(mapping string) error {
+	from, to, err := splitMapping(mapping)
+	if err != nil {
+		return err
+	}
+	old := net.ParseIP(from).To4()
+	if old == nil {
+		return fmt.Errorf("%q is not an IPv4 address", from)
+	}
+	replacement := net.ParseIP(to).To4()
+	if replacement == nil {
+		return fmt.Errorf("%q is not an IPv4 address", to)
+	}
+	var key, value [4]byte
+	copy(key[:], old)
+	copy(value[:], replacement)
+	r.Ips[key] = value
+	return nil
+}
+
+func splitMapping(mapping string) (string, string, error) {
+	parts := strings.SplitN(mapping, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%q is not an old=new pair", mapping)
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+func (r *Rewriter) mac(value []byte) ([]byte, bool) {
+	var key [6]byte
+	copy(key[:], value)
+	replacement, ok := r.Macs[key]
+	return replacement[:], ok
+}
+
+func (r *Rewriter) ip(value []byte) ([]byte, bool) {
+	var key [4]byte
+	copy(key[:], value)
+	replacement, ok := r.Ips[key]
+	return replacement[:], ok
+}
+
+// Rewrite edits frame in place and reports whether anything changed. Frames
+// too short to decode are left alone.
+func (r *Rewriter) Rewrite(frame []byte) (changed bool) {
+	if len(frame) < packet.HeaderLength {
+		return false
+	}
+	defer func() {
+		// The decoders index without bounds checks, a truncated header
+		// simply means the frame is replayed as it was captured.
+		if recover() != nil {
+			changed = false
+		}
+	}()
+
+	ethernet, ok := packet.ParseFactoryMethod(frame, protocol.Ethernet).(packet.EthernetPacket)
+	if !ok {
+		return false
+	}
+	header := ethernet.Header
+	if mac, ok := r.mac(header.DestMacAddr.Value); ok {
+		header.DestMacAddr = packet.MacAddress{Value: mac}
+		changed = true
+	}
+	if mac, ok := r.mac(header.SrcMacAddr.Value); ok {
+		header.SrcMacAddr = packet.MacAddress{Value: mac}
+		changed = true
+	}
+	if changed {
+		copy(frame, header.Serialize())
+	}
+	if !ethernet.CanParseMore {
+		return changed
+	}
+
+	switch layer := ethernet.PacketParser.(type) {
+	case packet.ArpPacket:
+		if r.rewriteArp(frame[packet.HeaderLength:]) {
+			changed = true
+		}
+	case packet.Ipv4Packet:
+		if r.rewriteIpv4(frame[packet.HeaderLength:], layer) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (r *Rewriter) rewriteArp(arp []byte) bool {
+	if len(arp) < packet.ArpHeaderLength {
+		return false
+	}
+	changed := false
+	for _, offset := range []int{packet.ArpSourceMacAddressOffset, packet.ArpDestHardwareAddressOffset} {
+		if mac, ok := r.mac(arp[offset : offset+packet.ArpSourceMacAddressSize]); ok {
+			copy(arp[offset:], mac)
+			changed = true
+		}
+	}
+	for _, offset := range []int{packet.ArpSourceProtocolAddressOffset, packet.ArpDestProtocolAddressOffset} {
+		if ip, ok := r.ip(arp[offset : offset+packet.ArpSourceProtocolAddressSize]); ok {
+			copy(arp[offset:], ip)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (r *Rewriter) rewriteIpv4(data []byte, ip packet.Ipv4Packet) bool {
+	header := ip.Header
+	old := append([]byte(nil), data[packet.Ipv4SourceAddressOffset:packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize]...)
+	changed := false
+	if address, ok := r.ip(header.SourceAddress.Value); ok {
+		header.SourceAddress = packet.IpAddress{Value: address}
+		changed = true
+	}
+	if address, ok := r.ip(header.DestinationAddress.Value); ok {
+		header.DestinationAddress = packet.IpAddress{Value: address}
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+	copy(data, header.Serialize())
+
+	// Only the first fragment carries the transport header.
+	if header.FragmentOffset != 0 {
+		return true
+	}
+	updated := data[packet.Ipv4SourceAddressOffset : packet.Ipv4DestAddressOffset+packet.Ipv4DestAddressSize]
+	segment := data[header.Length:]
+	switch header.PayloadProtocol.Value {
+	case flow.IpProtocolTcp:
+		if len(segment) >= packet.TcpChecksumOffset+packet.TcpChecksumSize {
+			checksum := binary.BigEndian.Uint16(segment[packet.TcpChecksumOffset:])
+			binary.BigEndian.PutUint16(segment[packet.TcpChecksumOffset:], packet.AdjustChecksum(checksum, old, updated))
+		}
+	case flow.IpProtocolUdp:
+		if len(segment) >= packet.UdpHeaderSize {
+			checksum := binary.BigEndian.Uint16(segment[packet.UdpChecksumOffset:])
+			// Zero means the sender did not compute a checksum.
+			if checksum != 0 {
+				checksum = packet.AdjustChecksum(checksum, old, updated)
+				if checksum == 0 {
+					checksum = 0xffff
+				}
+				binary.BigEndian.PutUint16(segment[packet.UdpChecksumOffset:], checksum)
+			}
+		}
+	}
+	return true
+}
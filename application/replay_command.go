@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/google/gopacket/pcap"
+	"os"
+	"os/signal"
+	"sniffer/application/replay"
+	"syscall"
+)
+
+// runReplay implements "sniffer replay [flags] file.pcap".
+func runReplay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	iface := flags.String("interface", "", "send frames out of this interface")
+	output := flags.String("out", "", "write frames to this pcap file instead of an interface")
+	speed := flags.Float64("speed", 1, "multiply the original timing, 0 sends as fast as possible")
+	rate := flags.Float64("rate", 0, "send at this many packets per second instead of the original timing")
+	loops := flags.Int("loop", 1, "replay the file this many times, 0 loops until interrupted")
+	var macs, ips patternList
+	flags.Var(&macs, "rewrite-mac", "replace a MAC address, old=new, may repeat")
+	flags.Var(&ips, "rewrite-ip", "replace an IPv4 address, old=new, may repeat")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer replay [flags] file.pcap")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || (*iface == "") == (*output == "") {
+		flags.Usage()
+		return 2
+	}
+	input := flags.Arg(0)
+
+	rewriter := replay.NewRewriter()
+	for _, mapping := range macs {
+		if err := rewriter.AddMac(mapping); err != nil {
+			fmt.Println("rewrite-mac:", err)
+			return 1
+		}
+	}
+	for _, mapping := range ips {
+		if err := rewriter.AddIp(mapping); err != nil {
+			fmt.Println("rewrite-ip:", err)
+			return 1
+		}
+	}
+
+	var sink replay.Sink
+	if *output != "" {
+		linkType, err := replay.LinkType(input)
+		if err != nil {
+			fmt.Println("replay:", err)
+			return 1
+		}
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Println("replay:", err)
+			return 1
+		}
+		defer file.Close()
+		sink, err = replay.NewFileSink(file, linkType)
+		if err != nil {
+			fmt.Println("replay:", err)
+			return 1
+		}
+	} else {
+		handle, err := pcap.OpenLive(*iface, 65536, false, pcap.BlockForever)
+		if err != nil {
+			fmt.Println("replay:", err)
+			return 1
+		}
+		defer handle.Close()
+		sink = replay.NewInterfaceSink(handle)
+	}
+
+	stop := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		close(stop)
+	}()
+
+	stats, err := replay.Replay(input, sink, replay.Config{
+		Speed:    *speed,
+		Rate:     *rate,
+		Loops:    *loops,
+		Rewriter: rewriter,
+	}, stop)
+	fmt.Println(stats.ToString())
+	if err != nil {
+		fmt.Println("replay:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package rpcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// The wire format follows rpcap-protocol.h from libpcap. Every message
+// starts with an 8 byte header; all fields are in network byte order.
+
+const (
+	DefaultPort = 2002
+	// Version is the only protocol version spoken, the one every rpcapd
+	// and libpcap client supports.
+	Version = 0
+)
+
+const (
+	msgError            = 1
+	msgFindAllIfRequest = 2
+	msgOpenRequest      = 3
+	msgStartCapRequest  = 4
+	msgUpdateFilterReq  = 5
+	msgClose            = 6
+	msgPacket           = 7
+	msgAuthRequest      = 8
+	msgStatsRequest     = 9
+	msgEndCapRequest    = 10
+	msgSetSamplingReq   = 11
+
+	msgReply = 128
+)
+
+// Error codes carried in the value field of an error message.
+const (
+	ErrNetwork              = 1
+	ErrAuth                 = 3
+	ErrFindAllIf            = 4
+	ErrOpen                 = 6
+	ErrUpdateFilter         = 7
+	ErrGetStats             = 8
+	ErrStartCapture         = 12
+	ErrEndCapture           = 13
+	ErrSetSampling          = 15
+	ErrWrongMessage         = 16
+	ErrWrongVersion         = 17
+	ErrAuthFailed           = 18
+	ErrAuthTypeNotSupported = 20
+)
+
+// maxPayloadLength bounds what we read from a peer.
+const maxPayloadLength = 1 << 20
+
+const (
+	authNull     = 0
+	authPassword = 1
+)
+
+const (
+	startCapPromiscuous = 1
+	startCapDatagram    = 2
+	startCapServerOpen  = 4
+)
+
+const (
+	filterBpf = 1
+)
+
+const (
+	headerLength       = 8
+	packetHeaderLength = 20
+	sockaddrLength     = 128
+	familyInet         = 2
+	familyInet6        = 23
+)
+
+// Error is an error message received from, or sent to, the other side.
+type Error struct {
+	Code    uint16
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpcap: %s (error %d)", e.Message, e.Code)
+}
+
+type header struct {
+	version byte
+	kind    byte
+	value   uint16
+	length  uint32
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var b [headerLength]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return header{}, err
+	}
+	return header{
+		version: b[0],
+		kind:    b[1],
+		value:   binary.BigEndian.Uint16(b[2:]),
+		length:  binary.BigEndian.Uint32(b[4:]),
+	}, nil
+}
+
+func appendHeader(b []byte, kind byte, value uint16, length int) []byte {
+	b = append(b, Version, kind, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-6:], value)
+	binary.BigEndian.PutUint32(b[len(b)-4:], uint32(length))
+	return b
+}
+
+func writeMessage(w io.Writer, kind byte, value uint16, payload []byte) error {
+	b := appendHeader(make([]byte, 0, headerLength+len(payload)), kind, value, len(payload))
+	_, err := w.Write(append(b, payload...))
+	return err
+}
+
+func writeError(w io.Writer, code uint16, message string) error {
+	return writeMessage(w, msgError, code, []byte(message))
+}
+
+// readPayload reads the payload announced by h.
+func readPayload(r io.Reader, h header) ([]byte, error) {
+	if h.length > maxPayloadLength {
+		return nil, fmt.Errorf("rpcap: message of %d bytes is too large", h.length)
+	}
+	payload := make([]byte, h.length)
+	_, err := io.ReadFull(r, payload)
+	return payload, err
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendSockaddr encodes an address, or a netmask, as struct
+// rpcap_sockaddr; all zeros when it is missing.
+func appendSockaddr(b []byte, ip net.IP) []byte {
+	var s [sockaddrLength]byte
+	if v4 := ip.To4(); v4 != nil {
+		binary.BigEndian.PutUint16(s[0:], familyInet)
+		copy(s[4:8], v4)
+	} else if len(ip) == net.IPv6len {
+		binary.BigEndian.PutUint16(s[0:], familyInet6)
+		copy(s[8:24], ip)
+	}
+	return append(b, s[:]...)
+}
+
+func parseSockaddr(s []byte) net.IP {
+	switch binary.BigEndian.Uint16(s[0:]) {
+	case familyInet:
+		return net.IP(append([]byte(nil), s[4:8]...))
+	case familyInet6:
+		return net.IP(append([]byte(nil), s[8:24]...))
+	}
+	return nil
+}
+
+// appendFilter encodes a compiled BPF program as struct rpcap_filter
+// followed by its instructions.
+func appendFilter(b []byte, program []pcap.BPFInstruction) []byte {
+	b = appendUint16(b, filterBpf)
+	b = appendUint16(b, 0)
+	b = appendUint32(b, uint32(len(program)))
+	for _, i := range program {
+		b = appendUint16(b, i.Code)
+		b = append(b, i.Jt, i.Jf)
+		b = appendUint32(b, i.K)
+	}
+	return b
+}
+
+var errBadFilter = errors.New("rpcap: malformed filter")
+
+func parseFilter(b []byte) ([]pcap.BPFInstruction, error) {
+	if len(b) < 8 || binary.BigEndian.Uint16(b) != filterBpf {
+		return nil, errBadFilter
+	}
+	count := binary.BigEndian.Uint32(b[4:])
+	b = b[8:]
+	if uint64(len(b)) < uint64(count)*8 {
+		return nil, errBadFilter
+	}
+	program := make([]pcap.BPFInstruction, count)
+	for i := range program {
+		program[i] = pcap.BPFInstruction{
+			Code: binary.BigEndian.Uint16(b),
+			Jt:   b[2],
+			Jf:   b[3],
+			K:    binary.BigEndian.Uint32(b[4:]),
+		}
+		b = b[8:]
+	}
+	return program, nil
+}
@@ -0,0 +1,404 @@
+package rpcap
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sniffer/application/capture"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+const (
+	// authTimeout is how long a new connection has to authenticate.
+	authTimeout = 90 * time.Second
+	// wrongAuthDelay slows down password guessing.
+	wrongAuthDelay = time.Second
+	// dataTimeout is how long the client has to open the data connection.
+	dataTimeout = 10 * time.Second
+	// socketBuffer is the buffer size announced to clients; they size
+	// their receive buffer from it.
+	socketBuffer = 1 << 20
+)
+
+// Device is a live capture the server can serve.
+type Device interface {
+	capture.CaptureSource
+	LinkType() layers.LinkType
+	SetInstructionFilter(program []pcap.BPFInstruction) error
+}
+
+type ServerConfig struct {
+	// Username and Password are required from clients. If both are empty
+	// any client is accepted, as with rpcapd -n.
+	Username string
+	Password string
+	// Open opens a device; it defaults to libpcap.
+	Open func(device string, snapLength int, promiscuous bool) (Device, error)
+	// Interfaces lists devices; it defaults to libpcap.
+	Interfaces func() ([]pcap.Interface, error)
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Open:       openPcap,
+		Interfaces: pcap.FindAllDevs,
+	}
+}
+
+func openPcap(device string, snapLength int, promiscuous bool) (Device, error) {
+	return capture.NewPcapSource(device, snapLength, promiscuous)
+}
+
+// Server speaks the rpcapd protocol, so libpcap based tools such as
+// Wireshark can capture through it with rpcap://host/device. Frames go
+// over a separate TCP connection the client opens; UDP data transfer and
+// active mode are not supported.
+type Server struct {
+	config ServerConfig
+
+	mutex    sync.Mutex
+	sessions map[*session]struct{}
+}
+
+func NewServer(config ServerConfig) *Server {
+	if config.Open == nil {
+		config.Open = openPcap
+	}
+	if config.Interfaces == nil {
+		config.Interfaces = pcap.FindAllDevs
+	}
+	return &Server{config: config, sessions: make(map[*session]struct{})}
+}
+
+// Serve accepts clients until the listener fails.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		session := &session{server: s, conn: conn}
+		s.mutex.Lock()
+		s.sessions[session] = struct{}{}
+		s.mutex.Unlock()
+		go func() {
+			session.run()
+			s.mutex.Lock()
+			delete(s.sessions, session)
+			s.mutex.Unlock()
+		}()
+	}
+}
+
+// Close disconnects every client and stops their captures.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for session := range s.sessions {
+		session.conn.Close()
+	}
+}
+
+// session is one control connection.
+type session struct {
+	server        *Server
+	conn          net.Conn
+	authenticated bool
+	device        string
+	capture       *serverCapture
+}
+
+// serverCapture is the capture started by a session and its data
+// connection.
+type serverCapture struct {
+	device   Device
+	listener net.Listener
+	sent     uint32
+	done     chan struct{}
+}
+
+func (s *session) run() {
+	defer s.conn.Close()
+	defer s.endCapture()
+
+	s.conn.SetReadDeadline(time.Now().Add(authTimeout))
+	for {
+		h, err := readHeader(s.conn)
+		if err != nil {
+			return
+		}
+		payload, err := readPayload(s.conn, h)
+		if err != nil {
+			return
+		}
+		if h.version != Version {
+			writeError(s.conn, ErrWrongVersion, fmt.Sprintf("protocol version %d is not supported", h.version))
+			continue
+		}
+		if h.kind == msgClose {
+			return
+		}
+		if !s.authenticated && h.kind != msgAuthRequest {
+			writeError(s.conn, ErrAuth, "authentication required")
+			return
+		}
+		if err := s.handle(h, payload); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers one request. It returns an error only when the session
+// must end.
+func (s *session) handle(h header, payload []byte) error {
+	switch h.kind {
+	case msgAuthRequest:
+		return s.authenticate(payload)
+	case msgFindAllIfRequest:
+		return s.findAllInterfaces()
+	case msgOpenRequest:
+		return s.open(string(payload))
+	case msgStartCapRequest:
+		return s.startCapture(payload)
+	case msgUpdateFilterReq:
+		return s.updateFilter(payload)
+	case msgStatsRequest:
+		return s.stats()
+	case msgEndCapRequest:
+		s.endCapture()
+		return writeMessage(s.conn, msgEndCapRequest|msgReply, 0, nil)
+	case msgSetSamplingReq:
+		if len(payload) < 8 || payload[0] != 0 {
+			return writeError(s.conn, ErrSetSampling, "sampling is not supported")
+		}
+		return writeMessage(s.conn, msgSetSamplingReq|msgReply, 0, nil)
+	}
+	return writeError(s.conn, ErrWrongMessage, fmt.Sprintf("unexpected message type %d", h.kind))
+}
+
+func (s *session) authenticate(payload []byte) error {
+	if len(payload) < 8 {
+		writeError(s.conn, ErrAuth, "malformed authentication request")
+		return errors.New("malformed authentication")
+	}
+	config := s.server.config
+	open := config.Username == "" && config.Password == ""
+	switch binary.BigEndian.Uint16(payload) {
+	case authNull:
+		if !open {
+			time.Sleep(wrongAuthDelay)
+			writeError(s.conn, ErrAuthFailed, "NULL authentication is not permitted")
+			return errors.New("authentication failed")
+		}
+	case authPassword:
+		userLength := int(binary.BigEndian.Uint16(payload[4:]))
+		passwordLength := int(binary.BigEndian.Uint16(payload[6:]))
+		if len(payload) < 8+userLength+passwordLength {
+			writeError(s.conn, ErrAuth, "malformed authentication request")
+			return errors.New("malformed authentication")
+		}
+		user := payload[8 : 8+userLength]
+		password := payload[8+userLength : 8+userLength+passwordLength]
+		userOk := subtle.ConstantTimeCompare(user, []byte(config.Username)) == 1
+		passwordOk := subtle.ConstantTimeCompare(password, []byte(config.Password)) == 1
+		if !open && !(userOk && passwordOk) {
+			time.Sleep(wrongAuthDelay)
+			writeError(s.conn, ErrAuthFailed, "authentication failed")
+			return errors.New("authentication failed")
+		}
+	default:
+		writeError(s.conn, ErrAuthTypeNotSupported, "authentication type not supported")
+		return errors.New("unsupported authentication")
+	}
+
+	s.authenticated = true
+	s.conn.SetReadDeadline(time.Time{})
+	// struct rpcap_authreply: the lowest and highest version we speak.
+	return writeMessage(s.conn, msgAuthRequest|msgReply, 0, []byte{Version, Version})
+}
+
+func (s *session) findAllInterfaces() error {
+	interfaces, err := s.server.config.Interfaces()
+	if err != nil {
+		return writeError(s.conn, ErrFindAllIf, err.Error())
+	}
+	var b []byte
+	for _, i := range interfaces {
+		b = appendUint16(b, uint16(len(i.Name)))
+		b = appendUint16(b, uint16(len(i.Description)))
+		b = appendUint32(b, i.Flags)
+		b = appendUint16(b, uint16(len(i.Addresses)))
+		b = appendUint16(b, 0)
+		b = append(b, i.Name...)
+		b = append(b, i.Description...)
+		for _, address := range i.Addresses {
+			b = appendSockaddr(b, address.IP)
+			b = appendSockaddr(b, net.IP(address.Netmask))
+			b = appendSockaddr(b, address.Broadaddr)
+			b = appendSockaddr(b, address.P2P)
+		}
+	}
+	return writeMessage(s.conn, msgFindAllIfRequest|msgReply, uint16(len(interfaces)), b)
+}
+
+// open checks the device and reports its link type. Like rpcapd, the
+// device is opened again with the client's settings when capture starts.
+func (s *session) open(device string) error {
+	d, err := s.server.config.Open(device, 1500, false)
+	if err != nil {
+		return writeError(s.conn, ErrOpen, err.Error())
+	}
+	linkType := d.LinkType()
+	d.Close()
+	s.device = device
+
+	var b []byte
+	b = appendUint32(b, uint32(linkType))
+	b = appendUint32(b, 0) // tzoff
+	return writeMessage(s.conn, msgOpenRequest|msgReply, 0, b)
+}
+
+func (s *session) startCapture(payload []byte) error {
+	if s.device == "" {
+		return writeError(s.conn, ErrStartCapture, "no device is open")
+	}
+	if s.capture != nil {
+		return writeError(s.conn, ErrStartCapture, "a capture is already running")
+	}
+	if len(payload) < 12 {
+		return writeError(s.conn, ErrStartCapture, "malformed start capture request")
+	}
+	snapLength := int(binary.BigEndian.Uint32(payload[0:]))
+	flags := binary.BigEndian.Uint16(payload[8:])
+	if flags&startCapDatagram != 0 {
+		return writeError(s.conn, ErrStartCapture, "UDP data transfer is not supported")
+	}
+	if flags&startCapServerOpen != 0 {
+		return writeError(s.conn, ErrStartCapture, "active mode is not supported")
+	}
+	program, err := parseFilter(payload[12:])
+	if err != nil {
+		return writeError(s.conn, ErrStartCapture, err.Error())
+	}
+	if snapLength <= 0 || snapLength > 262144 {
+		snapLength = 262144
+	}
+
+	device, err := s.server.config.Open(s.device, snapLength, flags&startCapPromiscuous != 0)
+	if err != nil {
+		return writeError(s.conn, ErrStartCapture, err.Error())
+	}
+	if err := device.SetInstructionFilter(program); err != nil {
+		device.Close()
+		return writeError(s.conn, ErrStartCapture, err.Error())
+	}
+	local := s.conn.LocalAddr().(*net.TCPAddr)
+	listener, err := net.Listen("tcp", net.JoinHostPort(local.IP.String(), "0"))
+	if err != nil {
+		device.Close()
+		return writeError(s.conn, ErrStartCapture, err.Error())
+	}
+	c := &serverCapture{device: device, listener: listener, done: make(chan struct{})}
+	s.capture = c
+	go c.run(s.conn.RemoteAddr().(*net.TCPAddr).IP)
+
+	var b []byte
+	b = appendUint32(b, socketBuffer)
+	b = appendUint16(b, uint16(listener.Addr().(*net.TCPAddr).Port))
+	b = appendUint16(b, 0)
+	return writeMessage(s.conn, msgStartCapRequest|msgReply, 0, b)
+}
+
+// run waits for the client's data connection and sends it every frame.
+func (c *serverCapture) run(client net.IP) {
+	defer close(c.done)
+	defer c.device.Close()
+
+	var data net.Conn
+	deadline := time.Now().Add(dataTimeout)
+	for data == nil {
+		c.listener.(*net.TCPListener).SetDeadline(deadline)
+		conn, err := c.listener.Accept()
+		if err != nil {
+			c.listener.Close()
+			return
+		}
+		// Only the client that asked for the capture may collect it.
+		if !conn.RemoteAddr().(*net.TCPAddr).IP.Equal(client) {
+			conn.Close()
+			continue
+		}
+		data = conn
+	}
+	c.listener.Close()
+	defer data.Close()
+
+	var b []byte
+	for {
+		frame, err := c.device.Next()
+		if err != nil {
+			return
+		}
+		number := atomic.AddUint32(&c.sent, 1)
+		b = appendHeader(b[:0], msgPacket, 0, packetHeaderLength+len(frame.Data))
+		b = appendUint32(b, uint32(frame.Timestamp.Unix()))
+		b = appendUint32(b, uint32(frame.Timestamp.Nanosecond()/1000))
+		b = appendUint32(b, uint32(len(frame.Data)))
+		b = appendUint32(b, uint32(frame.Length))
+		b = appendUint32(b, number)
+		b = append(b, frame.Data...)
+		if _, err := data.Write(b); err != nil {
+			return
+		}
+	}
+}
+
+func (s *session) endCapture() {
+	if s.capture == nil {
+		return
+	}
+	s.capture.listener.Close()
+	s.capture.device.Close()
+	<-s.capture.done
+	s.capture = nil
+}
+
+func (s *session) updateFilter(payload []byte) error {
+	if s.capture == nil {
+		return writeError(s.conn, ErrUpdateFilter, "no capture is running")
+	}
+	program, err := parseFilter(payload)
+	if err == nil {
+		err = s.capture.device.SetInstructionFilter(program)
+	}
+	if err != nil {
+		return writeError(s.conn, ErrUpdateFilter, err.Error())
+	}
+	return writeMessage(s.conn, msgUpdateFilterReq|msgReply, 0, nil)
+}
+
+func (s *session) stats() error {
+	var stats capture.Stats
+	var sent uint32
+	if s.capture != nil {
+		var err error
+		if stats, err = s.capture.device.Stats(); err != nil {
+			return writeError(s.conn, ErrGetStats, err.Error())
+		}
+		sent = atomic.LoadUint32(&s.capture.sent)
+	}
+	var b []byte
+	b = appendUint32(b, uint32(stats.Received))
+	b = appendUint32(b, uint32(stats.InterfaceDropped))
+	b = appendUint32(b, uint32(stats.Dropped))
+	b = appendUint32(b, sent)
+	return writeMessage(s.conn, msgStatsRequest|msgReply, 0, b)
+}
@@ -0,0 +1,340 @@
+package rpcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sniffer/application/capture"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+const dialTimeout = 10 * time.Second
+
+type SourceConfig struct {
+	// Address is the rpcapd host:port.
+	Address  string
+	Device   string
+	Username string
+	Password string
+	// Filter is a BPF expression in tcpdump syntax. It is compiled here
+	// and run on the server.
+	Filter      string
+	SnapLength  int
+	Promiscuous bool
+}
+
+// ParseUrl reads rpcap://[user:password@]host[:port]/device, the form
+// libpcap and Wireshark use. The device may be empty.
+func ParseUrl(text string) (SourceConfig, error) {
+	u, err := url.Parse(text)
+	if err != nil {
+		return SourceConfig{}, err
+	}
+	if u.Scheme != "rpcap" || u.Host == "" {
+		return SourceConfig{}, fmt.Errorf("%s: not an rpcap://host/device URL", text)
+	}
+	config := SourceConfig{
+		Address:     u.Host,
+		Device:      u.Path,
+		SnapLength:  65535,
+		Promiscuous: true,
+	}
+	if len(config.Device) > 0 && config.Device[0] == '/' {
+		config.Device = config.Device[1:]
+	}
+	if u.Port() == "" {
+		config.Address = net.JoinHostPort(u.Hostname(), strconv.Itoa(DefaultPort))
+	}
+	if u.User != nil {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+	return config, nil
+}
+
+// controlConn is an authenticated control connection.
+type controlConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(config SourceConfig) (*controlConn, error) {
+	conn, err := net.DialTimeout("tcp", config.Address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &controlConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	var auth []byte
+	if config.Username == "" && config.Password == "" {
+		auth = appendUint16(auth, authNull)
+		auth = append(auth, 0, 0, 0, 0, 0, 0)
+	} else {
+		auth = appendUint16(auth, authPassword)
+		auth = appendUint16(auth, 0)
+		auth = appendUint16(auth, uint16(len(config.Username)))
+		auth = appendUint16(auth, uint16(len(config.Password)))
+		auth = append(auth, config.Username...)
+		auth = append(auth, config.Password...)
+	}
+	if _, _, err := c.call(msgAuthRequest, auth); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// call sends a request and returns the value and payload of its reply.
+func (c *controlConn) call(kind byte, payload []byte) (uint16, []byte, error) {
+	c.conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := writeMessage(c.conn, kind, 0, payload); err != nil {
+		return 0, nil, err
+	}
+	h, err := readHeader(c.reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	reply, err := readPayload(c.reader, h)
+	if err != nil {
+		return 0, nil, err
+	}
+	if h.kind == msgError {
+		return 0, nil, &Error{Code: h.value, Message: string(reply)}
+	}
+	if h.kind != kind|msgReply {
+		return 0, nil, fmt.Errorf("rpcap: unexpected reply type %d to request %d", h.kind, kind)
+	}
+	return h.value, reply, nil
+}
+
+func (c *controlConn) close() {
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	writeMessage(c.conn, msgClose, 0, nil)
+	c.conn.Close()
+}
+
+// RemoteInterface is a device offered by an rpcap server.
+type RemoteInterface struct {
+	Name        string
+	Description string
+	Addresses   []net.IP
+}
+
+// FindAllDevs lists the devices of the server in config.
+func FindAllDevs(config SourceConfig) ([]RemoteInterface, error) {
+	c, err := dial(config)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	count, b, err := c.call(msgFindAllIfRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	errTruncated := errors.New("rpcap: truncated interface list")
+	var result []RemoteInterface
+	for i := 0; i < int(count); i++ {
+		if len(b) < 12 {
+			return nil, errTruncated
+		}
+		nameLength := int(binary.BigEndian.Uint16(b[0:]))
+		descriptionLength := int(binary.BigEndian.Uint16(b[2:]))
+		addresses := int(binary.BigEndian.Uint16(b[8:]))
+		b = b[12:]
+		if len(b) < nameLength+descriptionLength+addresses*4*sockaddrLength {
+			return nil, errTruncated
+		}
+		iface := RemoteInterface{
+			Name:        string(b[:nameLength]),
+			Description: string(b[nameLength : nameLength+descriptionLength]),
+		}
+		b = b[nameLength+descriptionLength:]
+		for j := 0; j < addresses; j++ {
+			if ip := parseSockaddr(b); ip != nil {
+				iface.Addresses = append(iface.Addresses, ip)
+			}
+			b = b[4*sockaddrLength:]
+		}
+		result = append(result, iface)
+	}
+	return result, nil
+}
+
+// Source captures on a remote rpcapd, or on "sniffer rpcapd", and is used
+// like a local capture source.
+type Source struct {
+	config   SourceConfig
+	control  *controlConn
+	mutex    sync.Mutex
+	data     net.Conn
+	reader   *bufio.Reader
+	linkType layers.LinkType
+	name     string
+}
+
+func NewSource(config SourceConfig) (*Source, error) {
+	if config.Device == "" {
+		return nil, errors.New("rpcap: no device given")
+	}
+	if config.SnapLength <= 0 {
+		config.SnapLength = 65535
+	}
+	c, err := dial(config)
+	if err != nil {
+		return nil, err
+	}
+	s := &Source{config: config, control: c, name: config.Address + "/" + config.Device}
+	if err := s.start(); err != nil {
+		c.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Source) start() error {
+	_, reply, err := s.control.call(msgOpenRequest, []byte(s.config.Device))
+	if err != nil {
+		return err
+	}
+	if len(reply) < 8 {
+		return errors.New("rpcap: short open reply")
+	}
+	s.linkType = layers.LinkType(binary.BigEndian.Uint32(reply))
+
+	// The frames travel over the same network, so keep them out of the
+	// capture: first the control connection, then the data connection too.
+	program, err := s.compile(nil)
+	if err != nil {
+		return err
+	}
+	var request []byte
+	request = appendUint32(request, uint32(s.config.SnapLength))
+	request = appendUint32(request, 1000) // read timeout, ms
+	var flags uint16
+	if s.config.Promiscuous {
+		flags |= startCapPromiscuous
+	}
+	request = appendUint16(request, flags)
+	request = appendUint16(request, 0)
+	request = appendFilter(request, program)
+	_, reply, err = s.control.call(msgStartCapRequest, request)
+	if err != nil {
+		return err
+	}
+	if len(reply) < 8 {
+		return errors.New("rpcap: short start capture reply")
+	}
+	port := binary.BigEndian.Uint16(reply[4:])
+	host, _, _ := net.SplitHostPort(s.config.Address)
+	data, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), dialTimeout)
+	if err != nil {
+		return err
+	}
+	s.data = data
+	s.reader = bufio.NewReaderSize(data, 1<<16)
+
+	if program, err = s.compile(data); err != nil {
+		data.Close()
+		return err
+	}
+	if _, _, err := s.control.call(msgUpdateFilterReq, appendFilter(nil, program)); err != nil {
+		data.Close()
+		return err
+	}
+	return nil
+}
+
+// compile builds the filter sent to the server: the user's filter minus
+// our own connections.
+func (s *Source) compile(data net.Conn) ([]pcap.BPFInstruction, error) {
+	own := []net.Conn{s.control.conn}
+	if data != nil {
+		own = append(own, data)
+	}
+	expression := ""
+	for _, conn := range own {
+		local := conn.LocalAddr().(*net.TCPAddr)
+		remote := conn.RemoteAddr().(*net.TCPAddr)
+		if expression != "" {
+			expression += " and "
+		}
+		expression += fmt.Sprintf("not (host %s and host %s and tcp port %d and tcp port %d)",
+			local.IP, remote.IP, local.Port, remote.Port)
+	}
+	if s.config.Filter != "" {
+		expression = "(" + s.config.Filter + ") and " + expression
+	}
+	return pcap.CompileBPFFilter(s.linkType, s.config.SnapLength, expression)
+}
+
+func (s *Source) Next() (capture.Frame, error) {
+	for {
+		h, err := readHeader(s.reader)
+		if err != nil {
+			return capture.Frame{}, err
+		}
+		payload, err := readPayload(s.reader, h)
+		if err != nil {
+			return capture.Frame{}, err
+		}
+		if h.kind == msgError {
+			return capture.Frame{}, &Error{Code: h.value, Message: string(payload)}
+		}
+		if h.kind != msgPacket || len(payload) < packetHeaderLength {
+			continue
+		}
+		captureLength := int(binary.BigEndian.Uint32(payload[8:]))
+		if captureLength > len(payload)-packetHeaderLength {
+			captureLength = len(payload) - packetHeaderLength
+		}
+		seconds := int64(binary.BigEndian.Uint32(payload[0:]))
+		microseconds := int64(binary.BigEndian.Uint32(payload[4:]))
+		return capture.Frame{
+			Data:          payload[packetHeaderLength : packetHeaderLength+captureLength],
+			Timestamp:     time.Unix(seconds, microseconds*1000),
+			CaptureLength: captureLength,
+			Length:        int(binary.BigEndian.Uint32(payload[12:])),
+			Interface:     s.name,
+			LinkType:      s.linkType,
+		}, nil
+	}
+}
+
+// Stats asks the server for its counters.
+func (s *Source) Stats() (capture.Stats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, reply, err := s.control.call(msgStatsRequest, nil)
+	if err != nil {
+		return capture.Stats{}, err
+	}
+	if len(reply) < 16 {
+		return capture.Stats{}, errors.New("rpcap: short stats reply")
+	}
+	return capture.Stats{
+		Received:         uint64(binary.BigEndian.Uint32(reply[0:])),
+		InterfaceDropped: uint64(binary.BigEndian.Uint32(reply[4:])),
+		Dropped:          uint64(binary.BigEndian.Uint32(reply[8:])),
+	}, nil
+}
+
+func (s *Source) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.control.call(msgEndCapRequest, nil)
+	s.control.close()
+	s.data.Close()
+}
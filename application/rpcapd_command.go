@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sniffer/application/rpcap"
+	"strconv"
+	"syscall"
+)
+
+// runRpcapd implements "sniffer rpcapd [flags]": a remote capture server
+// Wireshark can use with rpcap://host/interface.
+func runRpcapd(args []string) int {
+	flags := flag.NewFlagSet("rpcapd", flag.ExitOnError)
+	listen := flags.String("listen", ":"+strconv.Itoa(rpcap.DefaultPort), "accept clients on this address")
+	user := flags.String("user", "", "username clients must send")
+	password := flags.String("password", "", "password clients must send")
+	nullAuth := flags.Bool("null-auth", false, "accept clients without a username and password")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sniffer rpcapd -user NAME -password SECRET [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	if *user == "" && *password == "" && !*nullAuth {
+		fmt.Println("rpcapd: set -user and -password, or -null-auth to accept anyone")
+		return 2
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println("rpcapd:", err)
+		return 1
+	}
+
+	config := rpcap.DefaultServerConfig()
+	config.Username = *user
+	config.Password = *password
+	server := rpcap.NewServer(config)
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.Serve(listener)
+	}()
+	fmt.Println("rpcapd: listening on", listener.Addr())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	status := 0
+	select {
+	case <-interrupt:
+	case err := <-failed:
+		fmt.Println("rpcapd:", err)
+		status = 1
+	}
+	listener.Close()
+	server.Close()
+	return status
+}
@@ -0,0 +1,304 @@
+package rule
+
+import (
+	"bytes"
+	"net"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sync"
+	"time"
+)
+
+const (
+	flowTrackLimit = 65536
+	flowIdle       = 5 * time.Minute
+	// Snort and Suricata ignore the ECN bits unless a rule names them.
+	ecnFlags = 64 | 128
+)
+
+type compiledContent struct {
+	Content
+	// folded is the lower-cased pattern used by nocase matches.
+	folded []byte
+}
+
+type compiledRule struct {
+	Rule
+	protocol byte
+	contents []compiledContent
+}
+
+type Match struct {
+	Rule        *Rule
+	Time        time.Time
+	Observation flow.Observation
+	// ToServer tells which side of the tracked connection sent the packet.
+	ToServer bool
+}
+
+type connection struct {
+	client      flow.Endpoint
+	synSeen     bool
+	synAckSeen  bool
+	established bool
+	// passed is set once a pass rule matched the TCP connection.
+	passed   bool
+	lastSeen time.Time
+}
+
+// Engine evaluates pass rules before the others, as Suricata's default
+// action order does. A matching pass rule ends inspection of the packet
+// and, for TCP, of the rest of its connection.
+type Engine struct {
+	passRules   []compiledRule
+	rules       []compiledRule
+	mutex       sync.Mutex
+	connections map[flow.Key]*connection
+}
+
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{connections: make(map[flow.Key]*connection)}
+	for _, r := range rules {
+		compiled := compiledRule{Rule: r}
+		switch r.Protocol {
+		case "tcp":
+			compiled.protocol = flow.IpProtocolTcp
+		case "udp":
+			compiled.protocol = flow.IpProtocolUdp
+		case "icmp":
+			compiled.protocol = flow.IpProtocolIcmp
+		}
+		for _, c := range r.Contents {
+			compiled.contents = append(compiled.contents, compiledContent{Content: c, folded: bytes.ToLower(c.Pattern)})
+		}
+		if r.Action == "pass" {
+			e.passRules = append(e.passRules, compiled)
+		} else {
+			e.rules = append(e.rules, compiled)
+		}
+	}
+	return e
+}
+
+func (e *Engine) Len() int {
+	return len(e.passRules) + len(e.rules)
+}
+
+// packetState is what the rules are evaluated against.
+type packetState struct {
+	o           flow.Observation
+	source      net.IP
+	destination net.IP
+	payload     []byte
+	toServer    bool
+	established bool
+}
+
+// Match evaluates every rule against p and returns the ones that fired, in
+// rule file order. Nothing fires on a packet or connection a pass rule
+// matched.
+func (e *Engine) Match(p packet.Parsable, timestamp time.Time) []Match {
+	o, ok := flow.Observe(p)
+	if !ok {
+		return nil
+	}
+	state := packetState{
+		o:           o,
+		source:      net.IP(o.Source.Address[:]),
+		destination: net.IP(o.Destination.Address[:]),
+		payload:     payloadOf(o.Transport),
+	}
+	var passed bool
+	state.toServer, state.established, passed = e.track(o, timestamp)
+	if passed {
+		return nil
+	}
+
+	for i := range e.passRules {
+		if e.passRules[i].matches(&state) {
+			if o.Protocol == flow.IpProtocolTcp {
+				e.pass(o)
+			}
+			return nil
+		}
+	}
+
+	var matches []Match
+	for i := range e.rules {
+		r := &e.rules[i]
+		if r.matches(&state) {
+			matches = append(matches, Match{Rule: &r.Rule, Time: timestamp, Observation: o, ToServer: state.toServer})
+		}
+	}
+	return matches
+}
+
+func (r *compiledRule) matches(s *packetState) bool {
+	o := s.o
+	if r.protocol != 0 && r.protocol != o.Protocol {
+		return false
+	}
+	if !r.matchesEndpoints(s.source, o.Source.Port, s.destination, o.Destination.Port) {
+		return false
+	}
+	if !r.matchesFlow(s.toServer, s.established) {
+		return false
+	}
+	if r.Flags != nil && (o.Protocol != flow.IpProtocolTcp || !r.Flags.matches(o.TcpFlags)) {
+		return false
+	}
+	if r.Itype != nil && (o.Protocol != flow.IpProtocolIcmp || !r.Itype.Matches(int(o.IcmpType))) {
+		return false
+	}
+	if r.Icode != nil && (o.Protocol != flow.IpProtocolIcmp || !r.Icode.Matches(int(o.IcmpCode))) {
+		return false
+	}
+	return r.matchesContents(s.payload)
+}
+
+// payloadOf returns the transport payload, which the IPv4 layer already
+// trimmed to its total length, so Ethernet padding never matches.
+func payloadOf(transport packet.Parsable) []byte {
+	switch layer := transport.(type) {
+	case packet.TcpPacket:
+		return layer.RawPayload
+	case packet.UdpPacket:
+		return layer.RawPayload
+	case packet.IcmpV4Packet:
+		return layer.RawPayload
+	}
+	return nil
+}
+
+func (r *compiledRule) matchesEndpoints(source net.IP, sourcePort uint16, destination net.IP, destPort uint16) bool {
+	forward := r.Source.Matches(source) && r.Destination.Matches(destination)
+	if forward && r.protocol != flow.IpProtocolIcmp && r.protocol != 0 {
+		forward = r.SourcePorts.Matches(sourcePort) && r.DestPorts.Matches(destPort)
+	}
+	if forward || !r.Bidirectional {
+		return forward
+	}
+
+	backward := r.Source.Matches(destination) && r.Destination.Matches(source)
+	if backward && r.protocol != flow.IpProtocolIcmp && r.protocol != 0 {
+		backward = r.SourcePorts.Matches(destPort) && r.DestPorts.Matches(sourcePort)
+	}
+	return backward
+}
+
+func (r *compiledRule) matchesFlow(toServer bool, established bool) bool {
+	f := r.Flow
+	if f.ToServer && !toServer || f.ToClient && toServer {
+		return false
+	}
+	if f.Established && !established || f.NotEstablished && established {
+		return false
+	}
+	return true
+}
+
+func (r *compiledRule) matchesContents(payload []byte) bool {
+	var folded []byte
+	for _, c := range r.contents {
+		pattern, haystack := c.Pattern, payload
+		if c.Nocase {
+			if folded == nil {
+				folded = bytes.ToLower(payload)
+			}
+			pattern, haystack = c.folded, folded
+		}
+		if c.found(pattern, haystack) == c.Negated {
+			return false
+		}
+	}
+	return true
+}
+
+func (c compiledContent) found(pattern []byte, payload []byte) bool {
+	if c.Offset >= len(payload) {
+		return false
+	}
+	window := payload[c.Offset:]
+	if c.Depth > 0 && c.Depth < len(window) {
+		window = window[:c.Depth]
+	}
+	return bytes.Contains(window, pattern)
+}
+
+func (f FlagMatch) matches(flags byte) bool {
+	flags &^= f.Ignored
+	if f.Flags&ecnFlags == 0 {
+		flags &^= ecnFlags
+	}
+	switch f.Modifier {
+	case '+':
+		return flags&f.Flags == f.Flags
+	case '*':
+		return flags&f.Flags != 0
+	case '!':
+		return flags&f.Flags == 0
+	}
+	return flags == f.Flags
+}
+
+// track follows connections so flow:to_server and flow:established can be
+// evaluated. The client is the side that sent the SYN, or the first packet
+// for UDP and ICMP.
+func (e *Engine) track(o flow.Observation, timestamp time.Time) (toServer bool, established bool, passed bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key, _ := o.Key()
+	c, ok := e.connections[key]
+	if !ok {
+		if len(e.connections) >= flowTrackLimit {
+			e.expire(timestamp)
+		}
+		c = &connection{client: o.Source}
+		e.connections[key] = c
+	}
+	c.lastSeen = timestamp
+
+	syn := o.TcpFlags&packet.TcpFlagSyn != 0
+	ack := o.TcpFlags&packet.TcpFlagAck != 0
+	switch {
+	case o.Protocol != flow.IpProtocolTcp:
+		if o.Source != c.client {
+			c.established = true
+		}
+	case o.TcpFlags&packet.TcpFlagRst != 0:
+		c.established = false
+	case syn && !ack:
+		c.client = o.Source
+		c.synSeen = true
+		// A new handshake on the same 5-tuple is a new connection.
+		c.passed = false
+	case syn && ack:
+		c.synAckSeen = true
+	case ack && c.synSeen && c.synAckSeen:
+		c.established = true
+	}
+
+	return o.Source == c.client, c.established, c.passed
+}
+
+func (e *Engine) pass(o flow.Observation) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key, _ := o.Key()
+	if c, ok := e.connections[key]; ok {
+		c.passed = true
+	}
+}
+
+func (e *Engine) expire(now time.Time) {
+	for key, c := range e.connections {
+		if now.Sub(c.lastSeen) > flowIdle {
+			delete(e.connections, key)
+		}
+	}
+	if len(e.connections) >= flowTrackLimit {
+		e.connections = make(map[flow.Key]*connection)
+	}
+}
@@ -0,0 +1,98 @@
+package rule
+
+import (
+	"encoding/json"
+	"io"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"strings"
+	"sync"
+)
+
+const eveTimeFormat = "2006-01-02T15:04:05.000000-0700"
+
+type EveAlert struct {
+	Action      string `json:"action"`
+	Gid         int    `json:"gid"`
+	SignatureId int    `json:"signature_id"`
+	Rev         int    `json:"rev"`
+	Signature   string `json:"signature"`
+	Category    string `json:"category"`
+	Severity    int    `json:"severity"`
+}
+
+// EveEvent is the subset of Suricata's EVE-JSON alert record we can fill in.
+type EveEvent struct {
+	Timestamp string   `json:"timestamp"`
+	EventType string   `json:"event_type"`
+	SrcIp     string   `json:"src_ip"`
+	SrcPort   uint16   `json:"src_port,omitempty"`
+	DestIp    string   `json:"dest_ip"`
+	DestPort  uint16   `json:"dest_port,omitempty"`
+	Proto     string   `json:"proto"`
+	IcmpType  *byte    `json:"icmp_type,omitempty"`
+	IcmpCode  *byte    `json:"icmp_code,omitempty"`
+	Direction string   `json:"direction"`
+	Alert     EveAlert `json:"alert"`
+}
+
+func NewEveEvent(m Match) EveEvent {
+	o := m.Observation
+	event := EveEvent{
+		Timestamp: m.Time.Format(eveTimeFormat),
+		EventType: "alert",
+		SrcIp:     packet.IpAddress{Value: o.Source.Address[:]}.ToString(),
+		DestIp:    packet.IpAddress{Value: o.Destination.Address[:]}.ToString(),
+		Proto:     eveProtocol(o.Protocol),
+		Direction: "to_client",
+		Alert: EveAlert{
+			Action:      "allowed",
+			Gid:         1,
+			SignatureId: m.Rule.Sid,
+			Rev:         m.Rule.Rev,
+			Signature:   m.Rule.Msg,
+			Category:    m.Rule.Classtype,
+			Severity:    m.Rule.Priority,
+		},
+	}
+	if m.ToServer {
+		event.Direction = "to_server"
+	}
+	if m.Rule.Action == "drop" || m.Rule.Action == "reject" {
+		// We only observe traffic, report what an inline engine would have done.
+		event.Alert.Action = "blocked"
+	}
+
+	if o.Protocol == flow.IpProtocolIcmp {
+		icmpType, icmpCode := o.IcmpType, o.IcmpCode
+		event.IcmpType, event.IcmpCode = &icmpType, &icmpCode
+	} else {
+		event.SrcPort = o.Source.Port
+		event.DestPort = o.Destination.Port
+	}
+	return event
+}
+
+func eveProtocol(p byte) string {
+	if p == flow.IpProtocolIcmp {
+		return "ICMP"
+	}
+	return strings.ToUpper(flow.ProtocolName(p))
+}
+
+// EveWriter writes one JSON event per line, as Suricata's eve.json does.
+type EveWriter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewEveWriter(w io.Writer) *EveWriter {
+	return &EveWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *EveWriter) Write(m Match) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.encoder.Encode(NewEveEvent(m))
+}
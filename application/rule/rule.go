@@ -0,0 +1,606 @@
+package rule
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sniffer/application/search"
+	"strconv"
+	"strings"
+)
+
+type Rule struct {
+	Action      string
+	Protocol    string
+	Source      AddressSet
+	SourcePorts PortSet
+	Destination AddressSet
+	DestPorts   PortSet
+	// Bidirectional is set for the <> operator.
+	Bidirectional bool
+
+	Sid       int
+	Rev       int
+	Msg       string
+	Classtype string
+	Priority  int
+	Contents  []Content
+	Flags     *FlagMatch
+	Itype     *NumberMatch
+	Icode     *NumberMatch
+	Flow      FlowMatch
+	Raw       string
+}
+
+type Content struct {
+	Pattern []byte
+	Negated bool
+	Nocase  bool
+	Offset  int
+	// Depth is 0 when the pattern may appear anywhere after Offset.
+	Depth int
+}
+
+type FlagMatch struct {
+	Flags byte
+	// Modifier is 0 for an exact match, '+' for "at least these", '*' for
+	// "any of these" and '!' for "none of these".
+	Modifier byte
+	Ignored  byte
+}
+
+type NumberMatch struct {
+	// Operator is one of '=', '<', '>' or 'r' for the min<>max range form.
+	Operator byte
+	Value    int
+	Max      int
+}
+
+func (n NumberMatch) Matches(v int) bool {
+	switch n.Operator {
+	case '<':
+		return v < n.Value
+	case '>':
+		return v > n.Value
+	case 'r':
+		return v > n.Value && v < n.Max
+	}
+	return v == n.Value
+}
+
+type FlowMatch struct {
+	ToServer       bool
+	ToClient       bool
+	Established    bool
+	NotEstablished bool
+	Stateless      bool
+}
+
+type AddressSet struct {
+	Any      bool
+	Networks []*net.IPNet
+	Negated  []*net.IPNet
+}
+
+type PortRange struct {
+	Low  uint16
+	High uint16
+}
+
+type PortSet struct {
+	Any     bool
+	Ranges  []PortRange
+	Negated []PortRange
+}
+
+// Variables resolves $NAME references in address and port fields.
+type Variables map[string]string
+
+func DefaultVariables() Variables {
+	return Variables{
+		"HOME_NET":     "[192.168.0.0/16,10.0.0.0/8,172.16.0.0/12]",
+		"EXTERNAL_NET": "any",
+		"HTTP_PORTS":   "80",
+		"SSH_PORTS":    "22",
+	}
+}
+
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func LoadFile(path string, variables Variables) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Load(file, variables)
+}
+
+// Load parses one rule per line, skipping blanks and # comments. Lines ending
+// with a backslash continue on the next line.
+func Load(r io.Reader, variables Variables) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	pending := ""
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		line = strings.TrimSpace(pending + line)
+		pending = ""
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := Parse(line, variables)
+		if err != nil {
+			return nil, ParseError{Line: lineNumber, Err: err}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func Parse(text string, variables Variables) (Rule, error) {
+	open := strings.Index(text, "(")
+	if open < 0 || !strings.HasSuffix(text, ")") {
+		return Rule{}, errors.New("rule options must be enclosed in parentheses")
+	}
+
+	header := strings.Fields(text[:open])
+	if len(header) != 7 {
+		return Rule{}, fmt.Errorf("rule header needs 7 fields, got %d", len(header))
+	}
+
+	r := Rule{Raw: text, Action: header[0], Protocol: strings.ToLower(header[1]), Rev: 1, Priority: 3}
+	switch r.Action {
+	case "alert", "drop", "reject", "pass":
+	case "log":
+		return Rule{}, errors.New("the log action is not supported, use alert")
+	default:
+		return Rule{}, fmt.Errorf("unknown action %q", r.Action)
+	}
+	switch r.Protocol {
+	case "ip", "tcp", "udp", "icmp":
+	default:
+		return Rule{}, fmt.Errorf("unsupported protocol %q", r.Protocol)
+	}
+	switch header[4] {
+	case "->":
+	case "<>":
+		r.Bidirectional = true
+	default:
+		return Rule{}, fmt.Errorf("unknown direction %q", header[4])
+	}
+
+	var err error
+	if r.Source, err = parseAddressSet(header[2], variables); err != nil {
+		return Rule{}, err
+	}
+	if r.SourcePorts, err = parsePortSet(header[3], variables); err != nil {
+		return Rule{}, err
+	}
+	if r.Destination, err = parseAddressSet(header[5], variables); err != nil {
+		return Rule{}, err
+	}
+	if r.DestPorts, err = parsePortSet(header[6], variables); err != nil {
+		return Rule{}, err
+	}
+
+	if err = r.parseOptions(text[open+1 : len(text)-1]); err != nil {
+		return Rule{}, err
+	}
+	if r.Sid == 0 {
+		return Rule{}, errors.New("rule has no sid")
+	}
+	return r, nil
+}
+
+func (r *Rule) parseOptions(text string) error {
+	for _, option := range splitOptions(text) {
+		name, value := option, ""
+		if colon := strings.Index(option, ":"); colon >= 0 {
+			name, value = option[:colon], strings.TrimSpace(option[colon+1:])
+		}
+		name = strings.TrimSpace(name)
+
+		var err error
+		switch name {
+		case "msg":
+			r.Msg, err = unquote(value)
+		case "sid":
+			r.Sid, err = strconv.Atoi(value)
+		case "rev":
+			r.Rev, err = strconv.Atoi(value)
+		case "classtype":
+			r.Classtype = value
+		case "priority":
+			r.Priority, err = strconv.Atoi(value)
+		case "content":
+			var content Content
+			content, err = parseContent(value)
+			r.Contents = append(r.Contents, content)
+		case "nocase", "offset", "depth":
+			err = r.modifyContent(name, value)
+		case "flags":
+			r.Flags, err = parseFlags(value)
+		case "itype":
+			r.Itype, err = parseNumberMatch(value)
+		case "icode":
+			r.Icode, err = parseNumberMatch(value)
+		case "flow":
+			r.Flow, err = parseFlow(value)
+		case "metadata", "reference", "gid":
+			// Informational only.
+		default:
+			err = fmt.Errorf("unsupported option %q", name)
+		}
+		if err != nil {
+			return fmt.Errorf("option %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) modifyContent(name string, value string) error {
+	if len(r.Contents) == 0 {
+		return errors.New("must follow a content option")
+	}
+	content := &r.Contents[len(r.Contents)-1]
+
+	var err error
+	switch name {
+	case "nocase":
+		content.Nocase = true
+	case "offset":
+		content.Offset, err = strconv.Atoi(value)
+	case "depth":
+		content.Depth, err = strconv.Atoi(value)
+	}
+	return err
+}
+
+// splitOptions splits on semicolons outside quotes, honouring \; escapes.
+func splitOptions(text string) []string {
+	var options []string
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			current.WriteByte(c)
+			current.WriteByte(text[i+1])
+			i++
+			continue
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			if option := strings.TrimSpace(current.String()); option != "" {
+				options = append(options, option)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if option := strings.TrimSpace(current.String()); option != "" {
+		options = append(options, option)
+	}
+	return options
+}
+
+func unquote(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", errors.New("value must be quoted")
+	}
+	value = value[1 : len(value)-1]
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String(), nil
+}
+
+// parseContent decodes a content string, including |41 42| hex blocks.
+func parseContent(value string) (Content, error) {
+	var content Content
+	if strings.HasPrefix(value, "!") {
+		content.Negated = true
+		value = strings.TrimSpace(value[1:])
+	}
+	text, err := unquote(value)
+	if err != nil {
+		return content, err
+	}
+	pattern, err := search.ParsePattern(text, false)
+	content.Pattern = pattern.Bytes
+	return content, err
+}
+
+var flagLetters = map[byte]byte{
+	'F': 1, 'S': 2, 'R': 4, 'P': 8, 'A': 16, 'U': 32, 'C': 128, 'E': 64,
+	// Snort's historic names for the ECN bits.
+	'1': 128, '2': 64,
+}
+
+func parseFlags(value string) (*FlagMatch, error) {
+	match := &FlagMatch{}
+	parts := strings.SplitN(value, ",", 2)
+	flags := strings.TrimSpace(parts[0])
+	if len(flags) > 0 && strings.ContainsRune("+*!", rune(flags[0])) {
+		match.Modifier = flags[0]
+		flags = flags[1:]
+	}
+	if len(flags) > 0 && strings.ContainsRune("+*!", rune(flags[len(flags)-1])) {
+		match.Modifier = flags[len(flags)-1]
+		flags = flags[:len(flags)-1]
+	}
+	for i := 0; i < len(flags); i++ {
+		bit, ok := flagLetters[flags[i]]
+		if !ok && flags[i] != '0' {
+			return nil, fmt.Errorf("unknown flag %q", flags[i])
+		}
+		match.Flags |= bit
+	}
+	if len(parts) == 2 {
+		for _, c := range strings.TrimSpace(parts[1]) {
+			match.Ignored |= flagLetters[byte(c)]
+		}
+	}
+	return match, nil
+}
+
+func parseNumberMatch(value string) (*NumberMatch, error) {
+	if parts := strings.SplitN(value, "<>", 2); len(parts) == 2 {
+		low, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		high, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		return &NumberMatch{Operator: 'r', Value: low, Max: high}, nil
+	}
+
+	match := &NumberMatch{Operator: '='}
+	if value != "" && (value[0] == '<' || value[0] == '>') {
+		match.Operator = value[0]
+		value = value[1:]
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(value))
+	match.Value = v
+	return match, err
+}
+
+func parseFlow(value string) (FlowMatch, error) {
+	var match FlowMatch
+	for _, keyword := range strings.Split(value, ",") {
+		switch strings.TrimSpace(keyword) {
+		case "to_server", "from_client":
+			match.ToServer = true
+		case "to_client", "from_server":
+			match.ToClient = true
+		case "established":
+			match.Established = true
+		case "not_established":
+			match.NotEstablished = true
+		case "stateless":
+			match.Stateless = true
+		default:
+			return match, fmt.Errorf("unsupported flow keyword %q", keyword)
+		}
+	}
+	return match, nil
+}
+
+func expand(value string, variables Variables, depth int) (string, error) {
+	negated := strings.HasPrefix(value, "!")
+	name := strings.TrimPrefix(value, "!")
+	if !strings.HasPrefix(name, "$") {
+		return value, nil
+	}
+	if depth > 8 {
+		return "", fmt.Errorf("variable %s nests too deep", name)
+	}
+	resolved, ok := variables[name[1:]]
+	if !ok {
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	resolved, err := expand(resolved, variables, depth+1)
+	if negated {
+		resolved = "!" + resolved
+	}
+	return resolved, err
+}
+
+// splitList splits a [a,b,[c,d]] list at its top level.
+func splitList(value string) []string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return []string{value}
+	}
+	value = value[1 : len(value)-1]
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(value[start:]))
+}
+
+func parseAddressSet(value string, variables Variables) (AddressSet, error) {
+	var set AddressSet
+	err := set.add(value, variables, false, 0)
+	if len(set.Networks) == 0 && len(set.Negated) != 0 {
+		set.Any = true
+	}
+	return set, err
+}
+
+func (s *AddressSet) add(value string, variables Variables, negated bool, depth int) error {
+	value, err := expand(strings.TrimSpace(value), variables, depth)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(value, "!") {
+		negated = !negated
+		value = value[1:]
+	}
+	if strings.HasPrefix(value, "[") {
+		for _, item := range splitList(value) {
+			if err := s.add(item, variables, negated, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if value == "any" {
+		if negated {
+			return errors.New("!any matches nothing")
+		}
+		s.Any = true
+		return nil
+	}
+
+	if !strings.Contains(value, "/") {
+		value += "/32"
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return err
+	}
+	if negated {
+		s.Negated = append(s.Negated, network)
+	} else {
+		s.Networks = append(s.Networks, network)
+	}
+	return nil
+}
+
+func (s AddressSet) Matches(ip net.IP) bool {
+	for _, network := range s.Negated {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if s.Any {
+		return true
+	}
+	for _, network := range s.Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePortSet(value string, variables Variables) (PortSet, error) {
+	var set PortSet
+	err := set.add(value, variables, false, 0)
+	if len(set.Ranges) == 0 && len(set.Negated) != 0 {
+		set.Any = true
+	}
+	return set, err
+}
+
+func (s *PortSet) add(value string, variables Variables, negated bool, depth int) error {
+	value, err := expand(strings.TrimSpace(value), variables, depth)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(value, "!") {
+		negated = !negated
+		value = value[1:]
+	}
+	if strings.HasPrefix(value, "[") {
+		for _, item := range splitList(value) {
+			if err := s.add(item, variables, negated, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if value == "any" {
+		if negated {
+			return errors.New("!any matches nothing")
+		}
+		s.Any = true
+		return nil
+	}
+
+	portRange := PortRange{Low: 0, High: 65535}
+	bounds := strings.SplitN(value, ":", 2)
+	if bounds[0] != "" {
+		low, err := strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			return err
+		}
+		portRange.Low = uint16(low)
+		portRange.High = uint16(low)
+	}
+	if len(bounds) == 2 {
+		portRange.High = 65535
+		if bounds[1] != "" {
+			high, err := strconv.ParseUint(bounds[1], 10, 16)
+			if err != nil {
+				return err
+			}
+			portRange.High = uint16(high)
+		}
+	}
+
+	if negated {
+		s.Negated = append(s.Negated, portRange)
+	} else {
+		s.Ranges = append(s.Ranges, portRange)
+	}
+	return nil
+}
+
+func (s PortSet) Matches(port uint16) bool {
+	for _, r := range s.Negated {
+		if port >= r.Low && port <= r.High {
+			return false
+		}
+	}
+	if s.Any {
+		return true
+	}
+	for _, r := range s.Ranges {
+		if port >= r.Low && port <= r.High {
+			return true
+		}
+	}
+	return false
+}
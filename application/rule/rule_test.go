@@ -0,0 +1,179 @@
+package rule
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `alert tcp $HOME_NET any -> !10.0.0.0/8 [80,8000:8100] (msg:"GET \"x\"; seen"; ` +
+		`flow:to_server,established; flags:SA+,12; content:"GET|20|/"; nocase; offset:0; depth:8; ` +
+		`content:!"admin"; classtype:web-application-attack; sid:1000001; rev:3; metadata:created 2020;)`
+	r, err := Parse(text, DefaultVariables())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Action != "alert" || r.Protocol != "tcp" || r.Bidirectional {
+		t.Errorf("header parsed as %s %s bidirectional %v", r.Action, r.Protocol, r.Bidirectional)
+	}
+	if r.Msg != `GET "x"; seen` {
+		t.Errorf("msg %q", r.Msg)
+	}
+	if r.Sid != 1000001 || r.Rev != 3 || r.Priority != 3 || r.Classtype != "web-application-attack" {
+		t.Errorf("sid %d rev %d priority %d classtype %q", r.Sid, r.Rev, r.Priority, r.Classtype)
+	}
+	if !r.Flow.ToServer || !r.Flow.Established || r.Flow.ToClient {
+		t.Errorf("flow %+v", r.Flow)
+	}
+	if r.Flags == nil || r.Flags.Flags != 0x12 || r.Flags.Modifier != '+' || r.Flags.Ignored != 0xc0 {
+		t.Errorf("flags %+v", r.Flags)
+	}
+	if len(r.Contents) != 2 {
+		t.Fatalf("%d contents, want 2", len(r.Contents))
+	}
+	first := r.Contents[0]
+	if !bytes.Equal(first.Pattern, []byte("GET /")) || !first.Nocase || first.Depth != 8 || first.Negated {
+		t.Errorf("first content %+v", first)
+	}
+	if second := r.Contents[1]; string(second.Pattern) != "admin" || !second.Negated || second.Nocase {
+		t.Errorf("second content %+v", second)
+	}
+}
+
+func TestParseIcmpMatches(t *testing.T) {
+	r, err := Parse(`alert icmp any any <> any any (itype:8; icode:1<>5; sid:2;)`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Bidirectional {
+		t.Error("<> did not make the rule bidirectional")
+	}
+	for _, test := range []struct {
+		match *NumberMatch
+		value int
+		want  bool
+	}{
+		{r.Itype, 8, true},
+		{r.Itype, 0, false},
+		{r.Icode, 1, false},
+		{r.Icode, 3, true},
+		{r.Icode, 5, false},
+	} {
+		if got := test.match.Matches(test.value); got != test.want {
+			t.Errorf("%+v matches %d: %v, want %v", *test.match, test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`alert tcp any any -> any any sid:1;`, "parentheses"},
+		{`alert tcp any -> any any (sid:1;)`, "7 fields"},
+		{`log tcp any any -> any any (sid:1;)`, "log action"},
+		{`block tcp any any -> any any (sid:1;)`, "unknown action"},
+		{`alert sctp any any -> any any (sid:1;)`, "unsupported protocol"},
+		{`alert tcp any any <- any any (sid:1;)`, "unknown direction"},
+		{`alert tcp $NOWHERE any -> any any (sid:1;)`, "undefined variable"},
+		{`alert tcp !any any -> any any (sid:1;)`, "matches nothing"},
+		{`alert tcp 10.0.0.300 any -> any any (sid:1;)`, "invalid CIDR"},
+		{`alert tcp any 70000 -> any any (sid:1;)`, "out of range"},
+		{`alert tcp any any -> any any (msg:"x";)`, "no sid"},
+		{`alert tcp any any -> any any (nocase; sid:1;)`, "must follow a content"},
+		{`alert tcp any any -> any any (msg:unquoted; sid:1;)`, "quoted"},
+		{`alert tcp any any -> any any (flags:SX; sid:1;)`, "unknown flag"},
+		{`alert tcp any any -> any any (flow:sideways; sid:1;)`, "flow keyword"},
+		{`alert tcp any any -> any any (pcre:"/x/"; sid:1;)`, "unsupported option"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.text, DefaultVariables())
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.text, err, test.want)
+		}
+	}
+}
+
+func TestAddressSet(t *testing.T) {
+	variables := Variables{"INNER": "[10.1.0.0/16,!10.1.2.0/24]", "OUTER": "$INNER"}
+	tests := []struct {
+		value   string
+		address string
+		want    bool
+	}{
+		{"any", "1.2.3.4", true},
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.2", false},
+		{"!10.0.0.0/8", "10.9.9.9", false},
+		{"!10.0.0.0/8", "11.0.0.1", true},
+		{"$OUTER", "10.1.1.1", true},
+		{"$OUTER", "10.1.2.1", false},
+		{"!$OUTER", "10.1.1.1", false},
+		{"[192.168.0.0/16,[10.0.0.0/8,!10.0.0.1]]", "10.0.0.1", false},
+		{"[192.168.0.0/16,[10.0.0.0/8,!10.0.0.1]]", "10.0.0.2", true},
+	}
+	for _, test := range tests {
+		set, err := parseAddressSet(test.value, variables)
+		if err != nil {
+			t.Fatalf("%s: %v", test.value, err)
+		}
+		if got := set.Matches(net.ParseIP(test.address)); got != test.want {
+			t.Errorf("%s matches %s: %v, want %v", test.value, test.address, got, test.want)
+		}
+	}
+}
+
+func TestPortSet(t *testing.T) {
+	tests := []struct {
+		value string
+		port  uint16
+		want  bool
+	}{
+		{"any", 1, true},
+		{"80", 80, true},
+		{"80", 81, false},
+		{"1024:", 65535, true},
+		{"1024:", 1023, false},
+		{":1023", 0, true},
+		{":1023", 1024, false},
+		{"!80", 80, false},
+		{"!80", 443, true},
+		{"[1:100,!53]", 53, false},
+		{"[1:100,!53]", 54, true},
+		{"$HTTP_PORTS", 80, true},
+	}
+	for _, test := range tests {
+		set, err := parsePortSet(test.value, DefaultVariables())
+		if err != nil {
+			t.Fatalf("%s: %v", test.value, err)
+		}
+		if got := set.Matches(test.port); got != test.want {
+			t.Errorf("%s matches %d: %v, want %v", test.value, test.port, got, test.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	rules, err := Load(strings.NewReader(`# a comment
+
+alert udp any any -> any 53 \
+    (msg:"dns"; sid:1;)
+pass ip 10.0.0.1 any -> any any (sid:2;)
+`), DefaultVariables())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Msg != "dns" || rules[1].Action != "pass" {
+		t.Fatalf("loaded %+v", rules)
+	}
+
+	_, err = Load(strings.NewReader("alert udp any any -> any 53 (sid:1;)\n\nalert udp any any -> any 53 (msg:\"x\";)\n"), nil)
+	var parseError ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 3 {
+		t.Fatalf("got %v, want a parse error on line 3", err)
+	}
+}
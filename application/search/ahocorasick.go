@@ -0,0 +1,263 @@
+package search
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Pattern struct {
+	Name   string
+	Bytes  []byte
+	Nocase bool
+}
+
+// ParsePattern accepts plain text with optional |41 42| hex blocks, the same
+// notation Suricata uses for content.
+func ParsePattern(text string, nocase bool) (Pattern, error) {
+	pattern := Pattern{Name: text, Nocase: nocase}
+	hex := false
+	digits := ""
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '|':
+			if hex {
+				decoded, err := decodeHex(digits)
+				if err != nil {
+					return pattern, err
+				}
+				pattern.Bytes = append(pattern.Bytes, decoded...)
+				digits = ""
+			}
+			hex = !hex
+		case hex:
+			digits += string(c)
+		default:
+			pattern.Bytes = append(pattern.Bytes, c)
+		}
+	}
+	if hex {
+		return pattern, fmt.Errorf("unterminated hex block in %q", text)
+	}
+	if len(pattern.Bytes) == 0 {
+		return pattern, errors.New("empty pattern")
+	}
+	return pattern, nil
+}
+
+func decodeHex(digits string) ([]byte, error) {
+	digits = strings.Join(strings.Fields(digits), "")
+	if len(digits)%2 != 0 {
+		return nil, fmt.Errorf("odd number of hex digits in %q", digits)
+	}
+	result := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		v, err := strconv.ParseUint(digits[i:i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, byte(v))
+	}
+	return result, nil
+}
+
+// LoadPatterns reads one pattern per line, skipping blanks and # comments.
+func LoadPatterns(path string, nocase bool) ([]Pattern, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadPatterns(file, nocase)
+}
+
+func ReadPatterns(r io.Reader, nocase bool) ([]Pattern, error) {
+	var patterns []Pattern
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, err := ParsePattern(line, nocase)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, scanner.Err()
+}
+
+type Hit struct {
+	// Pattern indexes the slice the Matcher was built from.
+	Pattern int
+	// End is the offset just past the last matched byte, counted from the
+	// start of the scanned buffer or stream.
+	End int64
+}
+
+// automaton is an Aho-Corasick DFA over byte classes. Bytes that no pattern
+// uses share class 0, which keeps the transition table small.
+type automaton struct {
+	classes    [256]uint16
+	classCount int
+	delta      []int32
+	outputs    [][]int
+}
+
+func buildAutomaton(patterns []Pattern, indexes []int, fold bool) *automaton {
+	a := &automaton{}
+	key := func(c byte) byte {
+		if fold && c >= 'A' && c <= 'Z' {
+			return c + 'a' - 'A'
+		}
+		return c
+	}
+
+	nextClass := 1
+	for _, i := range indexes {
+		for _, c := range patterns[i].Bytes {
+			c = key(c)
+			if a.classes[c] == 0 {
+				a.classes[c] = uint16(nextClass)
+				nextClass++
+			}
+		}
+	}
+	if fold {
+		for c := 'A'; c <= 'Z'; c++ {
+			a.classes[c] = a.classes[c+'a'-'A']
+		}
+	}
+	a.classCount = nextClass
+
+	// Trie construction with sparse children.
+	children := []map[uint16]int32{{}}
+	a.outputs = [][]int{nil}
+	for _, i := range indexes {
+		state := int32(0)
+		for _, c := range patterns[i].Bytes {
+			class := a.classes[key(c)]
+			next, ok := children[state][class]
+			if !ok {
+				next = int32(len(children))
+				children = append(children, map[uint16]int32{})
+				a.outputs = append(a.outputs, nil)
+				children[state][class] = next
+			}
+			state = next
+		}
+		a.outputs[state] = append(a.outputs[state], i)
+	}
+
+	// Breadth first pass computing failure links and the full DFA.
+	states := len(children)
+	a.delta = make([]int32, states*a.classCount)
+	fail := make([]int32, states)
+	queue := make([]int32, 0, states)
+	for class := 0; class < a.classCount; class++ {
+		if next, ok := children[0][uint16(class)]; ok {
+			a.delta[class] = next
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		if len(a.outputs[fail[state]]) > 0 {
+			a.outputs[state] = append(append([]int(nil), a.outputs[state]...), a.outputs[fail[state]]...)
+		}
+		row := int(state) * a.classCount
+		failRow := int(fail[state]) * a.classCount
+		for class := 0; class < a.classCount; class++ {
+			if next, ok := children[state][uint16(class)]; ok {
+				fail[next] = a.delta[failRow+class]
+				a.delta[row+class] = next
+				queue = append(queue, next)
+			} else {
+				a.delta[row+class] = a.delta[failRow+class]
+			}
+		}
+	}
+	return a
+}
+
+func (a *automaton) scan(state int32, data []byte, base int64, hits []Hit) (int32, []Hit) {
+	for i, c := range data {
+		state = a.delta[int(state)*a.classCount+int(a.classes[c])]
+		for _, pattern := range a.outputs[state] {
+			hits = append(hits, Hit{Pattern: pattern, End: base + int64(i) + 1})
+		}
+	}
+	return state, hits
+}
+
+// Matcher finds every occurrence of many patterns in one pass. Case-sensitive
+// and case-insensitive patterns live in separate automata.
+type Matcher struct {
+	Patterns    []Pattern
+	sensitive   *automaton
+	insensitive *automaton
+}
+
+// Cursor carries automaton state between calls so a match may straddle
+// buffer boundaries, e.g. TCP segments.
+type Cursor struct {
+	sensitive   int32
+	insensitive int32
+	Offset      int64
+}
+
+func NewMatcher(patterns []Pattern) *Matcher {
+	var sensitive, insensitive []int
+	for i, p := range patterns {
+		if p.Nocase {
+			insensitive = append(insensitive, i)
+		} else {
+			sensitive = append(sensitive, i)
+		}
+	}
+
+	m := &Matcher{Patterns: patterns}
+	if len(sensitive) > 0 {
+		m.sensitive = buildAutomaton(patterns, sensitive, false)
+	}
+	if len(insensitive) > 0 {
+		m.insensitive = buildAutomaton(patterns, insensitive, true)
+	}
+	return m
+}
+
+func (m *Matcher) Scan(data []byte) []Hit {
+	var cursor Cursor
+	return m.Continue(&cursor, data, nil)
+}
+
+// Continue scans data as the continuation of whatever cursor has seen so far
+// and appends the hits to hits.
+func (m *Matcher) Continue(cursor *Cursor, data []byte, hits []Hit) []Hit {
+	if m.sensitive != nil {
+		cursor.sensitive, hits = m.sensitive.scan(cursor.sensitive, data, cursor.Offset, hits)
+	}
+	if m.insensitive != nil {
+		cursor.insensitive, hits = m.insensitive.scan(cursor.insensitive, data, cursor.Offset, hits)
+	}
+	cursor.Offset += int64(len(data))
+	return hits
+}
+
+func (m *Matcher) HitsToString(hits []Hit) string {
+	names := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		names = append(names, fmt.Sprintf("%q@%d", m.Patterns[hit.Pattern].Name, hit.End-int64(len(m.Patterns[hit.Pattern].Bytes))))
+	}
+	return strings.Join(names, ", ")
+}
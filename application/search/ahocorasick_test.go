@@ -0,0 +1,150 @@
+package search
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// naiveHits finds every occurrence of every pattern by brute force.
+func naiveHits(patterns []Pattern, data []byte) []Hit {
+	var hits []Hit
+	for i, p := range patterns {
+		haystack, needle := data, p.Bytes
+		if p.Nocase {
+			haystack, needle = lowerAscii(data), lowerAscii(p.Bytes)
+		}
+		for end := len(needle); end <= len(haystack); end++ {
+			if bytes.Equal(haystack[end-len(needle):end], needle) {
+				hits = append(hits, Hit{Pattern: i, End: int64(end)})
+			}
+		}
+	}
+	return sortHits(hits)
+}
+
+// lowerAscii folds A-Z only. bytes.ToLower would turn invalid UTF-8 into
+// replacement characters and move the offsets.
+func lowerAscii(data []byte) []byte {
+	result := make([]byte, len(data))
+	for i, c := range data {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		result[i] = c
+	}
+	return result
+}
+
+func sortHits(hits []Hit) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].End != hits[j].End {
+			return hits[i].End < hits[j].End
+		}
+		return hits[i].Pattern < hits[j].Pattern
+	})
+	return hits
+}
+
+func TestMatcherFindsOverlappingPatterns(t *testing.T) {
+	patterns := []Pattern{
+		{Name: "he", Bytes: []byte("he")},
+		{Name: "she", Bytes: []byte("she")},
+		{Name: "his", Bytes: []byte("his")},
+		{Name: "hers", Bytes: []byte("hers")},
+		{Name: "HE", Bytes: []byte("HE"), Nocase: true},
+	}
+	m := NewMatcher(patterns)
+	got := sortHits(m.Scan([]byte("ushers SHE")))
+	want := []Hit{{1, 4}, {0, 4}, {4, 4}, {3, 6}, {4, 10}}
+	want = sortHits(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %s\nwant %s", m.HitsToString(got), m.HitsToString(want))
+	}
+}
+
+func TestMatcherAgainstNaiveSearch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomBytes := func(n int) []byte {
+		// A small alphabet makes overlaps and shared prefixes common.
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abAB\x00\xff"[r.Intn(6)]
+		}
+		return b
+	}
+	for round := 0; round < 200; round++ {
+		patterns := make([]Pattern, 1+r.Intn(8))
+		for i := range patterns {
+			patterns[i] = Pattern{Bytes: randomBytes(1 + r.Intn(4)), Nocase: r.Intn(2) == 0}
+		}
+		data := randomBytes(r.Intn(200))
+		m := NewMatcher(patterns)
+		want := naiveHits(patterns, data)
+
+		if got := sortHits(m.Scan(data)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("round %d: Scan found %v, want %v", round, got, want)
+		}
+
+		// The same hits must come out when the data arrives in pieces.
+		var cursor Cursor
+		var hits []Hit
+		for rest := data; len(rest) > 0; {
+			n := 1 + r.Intn(len(rest))
+			hits = m.Continue(&cursor, rest[:n], hits)
+			rest = rest[n:]
+		}
+		if got := sortHits(hits); !reflect.DeepEqual(got, want) {
+			t.Fatalf("round %d: Continue found %v, want %v", round, got, want)
+		}
+		if cursor.Offset != int64(len(data)) {
+			t.Fatalf("round %d: cursor at %d after %d bytes", round, cursor.Offset, len(data))
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want []byte
+		err  string
+	}{
+		{"GET", []byte("GET"), ""},
+		{"|de ad|BE|EF|", []byte{0xde, 0xad, 'B', 'E', 0xef}, ""},
+		{"a|2F|b", []byte("a/b"), ""},
+		{"|de ad", nil, "unterminated"},
+		{"|abc|", nil, "odd number"},
+		{"|zz|", nil, "invalid syntax"},
+		{"||", nil, "empty pattern"},
+	}
+	for _, test := range tests {
+		p, err := ParsePattern(test.text, false)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: got error %v, want one containing %q", test.text, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(p.Bytes, test.want) {
+			t.Errorf("%q: got % x, %v, want % x", test.text, p.Bytes, err, test.want)
+		}
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	patterns, err := ReadPatterns(strings.NewReader("# comment\r\npassword\r\n\r\n|00 01|\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || string(patterns[0].Bytes) != "password" || !patterns[1].Nocase {
+		t.Fatalf("read %+v", patterns)
+	}
+
+	_, err = ReadPatterns(strings.NewReader("ok\n|0|\n"), false)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Fatalf("got %v, want an error on line 2", err)
+	}
+}
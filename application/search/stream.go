@@ -0,0 +1,195 @@
+package search
+
+import (
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultStreamIdleTimeout = 2 * time.Minute
+	// maxPendingSegments bounds how many out-of-order segments one direction
+	// may hold while waiting for a gap to fill.
+	maxPendingSegments = 64
+	maxStreams         = 65536
+	// maxGapWait is how long a direction waits for a missing segment before
+	// giving up on it and carrying on after the gap.
+	maxGapWait = time.Second
+)
+
+type streamKey struct {
+	flow  flow.Key
+	fromA bool
+}
+
+type stream struct {
+	cursor   Cursor
+	nextSeq  uint32
+	pending  map[uint32][]byte
+	lastSeen time.Time
+	// gapSince is when the oldest pending segment arrived.
+	gapSince time.Time
+}
+
+// StreamScanner reassembles each TCP direction in sequence order and feeds it
+// to a Matcher, so patterns split across segments are still found.
+type StreamScanner struct {
+	matcher     *Matcher
+	mutex       sync.Mutex
+	streams     map[streamKey]*stream
+	IdleTimeout time.Duration
+}
+
+func NewStreamScanner(matcher *Matcher) *StreamScanner {
+	return &StreamScanner{
+		matcher:     matcher,
+		streams:     make(map[streamKey]*stream),
+		IdleTimeout: DefaultStreamIdleTimeout,
+	}
+}
+
+// Scan feeds one segment and returns the hits that completed in it. Hit
+// offsets count from the first byte seen in that direction.
+func (s *StreamScanner) Scan(o flow.Observation, tcp packet.TcpPacket, timestamp time.Time) []Hit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, fromA := o.Key()
+	id := streamKey{flow: key, fromA: fromA}
+	header := tcp.Header
+	st, ok := s.streams[id]
+
+	if header.RST {
+		delete(s.streams, id)
+		delete(s.streams, streamKey{flow: key, fromA: !fromA})
+		return nil
+	}
+
+	seq := header.SequenceNumber
+	if header.SYN {
+		seq++
+	}
+	if !ok {
+		if len(s.streams) >= maxStreams {
+			s.expire(timestamp)
+		}
+		st = &stream{nextSeq: seq, pending: make(map[uint32][]byte)}
+		s.streams[id] = st
+	}
+	st.lastSeen = timestamp
+
+	if header.FIN {
+		defer delete(s.streams, id)
+	}
+
+	// RawPayload ends where the IP packet does, so the padding of short
+	// frames is never fed. Segments without data leave nextSeq alone.
+	payload := tcp.RawPayload
+	if len(payload) == 0 {
+		return nil
+	}
+	var hits []Hit
+	delta := int32(seq - st.nextSeq)
+	switch {
+	case delta > 0:
+		if len(st.pending) == 0 {
+			st.gapSince = timestamp
+		}
+		st.pending[seq] = append([]byte(nil), payload...)
+		// A segment that was lost or never captured would otherwise stall
+		// the direction for good.
+		if len(st.pending) >= maxPendingSegments || timestamp.Sub(st.gapSince) > maxGapWait {
+			hits = s.skipGap(st, hits, timestamp)
+		}
+	case -delta < int32(len(payload)):
+		// In order, or a retransmission carrying some new bytes.
+		hits = s.feed(st, payload[-delta:], hits)
+		hits = s.drain(st, hits)
+	}
+	return hits
+}
+
+func (s *StreamScanner) feed(st *stream, data []byte, hits []Hit) []Hit {
+	hits = s.matcher.Continue(&st.cursor, data, hits)
+	st.nextSeq += uint32(len(data))
+	return hits
+}
+
+// drain feeds buffered segments that have become contiguous.
+func (s *StreamScanner) drain(st *stream, hits []Hit) []Hit {
+	for len(st.pending) > 0 {
+		progressed := false
+		for seq, data := range st.pending {
+			delta := int32(seq - st.nextSeq)
+			if delta > 0 {
+				continue
+			}
+			delete(st.pending, seq)
+			if -delta < int32(len(data)) {
+				hits = s.feed(st, data[-delta:], hits)
+			}
+			progressed = true
+		}
+		if !progressed {
+			break
+		}
+	}
+	return hits
+}
+
+// skipGap gives up on the bytes missing before the first pending segment
+// and carries on from there. Matches cannot straddle the gap, but offsets
+// still count it.
+func (s *StreamScanner) skipGap(st *stream, hits []Hit, timestamp time.Time) []Hit {
+	first := true
+	var next uint32
+	for seq := range st.pending {
+		if first || int32(seq-next) < 0 {
+			next = seq
+			first = false
+		}
+	}
+	st.cursor = Cursor{Offset: st.cursor.Offset + int64(int32(next-st.nextSeq))}
+	st.nextSeq = next
+	hits = s.drain(st, hits)
+	st.gapSince = timestamp
+	return hits
+}
+
+func (s *StreamScanner) Expire(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expire(now)
+}
+
+func (s *StreamScanner) expire(now time.Time) {
+	for id, st := range s.streams {
+		if now.Sub(st.lastSeen) > s.IdleTimeout {
+			delete(s.streams, id)
+		}
+	}
+}
+
+// Grep scans the payload of one decoded packet. TCP payloads go through the
+// stream scanner, everything else is scanned on its own.
+func (s *StreamScanner) Grep(p packet.Parsable, timestamp time.Time) []Hit {
+	o, ok := flow.Observe(p)
+	if !ok {
+		return nil
+	}
+	switch transport := o.Transport.(type) {
+	case packet.TcpPacket:
+		return s.Scan(o, transport, timestamp)
+	case packet.UdpPacket:
+		return s.matcher.Scan(transport.RawPayload)
+	case packet.IcmpV4Packet:
+		return s.matcher.Scan(transport.RawPayload)
+	}
+	return nil
+}
+
+func (s *StreamScanner) HitsToString(hits []Hit) string {
+	return s.matcher.HitsToString(hits)
+}
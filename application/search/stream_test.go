@@ -0,0 +1,85 @@
+package search
+
+import (
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"testing"
+	"time"
+)
+
+var streamObservation = flow.Observation{
+	Source:      flow.Endpoint{Address: [4]byte{10, 0, 0, 1}, Port: 1024},
+	Destination: flow.Endpoint{Address: [4]byte{10, 0, 0, 2}, Port: 80},
+	Protocol:    flow.IpProtocolTcp,
+}
+
+func segment(seq uint32, payload string) packet.TcpPacket {
+	return packet.TcpPacket{
+		Packet: packet.Packet{RawPayload: []byte(payload)},
+		Header: packet.TcpHeader{SequenceNumber: seq, ACK: true},
+	}
+}
+
+func newTestScanner(t *testing.T, patterns ...string) *StreamScanner {
+	t.Helper()
+	var parsed []Pattern
+	for _, text := range patterns {
+		p, err := ParsePattern(text, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, p)
+	}
+	return NewStreamScanner(NewMatcher(parsed))
+}
+
+func TestStreamScannerMatchesAcrossSegments(t *testing.T) {
+	s := newTestScanner(t, "secret")
+	now := time.Unix(1000, 0)
+	var hits []Hit
+	hits = append(hits, s.Scan(streamObservation, segment(100, "xse"), now)...)
+	// Out of order: the end arrives before the middle.
+	hits = append(hits, s.Scan(streamObservation, segment(106, "t!"), now)...)
+	hits = append(hits, s.Scan(streamObservation, segment(103, "cre"), now)...)
+	if len(hits) != 1 || hits[0].End != 7 {
+		t.Fatalf("got %v, want one hit ending at 7", hits)
+	}
+}
+
+func TestStreamScannerSkipsLostSegment(t *testing.T) {
+	now := time.Unix(1000, 0)
+	for _, test := range []struct {
+		name string
+		// scan feeds the segments after the lost one and returns the hits.
+		scan func(s *StreamScanner) []Hit
+		// end is where the hit ends, counting the lost bytes.
+		end int64
+	}{
+		{"pending full", func(s *StreamScanner) []Hit {
+			var hits []Hit
+			seq := uint32(110)
+			for i := 0; i < maxPendingSegments; i++ {
+				hits = append(hits, s.Scan(streamObservation, segment(seq, "0123456789"), now)...)
+				seq += 10
+			}
+			return append(hits, s.Scan(streamObservation, segment(seq, "secret"), now)...)
+		}, 110 + maxPendingSegments*10 - 90 + 6},
+		{"gap stale", func(s *StreamScanner) []Hit {
+			hits := s.Scan(streamObservation, segment(110, "0123456789"), now)
+			later := now.Add(2 * maxGapWait)
+			return append(hits, s.Scan(streamObservation, segment(120, "secret"), later)...)
+		}, 120 - 90 + 6},
+	} {
+		s := newTestScanner(t, "secret")
+		s.Scan(streamObservation, segment(90, "0123456789"), now)
+		// The segment at 100 is lost.
+		hits := test.scan(s)
+		if len(hits) != 1 {
+			t.Errorf("%s: got %d hits, want 1", test.name, len(hits))
+			continue
+		}
+		if hits[0].End != test.end {
+			t.Errorf("%s: hit ends at %d, want %d", test.name, hits[0].End, test.end)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package stats
+
+import (
+	"fmt"
+	"sniffer/application/packet"
+	"sniffer/application/protocol"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sizeBuckets = []int{64, 128, 256, 512, 1024, 1518}
+
+// maxTalkers bounds the talker table, a scan or spoofed sources would
+// otherwise add an entry per address.
+const maxTalkers = 65536
+
+type Counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+func (c *Counter) add(length int) {
+	c.Packets++
+	c.Bytes += uint64(length)
+}
+
+// node is one level of the protocol hierarchy, e.g. Ethernet -> IpV4 -> Tcp.
+type node struct {
+	Counter
+	name     string
+	children []*node
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &node{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+type Collector struct {
+	mutex sync.Mutex
+
+	total       Counter
+	hierarchy   node
+	etherTypes  map[uint16]*Counter
+	ipProtocols map[byte]*Counter
+	talkers     map[string]*Counter
+	// untrackedTalkers is the traffic from addresses past maxTalkers.
+	untrackedTalkers Counter
+	sizes            []uint64
+
+	unknownEtherTypes  map[uint16]*Counter
+	unknownIpProtocols map[byte]*Counter
+
+	start         time.Time
+	intervalStart time.Time
+	interval      Counter
+}
+
+func NewCollector() *Collector {
+	now := time.Now()
+	return &Collector{
+		hierarchy:          node{name: "Frames"},
+		etherTypes:         make(map[uint16]*Counter),
+		ipProtocols:        make(map[byte]*Counter),
+		talkers:            make(map[string]*Counter),
+		sizes:              make([]uint64, len(sizeBuckets)+1),
+		unknownEtherTypes:  make(map[uint16]*Counter),
+		unknownIpProtocols: make(map[byte]*Counter),
+		start:              now,
+		intervalStart:      now,
+	}
+}
+
+// Add accounts a decoded frame. length is the wire length of the frame. p
+// may start at any layer, a frame read from a raw IP capture starts at Ipv4.
+func (c *Collector) Add(p packet.Parsable, length int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.total.add(length)
+	c.interval.add(length)
+	c.sizes[sizeBucket(length)]++
+
+	level := &c.hierarchy
+	level.add(length)
+
+	for layer := p; layer != nil; {
+		switch l := layer.(type) {
+		case packet.EthernetPacket:
+			level = level.child(protocol.Ethernet.Name)
+			level.add(length)
+			layer = c.addEthernet(l, level, length)
+
+		case packet.ArpPacket:
+			level.child(protocol.Arp.Name).add(length)
+			return
+
+		case packet.Ipv4Packet:
+			level = level.child(protocol.IpV4.Name)
+			level.add(length)
+			level, layer = c.addIpv4(l, level, length)
+
+		case packet.TcpPacket:
+			app := l.DestProtocol
+			if app == "" {
+				app = l.SourceProtocol
+			}
+			if app != "" {
+				level.child(app).add(length)
+			}
+			return
+
+		case packet.UdpPacket:
+			if l.CanParseMore {
+				level.child(l.Application.Name).add(length)
+			}
+			return
+
+		default:
+			return
+		}
+	}
+}
+
+// addEthernet returns the layer carried by ethernet, nil when there is none.
+func (c *Collector) addEthernet(ethernet packet.EthernetPacket, level *node, length int) packet.Parsable {
+	etherType := ethernet.Header.Type
+	counter16(c.etherTypes, etherType.Value).add(length)
+	if !ethernet.CanParseMore {
+		counter16(c.unknownEtherTypes, etherType.Value).add(length)
+		level.child(fmt.Sprintf("EtherType 0x%04x", etherType.Value)).add(length)
+		return nil
+	}
+	return ethernet.PacketParser
+}
+
+// addIpv4 returns the hierarchy level and the layer of the transport, a nil
+// layer when it was not decoded.
+func (c *Collector) addIpv4(ipv4 packet.Ipv4Packet, level *node, length int) (*node, packet.Parsable) {
+	payloadProtocol := ipv4.Header.PayloadProtocol
+	counter8(c.ipProtocols, payloadProtocol.Value).add(length)
+	c.addTalker(ipv4.Header.SourceAddress.ToString(), length)
+
+	if !ipv4.CanParseMore {
+		counter8(c.unknownIpProtocols, payloadProtocol.Value).add(length)
+		level.child(fmt.Sprintf("IP protocol %d", payloadProtocol.Value)).add(length)
+		return level, nil
+	}
+
+	level = level.child(payloadProtocol.PayloadProtocol.Name)
+	level.add(length)
+	return level, ipv4.PacketParser
+}
+
+// addTalker counts traffic from address. Once maxTalkers addresses are
+// known, traffic from new ones is only counted in untrackedTalkers.
+func (c *Collector) addTalker(address string, length int) {
+	talker, ok := c.talkers[address]
+	if !ok {
+		if len(c.talkers) >= maxTalkers {
+			c.untrackedTalkers.add(length)
+			return
+		}
+		talker = &Counter{}
+		c.talkers[address] = talker
+	}
+	talker.add(length)
+}
+
+func counter16(m map[uint16]*Counter, key uint16) *Counter {
+	counter, ok := m[key]
+	if !ok {
+		counter = &Counter{}
+		m[key] = counter
+	}
+	return counter
+}
+
+func counter8(m map[byte]*Counter, key byte) *Counter {
+	counter, ok := m[key]
+	if !ok {
+		counter = &Counter{}
+		m[key] = counter
+	}
+	return counter
+}
+
+func sizeBucket(length int) int {
+	for i, limit := range sizeBuckets {
+		if length < limit {
+			return i
+		}
+	}
+	return len(sizeBuckets)
+}
+
+// Rate returns the packet and byte rates since the previous call and starts a
+// new interval.
+func (c *Collector) Rate(now time.Time) (packetsPerSecond float64, bitsPerSecond float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	seconds := now.Sub(c.intervalStart).Seconds()
+	if seconds > 0 {
+		packetsPerSecond = float64(c.interval.Packets) / seconds
+		bitsPerSecond = float64(c.interval.Bytes*8) / seconds
+	}
+	c.interval = Counter{}
+	c.intervalStart = now
+	return packetsPerSecond, bitsPerSecond
+}
+
+func (c *Collector) Total() Counter {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.total
+}
+
+// ToString renders the full report. topTalkers limits the talker table.
+func (c *Collector) ToString(topTalkers int) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var b strings.Builder
+	elapsed := time.Since(c.start).Round(time.Second)
+	fmt.Fprintf(&b, "Traffic statistics after %s - packets %d - bytes %d\n", elapsed, c.total.Packets, c.total.Bytes)
+
+	b.WriteString("Protocol hierarchy:\n")
+	writeHierarchy(&b, &c.hierarchy, 1, c.total.Packets)
+
+	b.WriteString("EtherTypes:\n")
+	for _, key := range sortedKeys16(c.etherTypes) {
+		counter := c.etherTypes[key]
+		fmt.Fprintf(&b, "  0x%04x %-8s packets %d - bytes %d\n", key, packet.GetEtherType(key).Name, counter.Packets, counter.Bytes)
+	}
+
+	b.WriteString("IP protocols:\n")
+	for _, key := range sortedKeys8(c.ipProtocols) {
+		counter := c.ipProtocols[key]
+		fmt.Fprintf(&b, "  %3d packets %d - bytes %d\n", key, counter.Packets, counter.Bytes)
+	}
+
+	b.WriteString("Packet sizes:\n")
+	lower := 0
+	for i, count := range c.sizes {
+		if i < len(sizeBuckets) {
+			fmt.Fprintf(&b, "  %5d-%-5d %d\n", lower, sizeBuckets[i]-1, count)
+			lower = sizeBuckets[i]
+		} else {
+			fmt.Fprintf(&b, "  %5d+      %d\n", lower, count)
+		}
+	}
+
+	b.WriteString("Top talkers:\n")
+	for i, address := range c.topTalkers(topTalkers) {
+		counter := c.talkers[address]
+		fmt.Fprintf(&b, "  %3d. %-15s packets %d - bytes %d\n", i+1, address, counter.Packets, counter.Bytes)
+	}
+	if c.untrackedTalkers.Packets != 0 {
+		fmt.Fprintf(&b, "  addresses past the first %d: packets %d - bytes %d\n", maxTalkers, c.untrackedTalkers.Packets, c.untrackedTalkers.Bytes)
+	}
+
+	if len(c.unknownEtherTypes) != 0 || len(c.unknownIpProtocols) != 0 {
+		b.WriteString("Unknown protocols:\n")
+		for _, key := range sortedKeys16(c.unknownEtherTypes) {
+			fmt.Fprintf(&b, "  EtherType 0x%04x: %d\n", key, c.unknownEtherTypes[key].Packets)
+		}
+		for _, key := range sortedKeys8(c.unknownIpProtocols) {
+			fmt.Fprintf(&b, "  IP protocol %d: %d\n", key, c.unknownIpProtocols[key].Packets)
+		}
+	}
+
+	return b.String()
+}
+
+func writeHierarchy(b *strings.Builder, n *node, depth int, total uint64) {
+	percent := 0.0
+	if total != 0 {
+		percent = float64(n.Packets) * 100 / float64(total)
+	}
+	fmt.Fprintf(b, "%s%-*s %6.2f%% packets %d - bytes %d\n", strings.Repeat("  ", depth), 24-2*depth, n.name, percent, n.Packets, n.Bytes)
+
+	children := append([]*node(nil), n.children...)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Packets > children[j].Packets
+	})
+	for _, child := range children {
+		writeHierarchy(b, child, depth+1, total)
+	}
+}
+
+func (c *Collector) topTalkers(n int) []string {
+	addresses := make([]string, 0, len(c.talkers))
+	for address := range c.talkers {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return c.talkers[addresses[i]].Bytes > c.talkers[addresses[j]].Bytes
+	})
+	if len(addresses) > n {
+		addresses = addresses[:n]
+	}
+	return addresses
+}
+
+func sortedKeys16(m map[uint16]*Counter) []uint16 {
+	keys := make([]uint16, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func sortedKeys8(m map[byte]*Counter) []byte {
+	keys := make([]byte, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sniffer/application/packet"
+	"strings"
+	"testing"
+)
+
+// ipv4Datagram builds an IPv4 datagram from 10.0.0.1 carrying payload as
+// protocol proto.
+func ipv4Datagram(proto byte, payload []byte) []byte {
+	datagram := make([]byte, packet.Ipv4MinHeaderSize+len(payload))
+	datagram[packet.Ipv4VersionAndIhlOffset] = 0x45
+	binary.BigEndian.PutUint16(datagram[packet.Ipv4TotalLengthOffset:], uint16(len(datagram)))
+	datagram[packet.Ipv4TtlOffset] = 64
+	datagram[packet.Ipv4ProtocolOffset] = proto
+	copy(datagram[packet.Ipv4SourceAddressOffset:], []byte{10, 0, 0, 1})
+	copy(datagram[packet.Ipv4DestAddressOffset:], []byte{10, 0, 0, 2})
+	binary.BigEndian.PutUint16(datagram[packet.Ipv4HeaderChecksumOffset:],
+		packet.InternetChecksum(datagram[:packet.Ipv4MinHeaderSize]))
+	copy(datagram[packet.Ipv4MinHeaderSize:], payload)
+	return datagram
+}
+
+// dnsDatagram is a UDP datagram to port 53 holding a query for example.com.
+func dnsDatagram() []byte {
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, 1, 0, 1}
+	udp := make([]byte, packet.UdpHeaderSize+len(query))
+	binary.BigEndian.PutUint16(udp[packet.UdpSrcPortOffset:], 1024)
+	binary.BigEndian.PutUint16(udp[packet.UdpDestinationPortOffset:], 53)
+	binary.BigEndian.PutUint16(udp[packet.UdpLengthOffset:], uint16(len(udp)))
+	copy(udp[packet.UdpHeaderSize:], query)
+	return udp
+}
+
+func TestRawIpFrameHierarchy(t *testing.T) {
+	c := NewCollector()
+	datagram := ipv4Datagram(17, dnsDatagram())
+	c.Add(packet.ParseIpV4Packet(datagram), len(datagram))
+
+	report := c.ToString(10)
+	for _, want := range []string{"\n    IpV4 ", "\n      Udp ", "\n        Dns ", "10.0.0.1 "} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestUnknownProtocolsSorted(t *testing.T) {
+	c := NewCollector()
+	for _, proto := range []byte{200, 99, 150, 99} {
+		datagram := ipv4Datagram(proto, make([]byte, 8))
+		c.Add(packet.ParseIpV4Packet(datagram), len(datagram))
+	}
+
+	report := c.ToString(10)
+	unknown := report[strings.Index(report, "Unknown protocols:\n"):]
+	want := "Unknown protocols:\n  IP protocol 99: 2\n  IP protocol 150: 1\n  IP protocol 200: 1\n"
+	if unknown != want {
+		t.Fatalf("unknown protocols\n%s\nwant\n%s", unknown, want)
+	}
+}
+
+func TestTalkersBounded(t *testing.T) {
+	c := NewCollector()
+	for i := 0; i < maxTalkers+10; i++ {
+		c.addTalker(fmt.Sprintf("10.%d.%d.%d", i>>16, (i>>8)&0xff, i&0xff), 100)
+	}
+	if len(c.talkers) != maxTalkers {
+		t.Fatalf("%d talkers tracked, want %d", len(c.talkers), maxTalkers)
+	}
+	if c.untrackedTalkers.Packets != 10 || c.untrackedTalkers.Bytes != 1000 {
+		t.Fatalf("untracked %+v, want 10 packets and 1000 bytes", c.untrackedTalkers)
+	}
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"fmt"
+	"sniffer/application/packet"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Entry is one captured frame as the packet list shows it.
+type Entry struct {
+	Number      int
+	Time        time.Time
+	Source      string
+	Destination string
+	Protocol    string
+	Length      int
+	Info        string
+	Raw         []byte
+	Decoded     packet.Parsable
+	// Interface and LinkType say where Raw was captured, for saving it.
+	Interface string
+	LinkType  layers.LinkType
+}
+
+func NewEntry(number int, timestamp time.Time, raw []byte, decoded packet.Parsable) Entry {
+	e := Entry{
+		Number:  number,
+		Time:    timestamp,
+		Length:  len(raw),
+		Raw:     raw,
+		Decoded: decoded,
+	}
+	e.summarize()
+	return e
+}
+
+func (e *Entry) summarize() {
+	ethernet, ok := e.Decoded.(packet.EthernetPacket)
+	if !ok {
+		e.Protocol = "?"
+		return
+	}
+	e.Source = ethernet.Header.SrcMacAddr.ToString()
+	e.Destination = ethernet.Header.DestMacAddr.ToString()
+	e.Protocol = "Ethernet"
+	e.Info = fmt.Sprintf("EtherType 0x%04x", ethernet.Header.Type.Value)
+	if !ethernet.CanParseMore {
+		return
+	}
+
+	switch layer := ethernet.PacketParser.(type) {
+	case packet.ArpPacket:
+		e.Protocol = "ARP"
+		header := layer.Header
+		switch header.Operation.Value {
+		case 1:
+			e.Info = fmt.Sprintf("Who has %s? Tell %s", header.DstAddress.ToString(), header.SrcAddress.ToString())
+		case 2:
+			e.Info = fmt.Sprintf("%s is at %s", header.SrcAddress.ToString(), header.SrcHardwareAddr.ToString())
+		default:
+			e.Info = header.Operation.Name
+		}
+
+	case packet.Ipv4Packet:
+		e.Source = layer.Header.SourceAddress.ToString()
+		e.Destination = layer.Header.DestinationAddress.ToString()
+		e.Protocol = "IPv4"
+		e.Info = fmt.Sprintf("protocol %d ttl %d", layer.Header.PayloadProtocol.Value, layer.Header.Ttl)
+		if layer.CanParseMore {
+			e.summarizeTransport(layer.PacketParser)
+		}
+	}
+}
+
+func (e *Entry) summarizeTransport(transport packet.Parsable) {
+	switch layer := transport.(type) {
+	case packet.TcpPacket:
+		header := layer.Header
+		e.Protocol = "TCP"
+		if layer.SourceProtocol != "" {
+			e.Protocol = layer.SourceProtocol
+		} else if layer.DestProtocol != "" {
+			e.Protocol = layer.DestProtocol
+		}
+		e.Info = fmt.Sprintf("%d → %d [%s] Seq=%d Ack=%d Win=%d Len=%d", header.SourcePort, header.DestinationPort,
+			tcpFlagNames(header), header.SequenceNumber, header.AckNumber, header.Window, len(layer.RawPayload))
+		for _, annotation := range layer.Annotations {
+			e.Info = "[" + annotation + "] " + e.Info
+		}
+
+	case packet.UdpPacket:
+		e.Protocol = "UDP"
+		e.Info = fmt.Sprintf("%d → %d Len=%d", layer.Header.SourcePort, layer.Header.DestinationPort, len(layer.RawPayload))
+		if layer.LengthError != "" {
+			e.Info = "[Bad length] " + e.Info
+		}
+		if application, ok := layer.PacketParser.(packet.ApplicationLayer); ok && layer.CanParseMore {
+			e.Protocol = strings.ToUpper(layer.Application.Name)
+			e.Info = application.Summary()
+		}
+
+	case packet.IcmpV4Packet:
+		e.Protocol = "ICMP"
+		e.Info = layer.Header.Type.Name
+		if layer.Header.Detail.Name != "No Detail" {
+			e.Info += " (" + layer.Header.Detail.Name + ")"
+		}
+	}
+}
+
+func tcpFlagNames(header packet.TcpHeader) string {
+	result := ""
+	add := func(set bool, name string) {
+		if !set {
+			return
+		}
+		if result != "" {
+			result += ", "
+		}
+		result += name
+	}
+	add(header.SYN, "SYN")
+	add(header.FIN, "FIN")
+	add(header.RST, "RST")
+	add(header.PSH, "PSH")
+	add(header.ACK, "ACK")
+	add(header.URG, "URG")
+	return result
+}
@@ -0,0 +1,323 @@
+package tui
+
+import (
+	"fmt"
+	"net"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+	"strconv"
+	"strings"
+)
+
+// Filter is a compiled display filter. It supports a small subset of the
+// Wireshark syntax:
+//
+//	tcp, udp, icmp, arp, ip and application names such as http
+//	ip.addr, ip.src, ip.dst == or != an address
+//	tcp.port, udp.port, port == or != a number
+//	!, &&, || (also not, and, or) and parentheses
+//
+// Any other word, or a "quoted string", matches the info column.
+type Filter struct {
+	Text string
+	root filterNode
+}
+
+type filterNode interface {
+	match(e *Entry, o *flow.Observation) bool
+}
+
+func ParseFilter(text string) (*Filter, error) {
+	p := &filterParser{tokens: tokenize(text)}
+	if len(p.tokens) == 0 {
+		return &Filter{Text: text}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.position])
+	}
+	return &Filter{Text: text, root: root}, nil
+}
+
+func (f *Filter) Match(e *Entry) bool {
+	if f == nil || f.root == nil {
+		return true
+	}
+	var observation *flow.Observation
+	if o, ok := flow.Observe(e.Decoded); ok {
+		observation = &o
+	}
+	return f.root.match(e, observation)
+}
+
+func tokenize(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(text[i:], "&&"), strings.HasPrefix(text[i:], "||"),
+			strings.HasPrefix(text[i:], "=="), strings.HasPrefix(text[i:], "!="):
+			tokens = append(tokens, text[i:i+2])
+			i += 2
+		case c == '!':
+			tokens = append(tokens, "!")
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				tokens = append(tokens, text[i:])
+				return tokens
+			}
+			tokens = append(tokens, text[i:i+end+2])
+			i += end + 2
+		default:
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t()!&|=\"", rune(text[i])) {
+				i++
+			}
+			if i == start {
+				// A lone & | or =, keep it so the parser can report it.
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+		}
+	}
+	return tokens
+}
+
+type filterParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *filterParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" || p.peek() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" || p.peek() == "and" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch p.peek() {
+	case "!", "not":
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	case "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	token := p.next()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case ")", "&&", "||", "==", "!=":
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+	if strings.HasPrefix(token, "\"") {
+		return textNode(strings.ToLower(strings.Trim(token, "\""))), nil
+	}
+
+	field := strings.ToLower(token)
+	if operator := p.peek(); operator == "==" || operator == "!=" {
+		p.next()
+		value := p.next()
+		if value == "" {
+			return nil, fmt.Errorf("%s %s needs a value", field, operator)
+		}
+		node, err := comparison(field, value)
+		if err != nil {
+			return nil, err
+		}
+		if operator == "!=" {
+			return notNode{node}, nil
+		}
+		return node, nil
+	}
+
+	switch field {
+	case "tcp":
+		return protocolNode(flow.IpProtocolTcp), nil
+	case "udp":
+		return protocolNode(flow.IpProtocolUdp), nil
+	case "icmp":
+		return protocolNode(flow.IpProtocolIcmp), nil
+	case "ip", "ipv4":
+		return ipv4Node{}, nil
+	case "arp":
+		return arpNode{}, nil
+	}
+	return textNode(field), nil
+}
+
+func comparison(field string, value string) (filterNode, error) {
+	switch field {
+	case "ip.addr", "ip.src", "ip.dst":
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IPv4 address", value)
+		}
+		node := addressNode{field: field}
+		copy(node.address[:], ip)
+		return node, nil
+	case "port", "tcp.port", "udp.port":
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a port number", value)
+		}
+		node := portNode{port: uint16(port)}
+		switch field {
+		case "tcp.port":
+			node.protocol = flow.IpProtocolTcp
+		case "udp.port":
+			node.protocol = flow.IpProtocolUdp
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", field)
+}
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(e *Entry, o *flow.Observation) bool {
+	return n.left.match(e, o) || n.right.match(e, o)
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(e *Entry, o *flow.Observation) bool {
+	return n.left.match(e, o) && n.right.match(e, o)
+}
+
+type notNode struct{ inner filterNode }
+
+func (n notNode) match(e *Entry, o *flow.Observation) bool {
+	return !n.inner.match(e, o)
+}
+
+type protocolNode byte
+
+func (n protocolNode) match(e *Entry, o *flow.Observation) bool {
+	return o != nil && o.Protocol == byte(n)
+}
+
+type ipv4Node struct{}
+
+func (n ipv4Node) match(e *Entry, o *flow.Observation) bool {
+	_, ok := flow.FindIpv4(e.Decoded)
+	return ok
+}
+
+type arpNode struct{}
+
+func (n arpNode) match(e *Entry, o *flow.Observation) bool {
+	ethernet, ok := e.Decoded.(packet.EthernetPacket)
+	if !ok || !ethernet.CanParseMore {
+		return false
+	}
+	_, ok = ethernet.PacketParser.(packet.ArpPacket)
+	return ok
+}
+
+type addressNode struct {
+	field   string
+	address [4]byte
+}
+
+func (n addressNode) match(e *Entry, o *flow.Observation) bool {
+	if o == nil {
+		return false
+	}
+	source := o.Source.Address == n.address
+	destination := o.Destination.Address == n.address
+	switch n.field {
+	case "ip.src":
+		return source
+	case "ip.dst":
+		return destination
+	}
+	return source || destination
+}
+
+type portNode struct {
+	protocol byte
+	port     uint16
+}
+
+func (n portNode) match(e *Entry, o *flow.Observation) bool {
+	if o == nil || o.Protocol == flow.IpProtocolIcmp {
+		return false
+	}
+	if n.protocol != 0 && o.Protocol != n.protocol {
+		return false
+	}
+	return o.Source.Port == n.port || o.Destination.Port == n.port
+}
+
+// textNode matches application names and free text in the info column.
+type textNode string
+
+func (n textNode) match(e *Entry, o *flow.Observation) bool {
+	text := string(n)
+	return strings.ToLower(e.Protocol) == text || strings.Contains(strings.ToLower(e.Info), text)
+}
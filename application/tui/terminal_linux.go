@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminal switches a tty into raw mode and restores it afterwards.
+type terminal struct {
+	fd       uintptr
+	original syscall.Termios
+}
+
+func openTerminal(file *os.File) (*terminal, error) {
+	t := &terminal{fd: file.Fd()}
+	if err := ioctl(t.fd, syscall.TCGETS, unsafe.Pointer(&t.original)); err != nil {
+		return nil, err
+	}
+	raw := t.original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	// Keep ISIG so Ctrl-C still reaches the capture loop as SIGINT.
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *terminal) restore() error {
+	return ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&t.original))
+}
+
+// size returns the window size in columns and rows.
+func (t *terminal) size() (int, int, error) {
+	var ws struct {
+		rows, cols, xpixel, ypixel uint16
+	}
+	if err := ioctl(t.fd, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0, 0, err
+	}
+	return int(ws.cols), int(ws.rows), nil
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+type terminal struct{}
+
+func openTerminal(file *os.File) (*terminal, error) {
+	return nil, errors.New("the terminal UI is only supported on Linux")
+}
+
+func (t *terminal) restore() error {
+	return nil
+}
+
+func (t *terminal) size() (int, int, error) {
+	return 0, 0, errors.New("the terminal UI is only supported on Linux")
+}
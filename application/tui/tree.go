@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"fmt"
+	"sniffer/application/flow"
+	"sniffer/application/packet"
+)
+
+type treeLine struct {
+	field packet.Field
+	depth int
+}
+
+func flatten(fields []packet.Field, depth int, lines []treeLine) []treeLine {
+	for _, f := range fields {
+		lines = append(lines, treeLine{field: f, depth: depth})
+		lines = flatten(f.Children, depth+1, lines)
+	}
+	return lines
+}
+
+// BuildTree returns the detail tree of an entry: a frame summary followed by
+// the decoder's field tree, with TCP expert notes under the TCP header.
+func BuildTree(e Entry) []packet.Field {
+	fields := []packet.Field{{
+		Name:   "frame",
+		Label:  fmt.Sprintf("Frame %d: %d bytes", e.Number, e.Length),
+		Length: e.Length,
+	}}
+	if e.Decoded == nil {
+		return fields
+	}
+	layers := packet.Dissect(e.Decoded)
+	if tcp, ok := findTcp(e.Decoded); ok {
+		for i := range layers {
+			if layers[i].Name != "tcp" {
+				continue
+			}
+			for _, annotation := range tcp.Annotations {
+				layers[i].Children = append(layers[i].Children, packet.Field{
+					Name:   "tcp.analysis",
+					Label:  "Expert: " + annotation,
+					Offset: layers[i].Offset,
+					Length: layers[i].Length,
+				})
+			}
+		}
+	}
+	return append(fields, layers...)
+}
+
+func findTcp(p packet.Parsable) (packet.TcpPacket, bool) {
+	ip, ok := flow.FindIpv4(p)
+	if !ok || !ip.CanParseMore {
+		return packet.TcpPacket{}, false
+	}
+	tcp, ok := ip.PacketParser.(packet.TcpPacket)
+	return tcp, ok
+}
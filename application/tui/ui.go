@@ -0,0 +1,611 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultCapacity = 10000
+	redrawInterval  = 100 * time.Millisecond
+	bytesPerRow     = 16
+)
+
+const (
+	focusList = iota
+	focusTree
+	focusHex
+	focusCount
+)
+
+const (
+	escape       = "\x1b["
+	reverseVideo = escape + "7m"
+	bold         = escape + "1m"
+	resetStyle   = escape + "0m"
+	clearLine    = escape + "K"
+)
+
+// UI is a full screen packet browser in the spirit of Wireshark's three pane
+// layout: packet list, detail tree and hex dump. Add may be called from the
+// capture goroutine while Run owns the terminal.
+type UI struct {
+	Capacity int
+
+	mutex    sync.Mutex
+	all      []Entry
+	shown    []Entry
+	held     []Entry
+	total    int
+	paused   bool
+	filter   *Filter
+	selected int
+	listTop  int
+	follow   bool
+	dirty    bool
+
+	focus        int
+	treeFor      int
+	tree         []treeLine
+	treeSelected int
+	treeTop      int
+	hexTop       int
+
+	editing    bool
+	input      string
+	inputError string
+	status     string
+
+	quit     chan struct{}
+	quitOnce sync.Once
+	done     chan struct{}
+	err      error
+	width    int
+	height   int
+}
+
+func NewUI(capacity int) *UI {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &UI{
+		Capacity: capacity,
+		follow:   true,
+		treeFor:  -1,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Add appends a captured frame. While the view is paused frames are held back
+// and shown on resume.
+func (u *UI) Add(e Entry) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.total++
+	if u.paused {
+		u.held = u.trim(append(u.held, e))
+		return
+	}
+	u.append(e)
+}
+
+func (u *UI) append(e Entry) {
+	u.all = u.trim(append(u.all, e))
+	if !u.filter.Match(&e) {
+		return
+	}
+	before := len(u.shown)
+	u.shown = u.trim(append(u.shown, e))
+	if dropped := before + 1 - len(u.shown); dropped > 0 {
+		u.selected -= dropped
+		u.listTop -= dropped
+		if u.selected < 0 {
+			u.selected = 0
+		}
+		if u.listTop < 0 {
+			u.listTop = 0
+		}
+	}
+	if u.follow {
+		u.selected = len(u.shown) - 1
+	}
+	u.dirty = true
+}
+
+// trim keeps the newest Capacity entries, copying only once the slice has
+// grown to twice that so the cost is amortised.
+func (u *UI) trim(entries []Entry) []Entry {
+	if len(entries) <= u.Capacity*2 {
+		return entries
+	}
+	return append([]Entry(nil), entries[len(entries)-u.Capacity:]...)
+}
+
+// Notify shows a message, e.g. an alert, in the status line.
+func (u *UI) Notify(message string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.status = message
+	u.dirty = true
+}
+
+// Stop asks Run to restore the terminal and return.
+func (u *UI) Stop() {
+	u.quitOnce.Do(func() { close(u.quit) })
+}
+
+// Done is closed once Run has returned and the terminal is restored.
+func (u *UI) Done() <-chan struct{} {
+	return u.done
+}
+
+func (u *UI) Err() error {
+	return u.err
+}
+
+// Run takes over the terminal until the user quits or Stop is called.
+func (u *UI) Run() error {
+	defer close(u.done)
+
+	term, err := openTerminal(os.Stdin)
+	if err != nil {
+		u.err = err
+		return err
+	}
+	out := bufio.NewWriterSize(os.Stdout, 64*1024)
+	out.WriteString(escape + "?1049h" + escape + "?25l")
+	defer func() {
+		out.WriteString(resetStyle + escape + "?25h" + escape + "?1049l")
+		out.Flush()
+		term.restore()
+	}()
+
+	keys := make(chan []string, 16)
+	go readKeys(keys)
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	u.resize(term)
+	u.draw(out)
+	for {
+		select {
+		case batch := <-keys:
+			for _, key := range batch {
+				if !u.handleKey(key) {
+					return nil
+				}
+			}
+			u.draw(out)
+		case <-resize:
+			u.resize(term)
+			u.draw(out)
+		case <-ticker.C:
+			u.mutex.Lock()
+			dirty := u.dirty
+			u.mutex.Unlock()
+			if dirty {
+				u.draw(out)
+			}
+		case <-u.quit:
+			return nil
+		}
+	}
+}
+
+func (u *UI) resize(term *terminal) {
+	width, height, err := term.size()
+	if err != nil || width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+	u.mutex.Lock()
+	u.width, u.height = width, height
+	u.mutex.Unlock()
+}
+
+func readKeys(keys chan<- []string) {
+	buffer := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buffer)
+		if err != nil {
+			return
+		}
+		keys <- parseKeys(buffer[:n])
+	}
+}
+
+// parseKeys turns raw terminal input into key names. Printable characters are
+// returned as themselves.
+func parseKeys(data []byte) []string {
+	sequences := []struct{ bytes, name string }{
+		{"\x1b[A", "up"}, {"\x1b[B", "down"}, {"\x1b[C", "right"}, {"\x1b[D", "left"},
+		{"\x1bOA", "up"}, {"\x1bOB", "down"},
+		{"\x1b[5~", "pgup"}, {"\x1b[6~", "pgdn"},
+		{"\x1b[H", "home"}, {"\x1b[1~", "home"}, {"\x1b[F", "end"}, {"\x1b[4~", "end"},
+	}
+	var keys []string
+	for i := 0; i < len(data); {
+		matched := false
+		for _, s := range sequences {
+			if strings.HasPrefix(string(data[i:]), s.bytes) {
+				keys = append(keys, s.name)
+				i += len(s.bytes)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		switch c := data[i]; c {
+		case 0x1b:
+			keys = append(keys, "esc")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		default:
+			if c >= 0x20 && c < 0x7f {
+				keys = append(keys, string(c))
+			}
+		}
+		i++
+	}
+	return keys
+}
+
+// handleKey returns false when the user asked to quit.
+func (u *UI) handleKey(key string) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.editing {
+		u.editKey(key)
+		return true
+	}
+
+	switch key {
+	case "q", "Q":
+		return false
+	case "/":
+		u.editing = true
+		u.input = ""
+		if u.filter != nil {
+			u.input = u.filter.Text
+		}
+	case " ", "p":
+		u.setPaused(!u.paused)
+	case "tab":
+		u.focus = (u.focus + 1) % focusCount
+	case "up", "k":
+		u.move(-1)
+	case "down", "j":
+		u.move(1)
+	case "pgup":
+		u.move(-u.pageSize())
+	case "pgdn":
+		u.move(u.pageSize())
+	case "home", "g":
+		u.move(-1 << 30)
+	case "end", "G":
+		u.move(1 << 30)
+	}
+	return true
+}
+
+func (u *UI) editKey(key string) {
+	u.inputError = ""
+	switch key {
+	case "esc":
+		u.editing = false
+	case "enter":
+		filter, err := ParseFilter(u.input)
+		if err != nil {
+			u.inputError = err.Error()
+			return
+		}
+		u.editing = false
+		u.applyFilter(filter)
+	case "backspace":
+		if len(u.input) > 0 {
+			u.input = u.input[:len(u.input)-1]
+		}
+	default:
+		if len(key) == 1 {
+			u.input += key
+		}
+	}
+}
+
+func (u *UI) setPaused(paused bool) {
+	u.paused = paused
+	if paused {
+		return
+	}
+	held := u.held
+	u.held = nil
+	for _, e := range held {
+		u.append(e)
+	}
+}
+
+func (u *UI) applyFilter(filter *Filter) {
+	if filter.root == nil {
+		filter = nil
+	}
+	u.filter = filter
+	u.shown = nil
+	for i := range u.all {
+		if filter.Match(&u.all[i]) {
+			u.shown = append(u.shown, u.all[i])
+		}
+	}
+	u.selected = len(u.shown) - 1
+	u.follow = true
+	u.treeFor = -1
+}
+
+func (u *UI) move(delta int) {
+	switch u.focus {
+	case focusList:
+		u.selected = clamp(u.selected+delta, 0, len(u.shown)-1)
+		u.follow = u.selected == len(u.shown)-1
+	case focusTree:
+		u.treeSelected = clamp(u.treeSelected+delta, 0, len(u.tree)-1)
+	case focusHex:
+		u.hexTop += delta
+	}
+}
+
+func (u *UI) pageSize() int {
+	list, tree, hex := u.layout()
+	switch u.focus {
+	case focusTree:
+		return tree
+	case focusHex:
+		return hex
+	}
+	return list
+}
+
+// layout splits the rows between the three panes, leaving room for the
+// title, two separators and the status line.
+func (u *UI) layout() (int, int, int) {
+	rows := u.height - 4
+	if rows < 3 {
+		rows = 3
+	}
+	list := rows * 2 / 5
+	tree := rows * 3 / 10
+	return list, tree, rows - list - tree
+}
+
+func clamp(value int, low int, high int) int {
+	if value > high {
+		value = high
+	}
+	if value < low {
+		value = low
+	}
+	return value
+}
+
+func (u *UI) draw(out *bufio.Writer) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.dirty = false
+	listRows, treeRows, hexRows := u.layout()
+	var screen strings.Builder
+	screen.WriteString(escape + "H")
+	line := func(text string) {
+		screen.WriteString(text)
+		screen.WriteString(resetStyle + clearLine + "\r\n")
+	}
+
+	title := fmt.Sprintf("sniffer  %d captured, %d shown", u.total, len(u.shown))
+	if u.filter != nil {
+		title += "  filter: " + u.filter.Text
+	}
+	if u.paused {
+		title += fmt.Sprintf("  PAUSED (%d held)", len(u.held))
+	}
+	line(bold + fit(title, u.width))
+
+	u.drawList(line, listRows)
+	line(u.separator(" Details ", focusTree))
+
+	var selected *Entry
+	if u.selected >= 0 && u.selected < len(u.shown) {
+		selected = &u.shown[u.selected]
+	}
+	u.drawTree(line, selected, treeRows)
+	line(u.separator(" Bytes ", focusHex))
+	u.drawHex(line, selected, hexRows)
+
+	status := "q quit  / filter  space pause  tab switch pane  j/k move"
+	if u.status != "" {
+		status = u.status
+	}
+	if u.editing {
+		status = "filter: " + u.input + "_"
+		if u.inputError != "" {
+			status += "  (" + u.inputError + ")"
+		}
+	}
+	screen.WriteString(reverseVideo + fit(status, u.width) + resetStyle + clearLine)
+
+	out.WriteString(screen.String())
+	out.Flush()
+}
+
+func (u *UI) separator(label string, pane int) string {
+	text := "──" + label + strings.Repeat("─", u.width)
+	if u.focus == pane {
+		return bold + fit(text, u.width)
+	}
+	return fit(text, u.width)
+}
+
+func (u *UI) drawList(line func(string), rows int) {
+	if u.selected < u.listTop {
+		u.listTop = u.selected
+	}
+	if u.selected >= u.listTop+rows {
+		u.listTop = u.selected - rows + 1
+	}
+	if u.listTop < 0 {
+		u.listTop = 0
+	}
+
+	var first time.Time
+	if len(u.all) > 0 {
+		first = u.all[0].Time
+	}
+	for row := 0; row < rows; row++ {
+		i := u.listTop + row
+		if i >= len(u.shown) {
+			line("")
+			continue
+		}
+		e := u.shown[i]
+		text := fmt.Sprintf("%7d %11.6f %-21s %-21s %-8s %6d %s", e.Number, e.Time.Sub(first).Seconds(),
+			fit(e.Source, 21), fit(e.Destination, 21), fit(e.Protocol, 8), e.Length, e.Info)
+		text = fit(text, u.width)
+		switch {
+		case i == u.selected && u.focus == focusList:
+			line(reverseVideo + pad(text, u.width))
+		case i == u.selected:
+			line(bold + text)
+		default:
+			line(text)
+		}
+	}
+}
+
+func (u *UI) drawTree(line func(string), selected *Entry, rows int) {
+	if selected == nil {
+		u.tree = nil
+	} else if selected.Number != u.treeFor {
+		u.tree = flatten(BuildTree(*selected), 0, nil)
+		u.treeFor = selected.Number
+		u.treeSelected = 0
+		u.treeTop = 0
+		u.hexTop = 0
+	}
+
+	if u.treeSelected < u.treeTop {
+		u.treeTop = u.treeSelected
+	}
+	if u.treeSelected >= u.treeTop+rows {
+		u.treeTop = u.treeSelected - rows + 1
+	}
+	for row := 0; row < rows; row++ {
+		i := u.treeTop + row
+		if i >= len(u.tree) {
+			line("")
+			continue
+		}
+		text := fit(strings.Repeat("  ", u.tree[i].depth)+u.tree[i].field.Label, u.width)
+		if i == u.treeSelected && u.focus != focusList {
+			line(reverseVideo + pad(text, u.width))
+		} else {
+			line(text)
+		}
+	}
+}
+
+// drawHex prints the frame with the bytes of the selected tree line in
+// reverse video.
+func (u *UI) drawHex(line func(string), selected *Entry, rows int) {
+	if selected == nil {
+		for row := 0; row < rows; row++ {
+			line("")
+		}
+		return
+	}
+	start, end := -1, -1
+	if u.focus != focusList && u.treeSelected < len(u.tree) {
+		field := u.tree[u.treeSelected].field
+		start, end = field.Offset, field.End()
+		if u.focus == focusTree {
+			// Keep the highlighted field in view while walking the tree.
+			if start/bytesPerRow < u.hexTop || start/bytesPerRow >= u.hexTop+rows {
+				u.hexTop = start / bytesPerRow
+			}
+		}
+	}
+
+	raw := selected.Raw
+	lastRow := (len(raw) - 1) / bytesPerRow
+	u.hexTop = clamp(u.hexTop, 0, clamp(lastRow-rows+1, 0, lastRow))
+	for row := 0; row < rows; row++ {
+		offset := (u.hexTop + row) * bytesPerRow
+		if offset >= len(raw) {
+			line("")
+			continue
+		}
+		line(hexRow(raw, offset, start, end))
+	}
+}
+
+func hexRow(raw []byte, offset int, start int, end int) string {
+	var hex, ascii strings.Builder
+	inField := func(i int) bool {
+		return i >= start && i < end && i < len(raw)
+	}
+	for i := offset; i < offset+bytesPerRow; i++ {
+		if i >= len(raw) {
+			hex.WriteString("   ")
+			continue
+		}
+		if inField(i) && (i == offset || !inField(i-1)) {
+			hex.WriteString(reverseVideo)
+			ascii.WriteString(reverseVideo)
+		}
+		hex.WriteString(fmt.Sprintf("%02x", raw[i]))
+		c := raw[i]
+		if c < 0x20 || c >= 0x7f {
+			c = '.'
+		}
+		ascii.WriteByte(c)
+		if inField(i) && (i+1 == offset+bytesPerRow || !inField(i+1)) {
+			hex.WriteString(resetStyle)
+			ascii.WriteString(resetStyle)
+		}
+		hex.WriteString(" ")
+	}
+	return fmt.Sprintf("%04x  %s %s", offset, hex.String(), ascii.String())
+}
+
+// fit cuts text to at most width columns.
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string(runes[:width])
+}
+
+func pad(text string, width int) string {
+	if n := len([]rune(text)); n < width {
+		return text + strings.Repeat(" ", width-n)
+	}
+	return text
+}
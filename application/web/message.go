@@ -0,0 +1,124 @@
+package web
+
+import (
+	"sniffer/application/flow"
+	"sniffer/application/tui"
+	"time"
+)
+
+// summary is one row of the packet list.
+type summary struct {
+	Number      int       `json:"number"`
+	Time        time.Time `json:"time"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Protocol    string    `json:"protocol"`
+	Length      int       `json:"length"`
+	Info        string    `json:"info"`
+}
+
+func newSummary(e tui.Entry) summary {
+	return summary{
+		Number:      e.Number,
+		Time:        e.Time,
+		Source:      e.Source,
+		Destination: e.Destination,
+		Protocol:    e.Protocol,
+		Length:      e.Length,
+		Info:        e.Info,
+	}
+}
+
+// detail is a packet with its layer tree and bytes, for the detail view.
+type detail struct {
+	summary
+	Tree []field `json:"tree"`
+	Hex  string  `json:"hex"`
+}
+
+// Messages sent over the WebSocket carry their kind in Type.
+type packetMessage struct {
+	Type   string  `json:"type"`
+	Packet summary `json:"packet"`
+}
+
+type backlogMessage struct {
+	Type      string    `json:"type"`
+	Packets   []summary `json:"packets"`
+	Recording bool      `json:"recording"`
+}
+
+type statusMessage struct {
+	Type      string `json:"type"`
+	Recording bool   `json:"recording"`
+}
+
+type errorMessage struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+type flowSummary struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Protocol    string    `json:"protocol"`
+	Packets     uint64    `json:"packets"`
+	Bytes       uint64    `json:"bytes"`
+	AtoB        uint64    `json:"aToB"`
+	BtoA        uint64    `json:"bToA"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	State       string    `json:"state,omitempty"`
+}
+
+// newFlowSummary shows a flow from its initiator's side.
+func newFlowSummary(f flow.Flow) flowSummary {
+	source, destination := f.Key.A, f.Key.B
+	aToB, bToA := f.AtoB.Bytes, f.BtoA.Bytes
+	if f.Initiator == f.Key.B {
+		source, destination = destination, source
+		aToB, bToA = bToA, aToB
+	}
+	return flowSummary{
+		Source:      source.ToString(),
+		Destination: destination.ToString(),
+		Protocol:    flow.ProtocolName(f.Key.Protocol),
+		Packets:     f.Packets(),
+		Bytes:       f.Bytes(),
+		AtoB:        aToB,
+		BtoA:        bToA,
+		FirstSeen:   f.FirstSeen,
+		LastSeen:    f.LastSeen,
+		State:       string(f.TcpState),
+	}
+}
+
+type flowsMessage struct {
+	Enabled bool          `json:"enabled"`
+	Active  int           `json:"active"`
+	Flows   []flowSummary `json:"flows"`
+}
+
+type healthSummary struct {
+	Received         uint64  `json:"received"`
+	Captured         uint64  `json:"captured"`
+	KernelDropped    uint64  `json:"kernelDropped"`
+	InterfaceDropped uint64  `json:"interfaceDropped"`
+	QueueDropped     uint64  `json:"queueDropped"`
+	DecodeFailed     uint64  `json:"decodeFailed"`
+	Truncated        uint64  `json:"truncated"`
+	DropRate         float64 `json:"dropRate"`
+}
+
+type statsMessage struct {
+	Uptime    float64           `json:"uptime"`
+	Recording bool              `json:"recording"`
+	Packets   uint64            `json:"packets"`
+	Bytes     uint64            `json:"bytes"`
+	Kept      int               `json:"kept"`
+	Clients   int               `json:"clients"`
+	Dropped   uint64            `json:"dropped"`
+	Protocols map[string]uint64 `json:"protocols"`
+	Health    *healthSummary    `json:"health,omitempty"`
+	Report    string            `json:"report,omitempty"`
+}
@@ -0,0 +1,459 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"sniffer/application/flow"
+	"sniffer/application/health"
+	"sniffer/application/packet"
+	"sniffer/application/pcapng"
+	"sniffer/application/stats"
+	"sniffer/application/tui"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+const (
+	DefaultCapacity = 10000
+	// clientQueue is how many messages may wait for a slow browser before
+	// packets are dropped for it.
+	clientQueue = 1024
+	// maxBacklog caps the packets sent when a browser connects or changes
+	// its filter.
+	maxBacklog = 2000
+)
+
+type Config struct {
+	// Capacity is the number of packets kept for the list, the detail view
+	// and the pcapng download.
+	Capacity int
+	// Monitor, Flows and Stats back the flow and statistics pages, if set.
+	Monitor  *health.Monitor
+	Flows    *flow.Table
+	Stats    *stats.Collector
+	TopFlows int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Capacity: DefaultCapacity,
+		TopFlows: 100,
+	}
+}
+
+type client struct {
+	conn   *websocketConn
+	send   chan []byte
+	filter *tui.Filter
+}
+
+// Server is the web UI: a single page served from the binary, a WebSocket
+// that streams packets as they are added and a small JSON API for the
+// detail, flow and statistics views. Add is called from the packet loop;
+// everything else runs on HTTP goroutines.
+type Server struct {
+	config Config
+	mux    *http.ServeMux
+	start  time.Time
+
+	mutex     sync.Mutex
+	entries   []tui.Entry
+	recording bool
+	total     uint64
+	bytes     uint64
+	protocols map[string]uint64
+	clients   map[*client]struct{}
+	// dropped counts messages not sent to browsers that fell behind.
+	dropped uint64
+}
+
+func NewServer(config Config) *Server {
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultCapacity
+	}
+	s := &Server{
+		config:    config,
+		mux:       http.NewServeMux(),
+		start:     time.Now(),
+		recording: true,
+		protocols: make(map[string]uint64),
+		clients:   make(map[*client]struct{}),
+	}
+	s.mux.HandleFunc("/", s.serveIndex)
+	s.mux.HandleFunc("/ws", s.serveWebsocket)
+	s.mux.HandleFunc("/api/packets/", s.servePacket)
+	s.mux.HandleFunc("/api/flows", s.serveFlows)
+	s.mux.HandleFunc("/api/stats", s.serveStats)
+	s.mux.HandleFunc("/api/recording/resume", s.serveResume)
+	s.mux.HandleFunc("/api/recording/pause", s.servePause)
+	s.mux.HandleFunc("/api/capture.pcapng", s.servePcapng)
+	return s
+}
+
+// Serve runs the UI until the listener fails.
+func Serve(listener net.Listener, s *Server) error {
+	return http.Serve(listener, s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Add records a packet and streams it to every browser whose filter it
+// matches. While recording is paused from the UI packets are ignored; the
+// capture itself goes on for every other output.
+func (s *Server) Add(e tui.Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.recording {
+		return
+	}
+	s.entries = s.trim(append(s.entries, e))
+	s.total++
+	s.bytes += uint64(e.Length)
+	s.protocols[e.Protocol]++
+
+	var message []byte
+	for c := range s.clients {
+		if !c.filter.Match(&e) {
+			continue
+		}
+		if message == nil {
+			message, _ = json.Marshal(packetMessage{Type: "packet", Packet: newSummary(e)})
+		}
+		s.queue(c, message)
+	}
+}
+
+// trim keeps the newest Capacity entries, copying only once the slice has
+// grown to twice that so the cost is amortised.
+func (s *Server) trim(entries []tui.Entry) []tui.Entry {
+	if len(entries) <= s.config.Capacity*2 {
+		return entries
+	}
+	return append([]tui.Entry(nil), entries[len(entries)-s.config.Capacity:]...)
+}
+
+// newest returns the packets kept, oldest first.
+func (s *Server) newest() []tui.Entry {
+	if len(s.entries) > s.config.Capacity {
+		return s.entries[len(s.entries)-s.config.Capacity:]
+	}
+	return s.entries
+}
+
+func (s *Server) queue(c *client, message []byte) {
+	select {
+	case c.send <- message:
+	default:
+		s.dropped++
+	}
+}
+
+func (s *Server) broadcast(v interface{}) {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	for c := range s.clients {
+		s.queue(c, message)
+	}
+}
+
+// backlog replaces whatever is still queued for c with the kept packets
+// that match its filter.
+func (s *Server) backlog(c *client) {
+	for {
+		select {
+		case <-c.send:
+			continue
+		default:
+		}
+		break
+	}
+	var summaries []summary
+	entries := s.newest()
+	for i := len(entries) - 1; i >= 0 && len(summaries) < maxBacklog; i-- {
+		if c.filter.Match(&entries[i]) {
+			summaries = append(summaries, newSummary(entries[i]))
+		}
+	}
+	for i, j := 0, len(summaries)-1; i < j; i, j = i+1, j-1 {
+		summaries[i], summaries[j] = summaries[j], summaries[i]
+	}
+	message, _ := json.Marshal(backlogMessage{Type: "backlog", Packets: summaries, Recording: s.recording})
+	s.queue(c, message)
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c := &client{conn: conn, send: make(chan []byte, clientQueue)}
+
+	s.mutex.Lock()
+	s.clients[c] = struct{}{}
+	s.backlog(c)
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer conn.conn.Close()
+		for {
+			select {
+			case message := <-c.send:
+				if err := conn.WriteText(message); err != nil {
+					return
+				}
+			case <-done:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var request struct {
+			Filter *string `json:"filter"`
+		}
+		if err := json.Unmarshal(message, &request); err != nil || request.Filter == nil {
+			continue
+		}
+		filter, err := tui.ParseFilter(*request.Filter)
+
+		s.mutex.Lock()
+		if err != nil {
+			reply, _ := json.Marshal(errorMessage{Type: "error", Error: "filter: " + err.Error()})
+			s.queue(c, reply)
+		} else {
+			c.filter = filter
+			s.backlog(c)
+		}
+		s.mutex.Unlock()
+	}
+
+	s.mutex.Lock()
+	delete(s.clients, c)
+	s.mutex.Unlock()
+	close(done)
+}
+
+func (s *Server) servePacket(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/packets/"))
+	if err != nil {
+		http.Error(w, "bad packet number", http.StatusBadRequest)
+		return
+	}
+	s.mutex.Lock()
+	entries := s.newest()
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Number >= number })
+	found := i < len(entries) && entries[i].Number == number
+	var e tui.Entry
+	if found {
+		e = entries[i]
+	}
+	s.mutex.Unlock()
+
+	if !found {
+		http.Error(w, "packet is no longer kept", http.StatusNotFound)
+		return
+	}
+	writeJson(w, detail{
+		summary: newSummary(e),
+		Tree:    newFields(tui.BuildTree(e)),
+		Hex:     hex.EncodeToString(e.Raw),
+	})
+}
+
+func (s *Server) serveFlows(w http.ResponseWriter, r *http.Request) {
+	result := flowsMessage{Flows: []flowSummary{}}
+	if s.config.Flows != nil {
+		result.Enabled = true
+		result.Active = s.config.Flows.Len()
+		for _, f := range s.config.Flows.Top(s.config.TopFlows) {
+			result.Flows = append(result.Flows, newFlowSummary(f))
+		}
+	}
+	writeJson(w, result)
+}
+
+func (s *Server) serveStats(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	result := statsMessage{
+		Uptime:    time.Since(s.start).Seconds(),
+		Recording: s.recording,
+		Packets:   s.total,
+		Bytes:     s.bytes,
+		Kept:      len(s.newest()),
+		Clients:   len(s.clients),
+		Dropped:   s.dropped,
+		Protocols: make(map[string]uint64, len(s.protocols)),
+	}
+	for name, count := range s.protocols {
+		result.Protocols[name] = count
+	}
+	s.mutex.Unlock()
+
+	if s.config.Monitor != nil {
+		snapshot := s.config.Monitor.Snapshot()
+		result.Health = &healthSummary{
+			Received:         snapshot.Received,
+			Captured:         snapshot.Captured,
+			KernelDropped:    snapshot.KernelDropped,
+			InterfaceDropped: snapshot.InterfaceDropped,
+			QueueDropped:     snapshot.QueueDropped,
+			DecodeFailed:     snapshot.DecodeFailed,
+			Truncated:        snapshot.Truncated,
+			DropRate:         snapshot.DropRate(),
+		}
+	}
+	if s.config.Stats != nil {
+		result.Report = s.config.Stats.ToString(10)
+	}
+	writeJson(w, result)
+}
+
+// serveResume clears the buffer and starts recording packets again.
+func (s *Server) serveResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request", http.StatusForbidden)
+		return
+	}
+	s.mutex.Lock()
+	s.recording = true
+	s.entries = nil
+	s.total, s.bytes = 0, 0
+	s.protocols = make(map[string]uint64)
+	for c := range s.clients {
+		s.backlog(c)
+	}
+	s.mutex.Unlock()
+	writeJson(w, statusMessage{Type: "status", Recording: true})
+}
+
+// servePause stops recording packets for the UI; the buffer is kept for
+// browsing and download. The capture source keeps running.
+func (s *Server) servePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request", http.StatusForbidden)
+		return
+	}
+	s.mutex.Lock()
+	s.recording = false
+	s.broadcast(statusMessage{Type: "status", Recording: false})
+	s.mutex.Unlock()
+	writeJson(w, statusMessage{Type: "status", Recording: false})
+}
+
+// servePcapng downloads the kept packets, or those matching ?filter=. It
+// is pcapng so that packets from interfaces of different link types keep
+// theirs.
+func (s *Server) servePcapng(w http.ResponseWriter, r *http.Request) {
+	filter, err := tui.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		http.Error(w, "filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mutex.Lock()
+	entries := s.newest()
+	s.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-pcapng")
+	w.Header().Set("Content-Disposition", `attachment; filename="capture.pcapng"`)
+	writer, err := pcapng.NewWriter(w, "sniffer")
+	if err != nil {
+		return
+	}
+	for i := range entries {
+		if !filter.Match(&entries[i]) {
+			continue
+		}
+		err := writer.WritePacket(pcapng.Packet{
+			Interface: entries[i].Interface,
+			LinkType:  entries[i].LinkType,
+			Timestamp: entries[i].Time,
+			Data:      entries[i].Raw,
+			Length:    entries[i].Length,
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sameOrigin rejects requests a browser sends on behalf of another site,
+// which could otherwise read the packet stream or pause recording.
+// Clients that send no Origin, such as curl, are not browsers and pass.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// field is packet.Field as the detail view receives it.
+type field struct {
+	Name      string  `json:"name"`
+	Label     string  `json:"label"`
+	Offset    int     `json:"offset"`
+	Length    int     `json:"length"`
+	BitOffset int     `json:"bitOffset,omitempty"`
+	BitLength int     `json:"bitLength,omitempty"`
+	Children  []field `json:"children,omitempty"`
+}
+
+func newFields(fields []packet.Field) []field {
+	result := make([]field, len(fields))
+	for i, f := range fields {
+		result[i] = field{
+			Name:      f.Name,
+			Label:     f.Label,
+			Offset:    f.Offset,
+			Length:    f.Length,
+			BitOffset: f.BitOffset,
+			BitLength: f.BitLength,
+			Children:  newFields(f.Children),
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sniffer/application/tui"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+func TestPcapngKeepsLinkTypes(t *testing.T) {
+	s := NewServer(DefaultConfig())
+	start := time.Unix(1000, 0)
+	ethernet := make([]byte, 60)
+	raw := []byte{0x45, 0, 0, 20, 0, 0, 0, 0, 64, 17, 0, 0, 10, 0, 0, 1, 10, 0, 0, 2}
+	s.Add(tui.Entry{Number: 1, Time: start, Raw: ethernet, Length: len(ethernet), Interface: "eth0", LinkType: layers.LinkTypeEthernet})
+	s.Add(tui.Entry{Number: 2, Time: start.Add(time.Second), Raw: raw, Length: len(raw), Interface: "tun0", LinkType: layers.LinkTypeRaw})
+
+	response := httptest.NewRecorder()
+	s.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/capture.pcapng", nil))
+	reader, err := pcapgo.NewNgReader(response.Body, pcapgo.NgReaderOptions{WantMixedLinkType: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		length   int
+		name     string
+		linkType layers.LinkType
+	}{
+		{len(ethernet), "eth0", layers.LinkTypeEthernet},
+		{len(raw), "tun0", layers.LinkTypeRaw},
+	} {
+		data, info, err := reader.ReadPacketData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		iface, err := reader.Interface(info.InterfaceIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != want.length || iface.Name != want.name || iface.LinkType != want.linkType {
+			t.Errorf("got %d bytes on %s with %s, want %d bytes on %s with %s",
+				len(data), iface.Name, iface.LinkType, want.length, want.name, want.linkType)
+		}
+	}
+}
+
+func TestPauseKeepsBuffer(t *testing.T) {
+	s := NewServer(DefaultConfig())
+	s.Add(tui.Entry{Number: 1, Raw: []byte{1}})
+
+	response := httptest.NewRecorder()
+	s.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/recording/pause", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("pause answered %d", response.Code)
+	}
+	s.Add(tui.Entry{Number: 2, Raw: []byte{2}})
+	if len(s.newest()) != 1 {
+		t.Fatalf("%d packets kept while paused, want 1", len(s.newest()))
+	}
+
+	response = httptest.NewRecorder()
+	s.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/recording/resume", nil))
+	s.Add(tui.Entry{Number: 3, Raw: []byte{3}})
+	if entries := s.newest(); len(entries) != 1 || entries[0].Number != 3 {
+		t.Fatalf("after resume kept %+v, want only packet 3", entries)
+	}
+}
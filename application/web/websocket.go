@@ -0,0 +1,241 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGuid is appended to the client key to form the accept key, see
+// RFC 6455 section 1.3.
+const websocketGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const (
+	maxMessageSize = 64 * 1024
+	writeTimeout   = 10 * time.Second
+)
+
+var (
+	errNotWebsocket  = errors.New("websocket: not a websocket handshake")
+	errUnmasked      = errors.New("websocket: client frame is not masked")
+	errMessageTooBig = errors.New("websocket: message too big")
+	errBadFrame      = errors.New("websocket: malformed frame")
+)
+
+// websocketConn is the server side of a WebSocket connection. It is just
+// enough of RFC 6455 for the UI: text messages both ways, ping/pong and the
+// closing handshake, without extensions. One goroutine may read while
+// another writes.
+type websocketConn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+}
+
+func headerHasToken(h http.Header, name string, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGuid))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// upgrade completes the opening handshake and takes over the connection.
+// On error it has already answered the request.
+func upgrade(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, errNotWebsocket.Error(), http.StatusBadRequest)
+		return nil, errNotWebsocket
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "websocket: cross-origin request", http.StatusForbidden)
+		return nil, errNotWebsocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, errNotWebsocket
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: connection cannot be taken over", http.StatusInternalServerError)
+		return nil, errNotWebsocket
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocketConn{conn: conn, reader: buffered.Reader}, nil
+}
+
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// WriteText sends one unfragmented text message.
+func (c *websocketConn) WriteText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *websocketConn) readFrame() (final bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	final = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, errBadFrame
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, errUnmasked
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if opcode >= opClose && (!final || length > 125) {
+		return false, 0, nil, errBadFrame
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, errMessageTooBig
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return final, opcode, payload, nil
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns io.EOF once the client has closed the connection.
+func (c *websocketConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		final, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// Echo the status code, if any, to complete the handshake.
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, errBadFrame
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, errBadFrame
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+		if len(message)+len(payload) > maxMessageSize {
+			return nil, errMessageTooBig
+		}
+		message = append(message, payload...)
+		if final {
+			return message, nil
+		}
+	}
+}
+
+// Close sends a normal closure and closes the connection without waiting
+// for the client's reply.
+func (c *websocketConn) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}